@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kaium123/order/internal/config"
+	"github.com/kaium123/order/internal/encryption"
+	"github.com/spf13/cobra"
+)
+
+// configCmd returns a new `config` command to be used as a sub-command to root
+func configCmd() *cobra.Command {
+	configCmd := cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+	configCmd.AddCommand(configCheck())
+	return &configCmd
+}
+
+// configCheck returns the `config check` command validating the configuration
+// without starting any server.
+func configCheck() *cobra.Command {
+	var consulURL, consulPath string
+
+	checkCmd := cobra.Command{
+		Use:   "check",
+		Short: "Validate the configuration file, Consul path and environment",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			src := config.NewSource(cmd.Flags())
+			if consulURL != "" {
+				src.ConsulURL = consulURL
+			}
+			if consulPath != "" {
+				src.ConsulPath = consulPath
+			}
+
+			conf := config.New()
+			if err := conf.Read(src); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "configuration is valid (hash %s)\n", conf.Hash())
+			return nil
+		},
+	}
+	checkCmd.Flags().StringVar(&consulURL, "consul-url", "", "Consul address, overrides ORDERS_CONSUL_URL")
+	checkCmd.Flags().StringVar(&consulPath, "consul-path", "", "Consul key, overrides ORDERS_CONSUL_PATH")
+	return &checkCmd
+}
+
+// loadConfig reads the configuration of given command and installs its
+// encryption keyring, so the encrypted columns can be read and written. It
+// prints every problem found and exits on error.
+func loadConfig(cmd *cobra.Command) (*config.Source, *config.Config) {
+	src := config.NewSource(cmd.Flags())
+	conf := config.New()
+	if err := conf.Read(src); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	keyring, err := encryption.NewKeyring(&conf.Encryption)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	encryption.Use(keyring)
+	return src, conf
+}
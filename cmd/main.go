@@ -1,14 +1,20 @@
 package main
 
 import (
+	"github.com/kaium123/order/internal/config"
 	"github.com/spf13/cobra"
 	l "log"
 	"os"
 )
 
 func main() {
-	var rootCmd = &cobra.Command{}
+	var rootCmd = &cobra.Command{Use: "order", SilenceErrors: true}
+	config.RegisterFlags(rootCmd.PersistentFlags())
 	rootCmd.AddCommand(serve())
+	rootCmd.AddCommand(configCmd())
+	rootCmd.AddCommand(migrate())
+	rootCmd.AddCommand(seedCmd())
+	rootCmd.AddCommand(reencryptCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		l.Println(err)
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/sql"
+	"github.com/spf13/cobra"
+)
+
+// migrate returns a new `migrate` command to be used as a sub-command to root
+func migrate() *cobra.Command {
+	migrateCmd := cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database migrations",
+	}
+
+	migrateCmd.AddCommand(
+		&cobra.Command{
+			Use:   "up [N]",
+			Short: "Apply all or N up migrations",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: withMigrator(func(m *db.Migrator, args []string) error {
+				steps, err := optionalSteps(args)
+				if err != nil {
+					return err
+				}
+				return m.Up(steps)
+			}),
+		},
+		&cobra.Command{
+			Use:   "down [N]",
+			Short: "Roll back all or N migrations",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: withMigrator(func(m *db.Migrator, args []string) error {
+				steps, err := optionalSteps(args)
+				if err != nil {
+					return err
+				}
+				return m.Down(steps)
+			}),
+		},
+		&cobra.Command{
+			Use:   "goto V",
+			Short: "Migrate up or down to version V",
+			Args:  cobra.ExactArgs(1),
+			RunE: withMigrator(func(m *db.Migrator, args []string) error {
+				version, err := strconv.ParseUint(args[0], 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid version %q: %w", args[0], err)
+				}
+				return m.Goto(uint(version))
+			}),
+		},
+		&cobra.Command{
+			Use:   "version",
+			Short: "Print the current migration version",
+			Args:  cobra.NoArgs,
+			RunE: withMigrator(func(m *db.Migrator, _ []string) error {
+				version, dirty, err := m.Version()
+				if err != nil {
+					return err
+				}
+				if dirty {
+					fmt.Printf("%d (dirty)\n", version)
+					return nil
+				}
+				fmt.Println(version)
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "force V",
+			Short: "Set version V without running migrations and clear the dirty flag",
+			Args:  cobra.ExactArgs(1),
+			RunE: withMigrator(func(m *db.Migrator, args []string) error {
+				version, err := strconv.Atoi(args[0])
+				if err != nil {
+					return fmt.Errorf("invalid version %q: %w", args[0], err)
+				}
+				return m.Force(version)
+			}),
+		},
+		migrateCreate(),
+	)
+
+	return &migrateCmd
+}
+
+// withMigrator opens a Migrator of the configured database for given function.
+func withMigrator(fn func(m *db.Migrator, args []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		_, conf := loadConfig(cmd)
+
+		migrateDB, err := db.SQLFromUrl(conf.DB.URL)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database for migration: %w", err)
+		}
+
+		m, err := db.NewMigratorFromFS(migrateDB, "orders", sql.GetMigrations())
+		if err != nil {
+			migrateDB.Close() //nolint
+			return err
+		}
+		defer m.Close() //nolint
+
+		return fn(m, args)
+	}
+}
+
+// optionalSteps parses the optional [N] argument, 0 means all.
+func optionalSteps(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps <= 0 {
+		return 0, fmt.Errorf("invalid number of migrations %q", args[0])
+	}
+	return steps, nil
+}
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_.*\.(up|down)\.sql$`)
+
+// migrateCreate returns the `migrate create` command scaffolding a new pair of
+// up and down migration files.
+func migrateCreate() *cobra.Command {
+	var dir string
+
+	createCmd := cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a new pair of up and down migration files",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			name := strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).
+				ReplaceAllString(strings.ToLower(args[0]), "_"), "_")
+			if name == "" {
+				return fmt.Errorf("invalid migration name %q", args[0])
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				return fmt.Errorf("failed to read migrations directory: %w", err)
+			}
+
+			var last uint64
+			for _, entry := range entries {
+				match := migrationFileRegexp.FindStringSubmatch(entry.Name())
+				if match == nil {
+					continue
+				}
+				if version, _ := strconv.ParseUint(match[1], 10, 64); version > last {
+					last = version
+				}
+			}
+
+			base := fmt.Sprintf("%06d_%s", last+1, name)
+			for _, direction := range []db.Direction{db.DirectionUp, db.DirectionDown} {
+				path := filepath.Join(dir, fmt.Sprintf("%s.%s.sql", base, direction))
+				content := fmt.Sprintf("-- %s migration of %s\n", direction, name)
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					return fmt.Errorf("failed to create migration: %w", err)
+				}
+				fmt.Println(path)
+			}
+			return nil
+		},
+	}
+	createCmd.Flags().StringVar(&dir, "dir", filepath.Join("sql", "migrations"), "migrations directory")
+	return &createCmd
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/repository"
+	"github.com/spf13/cobra"
+)
+
+// reencryptCmd returns a new `reencrypt` command to be used as a sub-command
+// to root
+func reencryptCmd() *cobra.Command {
+	var batchSize int
+
+	reencryptCmd := cobra.Command{
+		Use:   "reencrypt",
+		Short: "Encrypt the recipient details again with the active key and refresh their blind index",
+		Long: `Encrypt the recipient details again with the active key and refresh their blind index.
+The webhook secrets are encrypted again too.
+
+Run it after switching encryption.active_key, changing encryption.blind_index_key
+or toggling encryption.enable. It only writes the orders which need it and can be
+interrupted and run again.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cmd.SilenceUsage = true
+			if batchSize <= 0 {
+				return fmt.Errorf("--batch-size must be positive, got %d", batchSize)
+			}
+			_, conf := loadConfig(cmd)
+
+			var (
+				ctx    = context.Background()
+				logger = log.New()
+			)
+
+			dbInstance, err := db.New(conf.DB, logger)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+			defer dbInstance.Close() //nolint
+
+			orderRepository := repository.NewOrder(&repository.InitOrderRepository{Db: dbInstance, Log: logger})
+
+			var lastID int64
+			read, total := 0, 0
+			for {
+				next, updated, err := orderRepository.ReencryptOrders(ctx, lastID, batchSize)
+				if err != nil {
+					return fmt.Errorf("failed after order %d: %w", lastID, err)
+				}
+				if next == 0 {
+					break
+				}
+				lastID = next
+				total += updated
+				read++
+			}
+			fmt.Printf("%d orders updated in %d batches\n", total, read)
+
+			webhookRepository := repository.NewWebhook(&repository.InitWebhookRepository{Db: dbInstance, Log: logger})
+			secrets, err := webhookRepository.ReencryptSecrets(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to reencrypt the webhook secrets: %w", err)
+			}
+			fmt.Printf("%d webhook secrets updated\n", secrets)
+			return nil
+		},
+	}
+	reencryptCmd.Flags().IntVar(&batchSize, "batch-size", 500, "number of orders read and updated per transaction")
+	return &reencryptCmd
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kaium123/order/internal/cache"
+	"github.com/kaium123/order/internal/config"
+	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/repository"
+	"github.com/kaium123/order/internal/seed"
+	"github.com/kaium123/order/internal/service"
+	"github.com/spf13/cobra"
+)
+
+// seedCmd returns a new `seed` command to be used as a sub-command to root
+func seedCmd() *cobra.Command {
+	var env string
+
+	seedCmd := cobra.Command{
+		Use:   "seed FILE...",
+		Short: "Load users, stores, rate cards and orders from yaml or json fixture files",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			_, conf := loadConfig(cmd)
+
+			// refuse to run against production, and against an environment
+			// other than the one the caller expects
+			if env == config.EnvProduction || conf.Env == config.EnvProduction {
+				return fmt.Errorf("refusing to seed the %s environment", config.EnvProduction)
+			}
+			if env != conf.Env {
+				return fmt.Errorf("--env %q doesn't match the configured environment %q", env, conf.Env)
+			}
+
+			var fixtures []*seed.Fixtures
+			for _, path := range args {
+				f, err := seed.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				fixtures = append(fixtures, f)
+			}
+
+			var (
+				ctx    = context.Background()
+				logger = log.New()
+			)
+
+			dbInstance, err := db.New(conf.DB, logger)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+			defer dbInstance.Close() //nolint
+
+			redisClient := cache.New(conf.Redis)
+			defer redisClient.Close() //nolint
+
+			userRepository := repository.NewUser(&repository.InitUserRepository{Db: dbInstance, Log: logger})
+			storeRepository := repository.NewStore(&repository.InitStoreRepository{Db: dbInstance, Log: logger})
+			orderRepository := repository.NewOrder(&repository.InitOrderRepository{Db: dbInstance, Log: logger})
+			orderService := service.NewOrder(&service.InitOrderService{
+				Log: logger, OrderRepository: orderRepository, StoreRepository: storeRepository,
+				RedisCache:   repository.NewRedisCache(&repository.InitRedisCache{Client: redisClient, Log: logger}),
+				Pricing:      func() model.Pricing { return conf.Pricing },
+				Cancellation: func() model.Cancellation { return conf.Cancellation },
+				Delivery:     func() model.DeliveryRules { return conf.Delivery },
+				Features:     func() model.Features { return conf.Features },
+			})
+
+			seeder := seed.New(&seed.InitSeeder{
+				UserRepository:  userRepository,
+				StoreRepository: storeRepository,
+				OrderRepository: orderRepository,
+				OrderService:    orderService,
+				Log:             logger,
+			})
+
+			for i, f := range fixtures {
+				res, err := seeder.Seed(ctx, f)
+				if err != nil {
+					return fmt.Errorf("%s: %w", args[i], err)
+				}
+				fmt.Printf("%s: %d created, %d updated, %d already existing\n", args[i], res.Created, res.Updated, res.Skipped)
+			}
+			return nil
+		},
+	}
+	seedCmd.Flags().StringVar(&env, "env", "", "environment the fixtures are loaded into, must match the configured env and can't be production")
+	_ = seedCmd.MarkFlagRequired("env")
+	return &seedCmd
+}
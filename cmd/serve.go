@@ -8,11 +8,13 @@ import (
 	"github.com/kaium123/order/internal/db"
 	"github.com/kaium123/order/internal/log"
 	"github.com/kaium123/order/internal/server"
+	"github.com/kaium123/order/internal/tracing"
 	"github.com/kaium123/order/sql"
 	"go.uber.org/zap"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -20,20 +22,41 @@ import (
 
 // serve returns a new `serve` command to be used as a sub-command to root
 func serve() *cobra.Command {
+	var noMigrate bool
+
 	serveCmd := cobra.Command{
 		Use:   "serve",
 		Short: "Start the server",
-		Run: func(_ *cobra.Command, _ []string) {
+		Run: func(cmd *cobra.Command, _ []string) {
 			var (
-				servers []server.Server
-				conf    = config.New().Load()
-				logger  = log.New()
-				ctx     = context.Background()
+				servers   []server.Server
+				src, conf = loadConfig(cmd)
+				logger    = log.New()
+				ctx       = context.Background()
 			)
 
-			// Run migrations
-			if migrateOnly, err := runMigrations(ctx, conf, logger); migrateOnly {
-				panic(err)
+			if err := logger.SetLevel(conf.Log.Level); err != nil {
+				logger.Fatal(ctx, "invalid log level.", zap.Error(err))
+			}
+
+			shutdownTracing, err := tracing.Setup(ctx, &conf.Tracing)
+			if err != nil {
+				logger.Fatal(ctx, "failed to set up tracing.", zap.Error(err))
+			}
+
+			// Reloadable settings are applied to the running services
+			reloader := config.NewReloader(src, conf, logger)
+			reloader.OnReload(func(conf *config.Config) {
+				if err := logger.SetLevel(conf.Log.Level); err != nil {
+					logger.Error(ctx, "invalid log level.", zap.Error(err))
+				}
+			})
+
+			// Run migrations, unless another instance or `migrate up` does it
+			if !noMigrate {
+				if migrateOnly, err := runMigrations(ctx, conf, logger); migrateOnly {
+					panic(err)
+				}
 			}
 
 			// Initialize API server
@@ -42,7 +65,8 @@ func serve() *cobra.Command {
 					ListenPort: conf.APIServer.Port,
 					Config:     *conf,
 				},
-				Log: logger,
+				Reloader: reloader,
+				Log:      logger,
 			}
 
 			apiServer, err := server.NewAPI(ctx, initNewAPI)
@@ -65,10 +89,69 @@ func serve() *cobra.Command {
 				servers = append(servers, swagServer)
 			}
 
+			// Initialize admin server serving the metrics if enabled
+			if conf.AdminServer.Enable {
+				initNewAdmin := &server.InitNewAdmin{
+					AdminServerOpts: server.AdminServerOpts{
+						ListenPort: conf.AdminServer.Port,
+					},
+					Log: logger,
+				}
+
+				adminServer := server.NewAdmin(ctx, initNewAdmin)
+				servers = append(servers, adminServer)
+			}
+
+			// Initialize the outbox relay if enabled
+			if conf.Outbox.Enable {
+				initNewRelay := &server.InitNewRelay{
+					RelayOpts: server.RelayOpts{Config: *conf},
+					Log:       logger,
+				}
+
+				relayServer, err := server.NewRelay(ctx, initNewRelay)
+				if err != nil {
+					logger.Fatal(ctx, "failed to init the outbox relay.", zap.Error(err))
+				}
+				servers = append(servers, relayServer)
+			}
+
+			// Initialize the webhook dispatcher if enabled
+			if conf.Webhook.Enable {
+				initNewWebhook := &server.InitNewWebhook{
+					WebhookOpts: server.WebhookOpts{Config: *conf},
+					Log:         logger,
+				}
+
+				webhookServer, err := server.NewWebhook(ctx, initNewWebhook)
+				if err != nil {
+					logger.Fatal(ctx, "failed to init the webhook dispatcher.", zap.Error(err))
+				}
+				servers = append(servers, webhookServer)
+			}
+
+			// Initialize the archiver of the completed orders if enabled
+			if conf.Archive.Enable {
+				initNewArchiver := &server.InitNewArchiver{
+					ArchiverOpts: server.ArchiverOpts{Config: *conf},
+					Log:          logger,
+				}
+
+				archiverServer, err := server.NewArchiver(ctx, initNewArchiver)
+				if err != nil {
+					logger.Fatal(ctx, "failed to init the order archiver.", zap.Error(err))
+				}
+				servers = append(servers, archiverServer)
+			}
+
 			// Handle graceful shutdown
 			ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
 			defer stop()
 
+			// Reload the configuration on Consul changes and on SIGHUP
+			go reloader.Watch(ctx)
+			go reloadOnHangup(ctx, reloader)
+
 			for _, s := range servers {
 				server := s
 				go func() {
@@ -83,21 +166,47 @@ func serve() *cobra.Command {
 
 			<-ctx.Done()
 			logger.Info(ctx, "server shutting down")
-			ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			// the signal context is done already, the shutdown gets its own
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 
 			for _, s := range servers {
-				if err := s.Shutdown(ctx); err != nil {
-					logger.Fatal(ctx, "error while shutting down.", zap.Error(err))
+				if err := s.Shutdown(shutdownCtx); err != nil {
+					logger.Fatal(shutdownCtx, "error while shutting down.", zap.Error(err))
 					panic(err)
 				}
 			}
-			logger.Info(ctx, "server shutdown gracefully")
+			// the pending spans are flushed within a budget of their own,
+			// whatever the servers took to shut down
+			tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelTracing()
+			if err := shutdownTracing(tracingCtx); err != nil {
+				logger.Error(tracingCtx, "failed to flush the traces.", zap.Error(err))
+			}
+			logger.Info(tracingCtx, "server shutdown gracefully")
 		},
 	}
+	serveCmd.Flags().BoolVar(&noMigrate, "no-migrate", false, "don't run the migrations on start")
 	return &serveCmd
 }
 
+// reloadOnHangup reloads the configuration on every SIGHUP until the context
+// is done.
+func reloadOnHangup(ctx context.Context, reloader *config.Reloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			_ = reloader.Reload(ctx)
+		}
+	}
+}
+
 // runMigrations handles database migrations
 func runMigrations(ctx context.Context, conf *config.Config, logger *log.Logger) (bool, error) {
 	migrateDirection, migrateOnly := conf.MigrationDirectionFlag()
@@ -109,12 +218,14 @@ func runMigrations(ctx context.Context, conf *config.Config, logger *log.Logger)
 	}
 	defer migrateDB.Close()
 
+	logger.Info(ctx, "running migrations", zap.String("direction", string(migrateDirection)))
 	migrations := sql.GetMigrations()
 	err = db.MigrateFromFS(migrateDB, migrateDirection, "orders", migrations)
 	if err != nil {
 		logger.Fatal(ctx, "failed to run migrations", zap.Error(err))
 		return false, err
 	}
+	logger.Info(ctx, "migrations applied")
 
 	if migrateOnly {
 		logger.Info(ctx, "Migration complete, exiting")
@@ -0,0 +1,84 @@
+// Package archive archives the orders completed for a while, so the default
+// order listings of the merchants stay short.
+package archive
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/metrics"
+	"github.com/kaium123/order/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Config of the archiver.
+type Config struct {
+	Enable bool `json:"enable" yaml:"enable" toml:"enable" mapstructure:"enable"`
+	// AfterDays is the number of days after which the completed orders left
+	// unchanged are archived.
+	AfterDays int `json:"after_days" yaml:"after_days" toml:"after_days" mapstructure:"after_days"`
+	// Interval is the time between the runs.
+	Interval time.Duration `json:"interval" yaml:"interval" toml:"interval" mapstructure:"interval"`
+	// BatchSize is the number of orders archived per statement.
+	BatchSize int `json:"batch_size" yaml:"batch_size" toml:"batch_size" mapstructure:"batch_size"`
+}
+
+type InitArchiver struct {
+	OrderRepository repository.IOrder
+	Config          *Config
+	Log             *log.Logger
+}
+
+// Archiver archives the completed orders.
+type Archiver struct {
+	orderRepository repository.IOrder
+	conf            *Config
+	log             *log.Logger
+}
+
+// NewArchiver returns a new Archiver.
+func NewArchiver(initArchiver *InitArchiver) *Archiver {
+	return &Archiver{
+		orderRepository: initArchiver.OrderRepository,
+		conf:            initArchiver.Config,
+		log:             initArchiver.Log,
+	}
+}
+
+// Run archives the orders due every interval until given context is done.
+func (a *Archiver) Run(ctx context.Context) {
+	for {
+		n, err := a.ArchiveOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			a.log.Error(ctx, "failed to archive the completed orders", zap.Error(err))
+		} else if n > 0 {
+			a.log.Info(ctx, "archived the completed orders", zap.Int("orders", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(a.conf.Interval):
+		}
+	}
+}
+
+// ArchiveOnce archives every order due, batch by batch, and returns their
+// number.
+func (a *Archiver) ArchiveOnce(ctx context.Context) (int, error) {
+	before := time.Now().UTC().AddDate(0, 0, -a.conf.AfterDays)
+	total := 0
+	for ctx.Err() == nil {
+		n, err := a.orderRepository.ArchiveCompletedOrders(ctx, before, a.conf.BatchSize)
+		total += n
+		metrics.OrdersArchived.WithLabelValues(metrics.ArchivedByArchiver).Add(float64(n))
+		if err != nil {
+			return total, err
+		}
+		if n < a.conf.BatchSize {
+			break
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,49 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchOrders archives batches of given sizes, then fails.
+type batchOrders struct {
+	repository.IOrder
+	batches []int
+	befores []time.Time
+}
+
+func (b *batchOrders) ArchiveCompletedOrders(_ context.Context, before time.Time, limit int) (int, error) {
+	b.befores = append(b.befores, before)
+	if len(b.batches) == 0 {
+		return 0, errors.New("no more batches")
+	}
+	n := min(b.batches[0], limit)
+	b.batches = b.batches[1:]
+	return n, nil
+}
+
+func TestArchiveOnce(t *testing.T) {
+	orders := &batchOrders{batches: []int{2, 2, 1, 2}}
+	archiver := NewArchiver(&InitArchiver{
+		OrderRepository: orders,
+		Config:          &Config{AfterDays: 30, BatchSize: 2},
+		Log:             log.New(),
+	})
+
+	n, err := archiver.ArchiveOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5, n, "archives until a batch isn't full")
+	require.Len(t, orders.befores, 3)
+	assert.WithinDuration(t, time.Now().UTC().AddDate(0, 0, -30), orders.befores[0], time.Minute)
+
+	n, err = archiver.ArchiveOnce(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 2, n)
+}
@@ -4,10 +4,11 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
+// Config of the Redis client.
 type Config struct {
-	Addr     string `json:"addr"`
-	Password string `json:"password"`
-	DB       int    `json:"db"`
+	Addr     string `json:"addr" yaml:"addr" toml:"addr" mapstructure:"addr"`
+	Password string `json:"password" yaml:"password" toml:"password" mapstructure:"password"`
+	DB       int    `json:"db" yaml:"db" toml:"db" mapstructure:"db"`
 }
 
 func New(config *Config) *redis.Client {
@@ -16,5 +17,7 @@ func New(config *Config) *redis.Client {
 		Password: config.Password,
 		DB:       config.DB,
 	})
+	rdb.AddHook(tracingHook{})
+	rdb.AddHook(metricsHook{})
 	return rdb
 }
@@ -1,28 +1,131 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/kaium123/order/internal/archive"
 	"github.com/kaium123/order/internal/cache"
 	"github.com/kaium123/order/internal/db"
 	"github.com/kaium123/order/internal/db/bundb"
+	"github.com/kaium123/order/internal/encryption"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/middleware"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/outbox"
+	"github.com/kaium123/order/internal/stream"
+	"github.com/kaium123/order/internal/tracing"
+	"github.com/kaium123/order/internal/webhook"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	_ "github.com/spf13/viper/remote"
-	"os"
 )
 
-// Config of entire application.
+// EnvPrefix is the prefix of environment variables overriding configuration keys,
+// e.g. ORDERS_DB_URL overrides db.url.
+const EnvPrefix = "ORDERS"
+
+// Environments the application runs in.
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
+)
+
+// FlagConfig is the name of the flag pointing to a configuration file.
+const FlagConfig = "config"
+
+// flagKeys maps command line flags to the configuration keys they override.
+var flagKeys = map[string]string{
+	"api-port":     "api_server.port",
+	"swagger-port": "swagger_server.port",
+	"admin-port":   "admin_server.port",
+	"db-url":       "db.url",
+	"redis-addr":   "redis.addr",
+}
+
+// Config of entire application. Fields tagged with reload:"true" are applied
+// to the running services on reload, others require a restart.
 type Config struct {
-	Url              string        `json:"url" yaml:"url" toml:"url" mapstructure:"url"`
-	DB               *bundb.Config `json:"db" yaml:"db" toml:"db" mapstructure:"db"` // nolint
-	MigrateDirection db.Direction  `json:"migrate"`
-	APIServer        Server        `json:"api_server" yaml:"api_server" toml:"api_server" mapstructure:"api_server"`
-	SwaggerServer    Server        `json:"swagger_server" yaml:"swagger_server" toml:"swagger_server" mapstructure:"swagger_server"`
-	Redis            *cache.Config `json:"redis" yaml:"redis" toml:"redis" mapstructure:"redis"`
+	Env              string            `json:"env" yaml:"env" toml:"env" mapstructure:"env"`
+	Url              string            `json:"url" yaml:"url" toml:"url" mapstructure:"url"`
+	DB               *bundb.Config     `json:"db" yaml:"db" toml:"db" mapstructure:"db"` // nolint
+	MigrateDirection db.Direction      `json:"migrate"`
+	APIServer        Server            `json:"api_server" yaml:"api_server" toml:"api_server" mapstructure:"api_server"`
+	SwaggerServer    Server            `json:"swagger_server" yaml:"swagger_server" toml:"swagger_server" mapstructure:"swagger_server"`
+	AdminServer      Server            `json:"admin_server" yaml:"admin_server" toml:"admin_server" mapstructure:"admin_server"`
+	Redis            *cache.Config     `json:"redis" yaml:"redis" toml:"redis" mapstructure:"redis"`
+	Tracing          tracing.Config    `json:"tracing" yaml:"tracing" toml:"tracing" mapstructure:"tracing"`
+	Encryption       encryption.Config `json:"encryption" yaml:"encryption" toml:"encryption" mapstructure:"encryption"`
+	Outbox           outbox.Config     `json:"outbox" yaml:"outbox" toml:"outbox" mapstructure:"outbox"`
+	Webhook          webhook.Config    `json:"webhook" yaml:"webhook" toml:"webhook" mapstructure:"webhook"`
+	Stream           stream.Config     `json:"stream" yaml:"stream" toml:"stream" mapstructure:"stream"`
+	Archive          archive.Config    `json:"archive" yaml:"archive" toml:"archive" mapstructure:"archive"`
+
+	Log          log.Config                 `json:"log" yaml:"log" toml:"log" mapstructure:"log" reload:"true"`
+	RateLimit    middleware.RateLimitConfig `json:"rate_limit" yaml:"rate_limit" toml:"rate_limit" mapstructure:"rate_limit" reload:"true"`
+	Pricing      model.Pricing              `json:"pricing" yaml:"pricing" toml:"pricing" mapstructure:"pricing" reload:"true"`
+	Cancellation model.Cancellation         `json:"cancellation" yaml:"cancellation" toml:"cancellation" mapstructure:"cancellation" reload:"true"`
+	Delivery     model.DeliveryRules        `json:"delivery" yaml:"delivery" toml:"delivery" mapstructure:"delivery" reload:"true"`
+	Features     model.Features             `json:"features" yaml:"features" toml:"features" mapstructure:"features" reload:"true"`
+
+	unknownKeys []string // keys of the sources not matching any field
 }
 
 // Server is the configuration for the server.
 type Server struct {
 	Enable bool `json:"enable" yaml:"enable" toml:"enable" mapstructure:"enable"`
 	Port   int  `json:"port" yaml:"port" toml:"port" mapstructure:"port"`
+	// DrainDelay is how long the server reports not ready before it stops
+	// accepting connections on shutdown.
+	DrainDelay time.Duration `json:"drain_delay" yaml:"drain_delay" toml:"drain_delay" mapstructure:"drain_delay"`
+}
+
+// Source describes where the Config is read from. The layers are applied in
+// order: defaults, File, Consul, ORDERS_* environment variables and Flags.
+// Every layer except the defaults is optional.
+type Source struct {
+	// File is a yaml, json or toml configuration file.
+	File string
+	// ConsulURL and ConsulPath point to a yaml document in the Consul KV store.
+	ConsulURL  string
+	ConsulPath string
+	// Flags override every other layer when set on the command line.
+	Flags *pflag.FlagSet
+}
+
+// NewSource returns a Source built from the --config flag of given flag set
+// and the ORDERS_CONFIG, ORDERS_CONSUL_URL and ORDERS_CONSUL_PATH environment
+// variables. The flag wins over ORDERS_CONFIG.
+func NewSource(fs *pflag.FlagSet) *Source {
+	src := &Source{
+		File:       os.Getenv(EnvPrefix + "_CONFIG"),
+		ConsulURL:  os.Getenv(EnvPrefix + "_CONSUL_URL"),
+		ConsulPath: os.Getenv(EnvPrefix + "_CONSUL_PATH"),
+		Flags:      fs,
+	}
+	if fs != nil {
+		if file, err := fs.GetString(FlagConfig); err == nil && file != "" {
+			src.File = file
+		}
+	}
+	return src
+}
+
+// RegisterFlags adds the configuration flags to given flag set.
+func RegisterFlags(fs *pflag.FlagSet) {
+	fs.String(FlagConfig, "", "path to a yaml, json or toml configuration file")
+	fs.Int("api-port", 0, "port of the API server")
+	fs.Int("swagger-port", 0, "port of the Swagger server")
+	fs.Int("admin-port", 0, "port of the admin server serving the metrics")
+	fs.String("db-url", "", "database connection URL")
+	fs.String("redis-addr", "", "Redis address")
 }
 
 // New default configurations.
@@ -31,29 +134,214 @@ func New() (conf *Config) {
 	return
 }
 
-// Load the Config from configuration files. This method panics on error.
-func (c *Config) Load() *Config {
+// setDefaults registers the default value of every key. Keys must be known to
+// viper for the environment variables to be picked up by Unmarshal.
+func setDefaults(v *viper.Viper) {
+	// the guards of production hold unless another environment is given
+	v.SetDefault("env", EnvProduction)
+	v.SetDefault("url", "")
+	v.SetDefault("migratedirection", "")
+
+	v.SetDefault("api_server.enable", true)
+	v.SetDefault("api_server.port", 8601)
+	v.SetDefault("api_server.drain_delay", "5s")
+	v.SetDefault("swagger_server.enable", false)
+	v.SetDefault("swagger_server.port", 3001)
+	v.SetDefault("swagger_server.drain_delay", "0s")
+	v.SetDefault("admin_server.enable", true)
+	v.SetDefault("admin_server.port", 8602)
+	v.SetDefault("admin_server.drain_delay", "0s")
+
+	v.SetDefault("redis.addr", "localhost:6379")
+	v.SetDefault("redis.password", "")
+	v.SetDefault("redis.db", 0)
+
+	v.SetDefault("db.url", "")
+	v.SetDefault("db.dial_timeout", "5s")
+	v.SetDefault("db.idle_timeout", "5m")
+	v.SetDefault("db.read_timeout", "5s")
+	v.SetDefault("db.write_timeout", "5s")
+	v.SetDefault("db.retry_statement_timeout", false)
+	v.SetDefault("db.max_retries", 5)
+	v.SetDefault("db.max_retry_backoff", "5s")
+	v.SetDefault("db.pool_size", 10)
+	v.SetDefault("db.pool_timeout", "5s")
+	v.SetDefault("db.tls.ca_file", "")
+	v.SetDefault("db.tls.server_name", "")
+	v.SetDefault("db.replicas", []string{})
+	v.SetDefault("db.replica_check_interval", "5s")
+	v.SetDefault("db.replica_max_lag", "5s")
+	v.SetDefault("db.query_log.enable", true)
+	v.SetDefault("db.query_log.slow_threshold", "200ms")
+	v.SetDefault("db.query_log.explain", false)
 
-	consulPath := os.Getenv("ORDERS_CONSUL_PATH")
-	consulURL := os.Getenv("ORDERS_CONSUL_URL")
+	v.SetDefault("log.level", "debug")
 
-	viper.AddRemoteProvider("consul", consulURL, consulPath)
-	viper.SetConfigType("yaml") // Need to explicitly set this to json
+	v.SetDefault("encryption.enable", false)
+	v.SetDefault("encryption.active_key", "")
+	v.SetDefault("encryption.keys", map[string]string{})
+	v.SetDefault("encryption.blind_index_key", "")
 
-	err := viper.ReadRemoteConfig()
-	if err != nil {
+	v.SetDefault("outbox.enable", true)
+	v.SetDefault("outbox.poll_interval", "1s")
+	v.SetDefault("outbox.batch_size", 100)
+	v.SetDefault("outbox.publish_timeout", "5s")
+	v.SetDefault("outbox.retention", "168h")
+	v.SetDefault("outbox.redis.enable", true)
+	v.SetDefault("outbox.redis.stream", "orders:events")
+	v.SetDefault("outbox.redis.max_len", 100000)
+	v.SetDefault("outbox.redis.channel", "orders:updates")
+	v.SetDefault("outbox.nats.enable", false)
+	v.SetDefault("outbox.nats.url", "nats://localhost:4222")
+	v.SetDefault("outbox.nats.subject_prefix", "orders")
+	v.SetDefault("outbox.nats.jetstream", false)
+	v.SetDefault("webhook.enable", true)
+	v.SetDefault("webhook.poll_interval", "1s")
+	v.SetDefault("webhook.batch_size", 50)
+	v.SetDefault("webhook.timeout", "10s")
+	v.SetDefault("webhook.max_attempts", 8)
+	v.SetDefault("webhook.min_backoff", "30s")
+	v.SetDefault("webhook.max_backoff", "1h")
+	v.SetDefault("webhook.allow_private_networks", false)
+	v.SetDefault("stream.enable", true)
+	v.SetDefault("stream.heartbeat", "15s")
+	v.SetDefault("stream.replay_limit", 500)
+	v.SetDefault("stream.buffer_size", 64)
+	v.SetDefault("archive.enable", true)
+	v.SetDefault("archive.after_days", 30)
+	v.SetDefault("archive.interval", "1h")
+	v.SetDefault("archive.batch_size", 1000)
+
+	v.SetDefault("tracing.exporter", tracing.ExporterNone)
+	v.SetDefault("tracing.endpoint", "")
+	v.SetDefault("tracing.insecure", false)
+	v.SetDefault("tracing.file", "")
+	v.SetDefault("tracing.sample_ratio", 1)
+	v.SetDefault("tracing.service_name", "orders")
+
+	v.SetDefault("rate_limit.enable", false)
+	v.SetDefault("rate_limit.rate", 10)
+	v.SetDefault("rate_limit.burst", 20)
+	v.SetDefault("rate_limit.expires_in", "3m")
+
+	v.SetDefault("pricing.inside_city_id", 1)
+	v.SetDefault("pricing.inside_city_fee", 60)
+	v.SetDefault("pricing.outside_city_fee", 100)
+	v.SetDefault("pricing.cod_percentage", 1)
+	v.SetDefault("pricing.rto_percentage", 50)
+
+	v.SetDefault("cancellation.reasons", []string{
+		"customer_request", "duplicate_order", "wrong_address", "out_of_stock", "other",
+	})
+
+	v.SetDefault("delivery.max_failed_attempts", 3)
+
+	v.SetDefault("features."+model.FeatureSearchHighlights, true)
+}
+
+// Load the Config from given Source. This method panics on error.
+func (c *Config) Load(src *Source) *Config {
+	if err := c.Read(src); err != nil {
 		panic(err)
 	}
-	viper.Unmarshal(c)
+	return c
+}
 
-	migrate := c.MigrateDirection
-	c.MigrateDirection = db.Direction(migrate)
-	if err = c.MigrateDirection.Check(); err != nil {
-		panic(err)
+// Read the Config from given Source and validate it.
+func (c *Config) Read(src *Source) (err error) {
+	var v *viper.Viper
+	if v, err = newViper(src); err != nil {
+		return
 	}
 
-	return c
+	var md mapstructure.Metadata
+	if err = v.Unmarshal(c, func(dc *mapstructure.DecoderConfig) {
+		dc.Metadata = &md
+		dc.DecodeHook = mapstructure.ComposeDecodeHookFunc(dc.DecodeHook, stringToMapHook)
+	}); err != nil {
+		return fmt.Errorf("failed to decode configuration: %w", err)
+	}
+	c.unknownKeys = md.Unused
+	sort.Strings(c.unknownKeys)
+
+	return c.Validate()
+}
+
+// newViper returns a viper instance with every layer of given Source applied.
+func newViper(src *Source) (v *viper.Viper, err error) {
+	if src == nil {
+		src = new(Source)
+	}
+
+	v = viper.New()
+	setDefaults(v)
+
+	if src.File != "" {
+		v.SetConfigFile(src.File)
+		if err = v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", src.File, err)
+		}
+	}
+
+	if src.ConsulURL != "" {
+		var remote *viper.Viper
+		if remote, err = readConsul(src.ConsulURL, src.ConsulPath); err != nil {
+			return
+		}
+		// merge the Consul layer on top of the file
+		if err = v.MergeConfigMap(remote.AllSettings()); err != nil {
+			return nil, fmt.Errorf("failed to merge consul config: %w", err)
+		}
+	}
+
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	if src.Flags != nil {
+		for name, key := range flagKeys {
+			if flag := src.Flags.Lookup(name); flag != nil {
+				if err = v.BindPFlag(key, flag); err != nil {
+					return nil, fmt.Errorf("failed to bind flag %s: %w", name, err)
+				}
+			}
+		}
+	}
+
+	return
+}
 
+// stringToMapHook decodes the "id:value,id2:value2" strings of the
+// environment, e.g. ORDERS_ENCRYPTION_KEYS, into maps of strings.
+func stringToMapHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(map[string]string{}) {
+		return data, nil
+	}
+	m := map[string]string{}
+	for _, pair := range strings.Split(data.(string), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		id, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("%q is not an id:value pair", pair)
+		}
+		m[id] = value
+	}
+	return m, nil
+}
+
+// readConsul reads the yaml document stored at given path of the Consul KV.
+func readConsul(url, path string) (remote *viper.Viper, err error) {
+	remote = viper.New()
+	remote.SetConfigType("yaml")
+	if err = remote.AddRemoteProvider("consul", url, path); err != nil {
+		return nil, fmt.Errorf("failed to add consul provider: %w", err)
+	}
+	if err = remote.ReadRemoteConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read consul config %s/%s: %w", url, path, err)
+	}
+	return
 }
 
 // MigrationDirectionFlag returns migration direction and migrateOnly flag
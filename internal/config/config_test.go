@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestReadDefaults(t *testing.T) {
+	t.Setenv("ORDERS_DB_URL", "postgresql://env")
+
+	conf := New()
+	require.NoError(t, conf.Read(&Source{}))
+
+	assert.Equal(t, 8601, conf.APIServer.Port)
+	assert.Equal(t, "localhost:6379", conf.Redis.Addr)
+	assert.Equal(t, 5*time.Second, conf.DB.DialTimeout)
+	assert.Equal(t, EnvProduction, conf.Env, "the guards hold unless told otherwise")
+}
+
+func TestReadLayers(t *testing.T) {
+	path := writeConfigFile(t, `
+api_server:
+  port: 9000
+swagger_server:
+  port: 9001
+redis:
+  addr: "file:6379"
+db:
+  url: "postgresql://file"
+`)
+	t.Setenv("ORDERS_REDIS_ADDR", "env:6379")
+	t.Setenv("ORDERS_SWAGGER_SERVER_PORT", "9101")
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{"--config", path, "--swagger-port", "9201"}))
+
+	conf := New()
+	require.NoError(t, conf.Read(NewSource(fs)))
+
+	assert.Equal(t, 9000, conf.APIServer.Port)        // file
+	assert.Equal(t, "postgresql://file", conf.DB.URL) // file
+	assert.Equal(t, "env:6379", conf.Redis.Addr)      // env over file
+	assert.Equal(t, 9201, conf.SwaggerServer.Port)    // flag over env
+}
+
+func TestReadMissingFile(t *testing.T) {
+	err := New().Read(&Source{File: filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/metrics"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// Hash returns a short hash of the configuration.
+func (c *Config) Hash() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// RestartRequired returns the keys of the settings that differ between the
+// configurations and can't be applied without a restart.
+func (c *Config) RestartRequired(next *Config) (keys []string) {
+	cv, nv := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	ct := cv.Type()
+	for i := 0; i < ct.NumField(); i++ {
+		field := ct.Field(i)
+		if !field.IsExported() || field.Tag.Get("reload") == "true" {
+			continue
+		}
+		if !reflect.DeepEqual(cv.Field(i).Interface(), nv.Field(i).Interface()) {
+			key := field.Tag.Get("mapstructure")
+			if key == "" {
+				key = field.Name
+			}
+			keys = append(keys, key)
+		}
+	}
+	return
+}
+
+// Listener is called with the new Config on every successful reload.
+type Listener func(conf *Config)
+
+// Reloader keeps the current Config and re-reads it from the Source on
+// changes. The current Config is replaced atomically, readers should call
+// Current every time they need a reloadable setting.
+type Reloader struct {
+	src       *Source
+	log       *log.Logger
+	current   atomic.Pointer[Config]
+	mu        sync.Mutex // serializes reloads and protects listeners
+	listeners []Listener
+}
+
+// NewReloader returns a Reloader started with given configuration.
+func NewReloader(src *Source, conf *Config, logger *log.Logger) *Reloader {
+	r := &Reloader{
+		src: src,
+		log: logger.Named("config"),
+	}
+	r.current.Store(conf)
+	metrics.SetConfigHash(conf.Hash())
+	return r
+}
+
+// Current returns the current Config. It must not be modified.
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// OnReload registers given listener.
+func (r *Reloader) OnReload(listener Listener) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, listener)
+}
+
+// Reload re-reads the Config from the Source. An unchanged configuration
+// isn't replaced, the listeners aren't called, but the reload is logged and
+// counted as unchanged.
+func (r *Reloader) Reload(ctx context.Context) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := New()
+	if err = next.Read(r.src); err != nil {
+		metrics.ConfigReloads.WithLabelValues("error").Inc()
+		r.log.Error(ctx, "failed to reload configuration", zap.Error(err))
+		return
+	}
+
+	prev := r.current.Load()
+	prevHash, nextHash := prev.Hash(), next.Hash()
+	if prevHash == nextHash {
+		metrics.ConfigReloads.WithLabelValues("unchanged").Inc()
+		metrics.SetConfigHash(nextHash)
+		r.log.Info(ctx, "configuration unchanged", zap.String("hash", nextHash))
+		return
+	}
+
+	r.current.Store(next)
+	for _, listener := range r.listeners {
+		listener(next)
+	}
+
+	metrics.ConfigReloads.WithLabelValues("success").Inc()
+	metrics.SetConfigHash(nextHash)
+
+	fields := []zap.Field{zap.String("previous_hash", prevHash), zap.String("hash", nextHash)}
+	if keys := prev.RestartRequired(next); len(keys) > 0 {
+		fields = append(fields, zap.Strings("restart_required", keys))
+	}
+	r.log.Info(ctx, "configuration reloaded", fields...)
+	return
+}
+
+// Watch reloads the Config on every change of the Consul key until the
+// context is done. It returns immediately when the Source has no Consul.
+func (r *Reloader) Watch(ctx context.Context) {
+	if r.src == nil || r.src.ConsulURL == "" {
+		return
+	}
+
+	provider := &consulProvider{endpoint: r.src.ConsulURL, path: r.src.ConsulPath}
+	changes, quit := viper.RemoteConfig.WatchChannel(provider)
+	if changes == nil {
+		r.log.Error(ctx, "failed to watch consul configuration")
+		return
+	}
+	defer close(quit)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case resp := <-changes:
+			if resp.Error != nil {
+				r.log.Error(ctx, "failed to watch consul configuration", zap.Error(resp.Error))
+				continue
+			}
+			// the first response is the current value, Reload ignores
+			// unchanged configurations
+			_ = r.Reload(ctx)
+		}
+	}
+}
+
+// consulProvider implements viper.RemoteProvider.
+type consulProvider struct {
+	endpoint string
+	path     string
+}
+
+func (p *consulProvider) Provider() string      { return "consul" }
+func (p *consulProvider) Endpoint() string      { return p.endpoint }
+func (p *consulProvider) Path() string          { return p.path }
+func (p *consulProvider) SecretKeyring() string { return "" }
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/metrics"
+	"github.com/kaium123/order/internal/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloaderReload(t *testing.T) {
+	t.Setenv("ORDERS_DB_URL", "postgresql://env")
+	path := writeConfigFile(t, `
+api_server:
+  port: 9000
+pricing:
+  inside_city_fee: 60
+`)
+	src := &Source{File: path}
+	conf := New()
+	require.NoError(t, conf.Read(src))
+
+	r := NewReloader(src, conf, log.New())
+	assert.True(t, r.Current().Features.Enabled(model.FeatureSearchHighlights))
+
+	var reloaded *Config
+	r.OnReload(func(conf *Config) { reloaded = conf })
+
+	// unchanged configuration is not reloaded, but counted
+	unchanged := testutil.ToFloat64(metrics.ConfigReloads.WithLabelValues("unchanged"))
+	require.NoError(t, r.Reload(context.Background()))
+	assert.Nil(t, reloaded)
+	assert.Equal(t, unchanged+1, testutil.ToFloat64(metrics.ConfigReloads.WithLabelValues("unchanged")))
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+api_server:
+  port: 9100
+pricing:
+  inside_city_fee: 70
+features:
+  search_highlights: false
+`), 0o600))
+	require.NoError(t, r.Reload(context.Background()))
+
+	require.NotNil(t, reloaded)
+	assert.Equal(t, 70.0, r.Current().Pricing.InsideCityFee)
+	assert.False(t, r.Current().Features.Enabled(model.FeatureSearchHighlights), "the flags are reloaded")
+	assert.Equal(t, []string{"api_server"}, conf.RestartRequired(reloaded))
+	assert.NotEqual(t, conf.Hash(), reloaded.Hash())
+}
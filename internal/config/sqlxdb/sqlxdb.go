@@ -114,10 +114,10 @@ func (db *DB) Close() error {
 // sql.ErrNoRows. Otherwise, it return the err. Use it in queries
 // with corresponding 'not found error' from your model.
 //
-//     if user, err = mydb.User(ctx, query); err != nil {
-//         return nil, db.NotFoundError(err, model.ErrUserNotFound)
-//     }
-//     return
+//	if user, err = mydb.User(ctx, query); err != nil {
+//	    return nil, db.NotFoundError(err, model.ErrUserNotFound)
+//	}
+//	return
 //
 // Or any other case.
 //
@@ -0,0 +1,288 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kaium123/order/internal/db/bundb"
+	"github.com/kaium123/order/internal/tracing"
+	"go.uber.org/zap/zapcore"
+)
+
+// ValidationErrors lists every problem found in a configuration.
+type ValidationErrors []string
+
+// Error implements error.
+func (e ValidationErrors) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// add a problem with given key.
+func (e *ValidationErrors) add(key, format string, args ...interface{}) {
+	*e = append(*e, key+": "+fmt.Sprintf(format, args...))
+}
+
+// publishedKeys were committed to the sample configurations and must not seal
+// real data.
+var publishedKeys = map[string]bool{
+	"OoxAlLUa83ve3eSHjDt1nMZXZSXzD9PUXJW8cAWhOC0=": true,
+	"sZzMrDdy+RBhAijUwj6bDSQEdMJJJTutLT9JKpZrSLg=": true,
+}
+
+// Validate checks required fields, ranges and unknown keys of the Config. It
+// returns ValidationErrors with every problem found or nil.
+func (c *Config) Validate() error {
+	var errs ValidationErrors
+
+	for _, key := range c.unknownKeys {
+		errs.add(key, "unknown key")
+	}
+
+	switch c.Env {
+	case EnvDevelopment, EnvTest, EnvStaging, EnvProduction:
+	default:
+		errs.add("env", "must be one of %s, %s, %s or %s, got %q",
+			EnvDevelopment, EnvTest, EnvStaging, EnvProduction, c.Env)
+	}
+
+	if err := c.MigrateDirection.Check(); err != nil {
+		errs.add("migratedirection", "%v", err)
+	}
+
+	servers := []struct {
+		key    string
+		server Server
+	}{
+		{"api_server", c.APIServer},
+		{"swagger_server", c.SwaggerServer},
+		{"admin_server", c.AdminServer},
+	}
+	for i, s := range servers {
+		validateServer(&errs, s.key, s.server)
+		for _, other := range servers[:i] {
+			if s.server.Enable && other.server.Enable && s.server.Port == other.server.Port {
+				errs.add(s.key+".port", "must differ from %s.port", other.key)
+			}
+		}
+	}
+
+	if c.DB == nil || c.DB.URL == "" {
+		errs.add("db.url", "is required")
+	} else {
+		if u, err := url.Parse(c.DB.URL); err != nil {
+			errs.add("db.url", "is not a valid URL")
+		} else if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+			errs.add("db.url", "must use the postgres or postgresql scheme, got %q", u.Scheme)
+		} else if mode := u.Query().Get("sslmode"); !bundb.ValidSSLMode(mode) {
+			errs.add("db.url", "unsupported sslmode %q", mode)
+		}
+	}
+	if c.DB != nil {
+		if c.DB.PoolSize <= 0 {
+			errs.add("db.pool_size", "must be greater than 0, got %d", c.DB.PoolSize)
+		}
+		if c.DB.MaxRetries < 0 {
+			errs.add("db.max_retries", "must not be negative, got %d", c.DB.MaxRetries)
+		}
+		validateDuration(&errs, "db.dial_timeout", c.DB.DialTimeout)
+		validateDuration(&errs, "db.idle_timeout", c.DB.IdleTimeout)
+		validateDuration(&errs, "db.read_timeout", c.DB.ReadTimeout)
+		validateDuration(&errs, "db.write_timeout", c.DB.WriteTimeout)
+		validateDuration(&errs, "db.max_retry_backoff", c.DB.MaxRetryBackoff)
+		validateDuration(&errs, "db.pool_timeout", c.DB.PoolTimeout)
+		for i, replica := range c.DB.Replicas {
+			key := fmt.Sprintf("db.replicas[%d]", i)
+			if u, err := url.Parse(replica); err != nil {
+				errs.add(key, "is not a valid URL")
+			} else if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+				errs.add(key, "must use the postgres or postgresql scheme, got %q", u.Scheme)
+			} else if mode := u.Query().Get("sslmode"); !bundb.ValidSSLMode(mode) {
+				errs.add(key, "unsupported sslmode %q", mode)
+			}
+		}
+		if len(c.DB.Replicas) > 0 && c.DB.ReplicaCheckInterval <= 0 {
+			errs.add("db.replica_check_interval", "must be positive with replicas, got %s", c.DB.ReplicaCheckInterval)
+		}
+		validateDuration(&errs, "db.replica_max_lag", c.DB.ReplicaMaxLag)
+		if c.DB.RetryStatementTimeout {
+			errs.add("db.retry_statement_timeout", "is not supported by the database driver")
+		}
+		if c.DB.TLS.CAFile != "" {
+			if _, err := os.Stat(c.DB.TLS.CAFile); err != nil {
+				errs.add("db.tls.ca_file", "is not readable: %v", err)
+			}
+		}
+		validateDuration(&errs, "db.query_log.slow_threshold", c.DB.QueryLog.SlowThreshold)
+		if c.DB.QueryLog.Explain && c.Env != EnvDevelopment && c.Env != EnvTest {
+			errs.add("db.query_log.explain", "is only allowed in the development and test environments")
+		}
+	}
+
+	if c.Redis == nil || c.Redis.Addr == "" {
+		errs.add("redis.addr", "is required")
+	}
+	if c.Redis != nil && (c.Redis.DB < 0 || c.Redis.DB > 15) {
+		errs.add("redis.db", "must be between 0 and 15, got %d", c.Redis.DB)
+	}
+
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+		errs.add("log.level", "unknown level %q", c.Log.Level)
+	}
+
+	for _, problem := range c.Encryption.Validate() {
+		errs.add("encryption."+problem.Key, "%s", problem.Message)
+	}
+	if c.Env != EnvDevelopment && c.Env != EnvTest {
+		ids := make([]string, 0, len(c.Encryption.Keys))
+		for id := range c.Encryption.Keys {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			if publishedKeys[c.Encryption.Keys[id]] {
+				errs.add("encryption.keys."+id, "is a published key, only allowed in the development and test environments")
+			}
+		}
+		if publishedKeys[c.Encryption.BlindIndexKey] {
+			errs.add("encryption.blind_index_key", "is a published key, only allowed in the development and test environments")
+		}
+	}
+
+	if c.Outbox.Enable {
+		if c.Outbox.PollInterval <= 0 {
+			errs.add("outbox.poll_interval", "must be positive, got %s", c.Outbox.PollInterval)
+		}
+		if c.Outbox.BatchSize <= 0 {
+			errs.add("outbox.batch_size", "must be greater than 0, got %d", c.Outbox.BatchSize)
+		}
+		if !c.Outbox.Redis.Enable && !c.Outbox.NATS.Enable && !c.Webhook.Enable {
+			errs.add("outbox", "enable the redis or nats sink or the webhooks, or disable the relay")
+		}
+	}
+	validateDuration(&errs, "outbox.publish_timeout", c.Outbox.PublishTimeout)
+	validateDuration(&errs, "outbox.retention", c.Outbox.Retention)
+	if c.Outbox.Redis.Enable && c.Outbox.Redis.Stream == "" {
+		errs.add("outbox.redis.stream", "is required")
+	}
+	if c.Outbox.Redis.MaxLen < 0 {
+		errs.add("outbox.redis.max_len", "must not be negative, got %d", c.Outbox.Redis.MaxLen)
+	}
+	if c.Outbox.NATS.Enable {
+		if c.Outbox.NATS.URL == "" {
+			errs.add("outbox.nats.url", "is required")
+		}
+		if c.Outbox.NATS.SubjectPrefix == "" {
+			errs.add("outbox.nats.subject_prefix", "is required")
+		}
+	}
+
+	if c.Webhook.Enable {
+		if c.Webhook.PollInterval <= 0 {
+			errs.add("webhook.poll_interval", "must be positive, got %s", c.Webhook.PollInterval)
+		}
+		if c.Webhook.BatchSize <= 0 {
+			errs.add("webhook.batch_size", "must be greater than 0, got %d", c.Webhook.BatchSize)
+		}
+		if c.Webhook.MaxAttempts <= 0 {
+			errs.add("webhook.max_attempts", "must be greater than 0, got %d", c.Webhook.MaxAttempts)
+		}
+		if c.Webhook.MinBackoff <= 0 {
+			errs.add("webhook.min_backoff", "must be positive, got %s", c.Webhook.MinBackoff)
+		}
+		if c.Webhook.MaxBackoff < c.Webhook.MinBackoff {
+			errs.add("webhook.max_backoff", "must not be less than webhook.min_backoff, got %s", c.Webhook.MaxBackoff)
+		}
+	}
+	if c.Webhook.Timeout <= 0 {
+		errs.add("webhook.timeout", "must be positive, got %s", c.Webhook.Timeout)
+	}
+
+	if c.Stream.Enable {
+		if !c.Outbox.Redis.Enable || c.Outbox.Redis.Channel == "" {
+			errs.add("stream.enable", "requires outbox.redis.enable and outbox.redis.channel")
+		}
+		if c.Stream.Heartbeat <= 0 {
+			errs.add("stream.heartbeat", "must be positive, got %s", c.Stream.Heartbeat)
+		}
+		if c.Stream.BufferSize <= 0 {
+			errs.add("stream.buffer_size", "must be greater than 0, got %d", c.Stream.BufferSize)
+		}
+	}
+	if c.Stream.ReplayLimit < 0 {
+		errs.add("stream.replay_limit", "must not be negative, got %d", c.Stream.ReplayLimit)
+	}
+
+	if c.Archive.Enable {
+		if c.Archive.AfterDays <= 0 {
+			errs.add("archive.after_days", "must be greater than 0, got %d", c.Archive.AfterDays)
+		}
+		if c.Archive.Interval <= 0 {
+			errs.add("archive.interval", "must be positive, got %s", c.Archive.Interval)
+		}
+		if c.Archive.BatchSize <= 0 {
+			errs.add("archive.batch_size", "must be greater than 0, got %d", c.Archive.BatchSize)
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		errs.add("tracing.exporter", "must be one of none, otlp or stdout, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs.add("tracing.sample_ratio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	if c.RateLimit.Enable && c.RateLimit.Rate <= 0 {
+		errs.add("rate_limit.rate", "must be greater than 0 when enabled, got %v", c.RateLimit.Rate)
+	}
+	if c.RateLimit.Burst < 0 {
+		errs.add("rate_limit.burst", "must not be negative, got %d", c.RateLimit.Burst)
+	}
+
+	if c.Pricing.InsideCityFee < 0 {
+		errs.add("pricing.inside_city_fee", "must not be negative, got %v", c.Pricing.InsideCityFee)
+	}
+	if c.Pricing.OutsideCityFee < 0 {
+		errs.add("pricing.outside_city_fee", "must not be negative, got %v", c.Pricing.OutsideCityFee)
+	}
+	if c.Pricing.CODPercentage < 0 || c.Pricing.CODPercentage > 100 {
+		errs.add("pricing.cod_percentage", "must be between 0 and 100, got %v", c.Pricing.CODPercentage)
+	}
+	if c.Pricing.RTOPercentage < 0 || c.Pricing.RTOPercentage > 100 {
+		errs.add("pricing.rto_percentage", "must be between 0 and 100, got %v", c.Pricing.RTOPercentage)
+	}
+	if len(c.Cancellation.Reasons) == 0 {
+		errs.add("cancellation.reasons", "is required")
+	}
+	for _, reason := range c.Cancellation.Reasons {
+		if reason == "" || reason != strings.ToLower(strings.TrimSpace(reason)) {
+			errs.add("cancellation.reasons", "must be lower case codes, got %q", reason)
+		}
+	}
+	if c.Delivery.MaxFailedAttempts < 1 {
+		errs.add("delivery.max_failed_attempts", "must be at least 1, got %d", c.Delivery.MaxFailedAttempts)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateServer(errs *ValidationErrors, key string, s Server) {
+	if s.Enable && (s.Port <= 0 || s.Port > 65535) {
+		errs.add(key+".port", "must be between 1 and 65535, got %d", s.Port)
+	}
+	validateDuration(errs, key+".drain_delay", s.DrainDelay)
+}
+
+func validateDuration(errs *ValidationErrors, key string, d time.Duration) {
+	if d < 0 {
+		errs.add(key, "must not be negative, got %s", d)
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	path := writeConfigFile(t, `
+api_server:
+  port: 70000
+swagger_server:
+  enable: true
+  port: 3001
+db:
+  url: "mysql://localhost"
+  pool_size: 0
+  max_connections: 10
+log:
+  level: loud
+pricing:
+  cod_percentage: 120
+`)
+
+	err := New().Read(&Source{File: path})
+	require.Error(t, err)
+
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, ValidationErrors{
+		"db.max_connections: unknown key",
+		"api_server.port: must be between 1 and 65535, got 70000",
+		`db.url: must use the postgres or postgresql scheme, got "mysql"`,
+		"db.pool_size: must be greater than 0, got 0",
+		`log.level: unknown level "loud"`,
+		"pricing.cod_percentage: must be between 0 and 100, got 120",
+	}, errs)
+}
+
+func TestValidateValid(t *testing.T) {
+	path := writeConfigFile(t, `
+db:
+  url: "postgresql://root@localhost/orders"
+`)
+	assert.NoError(t, New().Read(&Source{File: path}))
+}
+
+func TestValidatePublishedKeys(t *testing.T) {
+	path := writeConfigFile(t, `
+env: staging
+db:
+  url: "postgresql://root@localhost/orders"
+encryption:
+  enable: true
+  active_key: v1
+`)
+	t.Setenv("ORDERS_ENCRYPTION_KEYS", "v1:OoxAlLUa83ve3eSHjDt1nMZXZSXzD9PUXJW8cAWhOC0=")
+	t.Setenv("ORDERS_ENCRYPTION_BLIND_INDEX_KEY", "sZzMrDdy+RBhAijUwj6bDSQEdMJJJTutLT9JKpZrSLg=")
+
+	err := New().Read(&Source{File: path})
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, ValidationErrors{
+		"encryption.keys.v1: is a published key, only allowed in the development and test environments",
+		"encryption.blind_index_key: is a published key, only allowed in the development and test environments",
+	}, errs)
+
+	t.Setenv("ORDERS_ENV", "development")
+	conf := New()
+	require.NoError(t, conf.Read(&Source{File: path}))
+	assert.Equal(t, map[string]string{"v1": "OoxAlLUa83ve3eSHjDt1nMZXZSXzD9PUXJW8cAWhOC0="}, conf.Encryption.Keys, "the keys are read from the environment")
+}
@@ -2,29 +2,51 @@ package bundb
 
 import (
 	"context"
-	"crypto/tls"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/metrics"
+	"github.com/kaium123/order/internal/tracing"
 	"github.com/uptrace/bun/dialect/pgdialect"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/driver/pgdriver"
 )
 
-// Config for DB.
+// Config for DB. The timeouts set here override the ones of the URL.
 type Config struct {
-	URL                   string        `json:"url" yaml:"ulr" toml:"url" mapstructure:"url"`                                                                                 // nolint
-	DialTimeout           time.Duration `json:"dial_timeout" yaml:"dial_timeout" toml:"dial_timeout" mapstructure:"dial_timeout"`                                             // nolint
-	IdleTimeout           time.Duration `json:"idle_timeout" yaml:"idle_timeout" toml:"idle_timeout" mapstructure:"idle_timeout"`                                             // nolint
-	ReadTimeout           time.Duration `json:"read_timeout" yaml:"read_timeout" toml:"read_timeout" mapstructure:"read_timeout"`                                             // nolint
-	WriteTimeout          time.Duration `json:"write_timeout" yaml:"write_timeout" toml:"write_timeout" mapstructure:"write_timeout"`                                         // nolint
-	RetryStatementTimeout bool          `json:"retry_statement_timeout" yaml:"retry_statement_timeout" toml:"retry_statement_timeout" mapstructure:"retry_statement_timeout"` // nolint
-	MaxRetries            int           `json:"max_retries" yaml:"max_retries" toml:"max_retries" mapstructure:"max_retries"`                                                 // nolint
-	MaxRetryBackoff       time.Duration `json:"max_retry_backoff" yaml:"max_retry_backoff" toml:"max_retry_backoff" mapstructure:"max_retry_backoff"`                         // nolint
-	PoolSize              int           `json:"pool_size" yaml:"pool_size" toml:"pool_size" mapstructure:"pool_size"`                                                         // nolint
-	PoolTimeout           time.Duration `json:"pool_timeout" yaml:"pool_timeout" toml:"pool_timeout" mapstructure:"pool_timeout"`                                             // nolint
+	URL          string        `json:"url" yaml:"url" toml:"url" mapstructure:"url"`                                         // nolint
+	DialTimeout  time.Duration `json:"dial_timeout" yaml:"dial_timeout" toml:"dial_timeout" mapstructure:"dial_timeout"`     // nolint
+	IdleTimeout  time.Duration `json:"idle_timeout" yaml:"idle_timeout" toml:"idle_timeout" mapstructure:"idle_timeout"`     // nolint
+	ReadTimeout  time.Duration `json:"read_timeout" yaml:"read_timeout" toml:"read_timeout" mapstructure:"read_timeout"`     // nolint
+	WriteTimeout time.Duration `json:"write_timeout" yaml:"write_timeout" toml:"write_timeout" mapstructure:"write_timeout"` // nolint
+	// Deprecated: RetryStatementTimeout is a go-pg setting, pgdriver doesn't
+	// retry the statements.
+	RetryStatementTimeout bool `json:"retry_statement_timeout" yaml:"retry_statement_timeout" toml:"retry_statement_timeout" mapstructure:"retry_statement_timeout"` // nolint
+	// MaxRetries of the connection at startup, backing off exponentially up
+	// to MaxRetryBackoff. 0 disables the retries.
+	MaxRetries      int           `json:"max_retries" yaml:"max_retries" toml:"max_retries" mapstructure:"max_retries"`                         // nolint
+	MaxRetryBackoff time.Duration `json:"max_retry_backoff" yaml:"max_retry_backoff" toml:"max_retry_backoff" mapstructure:"max_retry_backoff"` // nolint
+	PoolSize        int           `json:"pool_size" yaml:"pool_size" toml:"pool_size" mapstructure:"pool_size"`                                 // nolint
+	// PoolTimeout bounds every connection attempt at startup, database/sql
+	// bounds the wait for a free connection by the context of the query.
+	PoolTimeout time.Duration `json:"pool_timeout" yaml:"pool_timeout" toml:"pool_timeout" mapstructure:"pool_timeout"` // nolint
+	TLS         TLSConfig     `json:"tls" yaml:"tls" toml:"tls" mapstructure:"tls"`                                     // nolint
+	// Replicas are the URLs of the read replicas, sharing the other settings
+	// of the primary.
+	Replicas []string `json:"replicas" yaml:"replicas" toml:"replicas" mapstructure:"replicas"` // nolint
+	// ReplicaCheckInterval between the health checks of the replicas.
+	ReplicaCheckInterval time.Duration `json:"replica_check_interval" yaml:"replica_check_interval" toml:"replica_check_interval" mapstructure:"replica_check_interval"` // nolint
+	// ReplicaMaxLag above which a replica isn't read from, 0 disables it.
+	ReplicaMaxLag time.Duration  `json:"replica_max_lag" yaml:"replica_max_lag" toml:"replica_max_lag" mapstructure:"replica_max_lag"` // nolint
+	QueryLog      QueryLogConfig `json:"query_log" yaml:"query_log" toml:"query_log" mapstructure:"query_log"`                         // nolint
 }
 
 // NewConfig returns new default configurations.
@@ -35,89 +57,139 @@ func NewConfig() (conf *Config) {
 	return
 }
 
-// Options for underlying DB ORM by these configurations.
-func (c *Config) options() (opts *pgdriver.Option, err error) {
-	// cfg := pgdriver.Config{
-	// 	Network:     "tcp",
-	// 	Addr:        c.URL,
-	// 	TLSConfig:   &tls.Config{InsecureSkipVerify: true},
-	// 	DialTimeout: c.DialTimeout,
-	// 	ReadTimeout: c.ReadTimeout,
-	// }
-	//
-	// if opts, err = pg.ParseURL(c.URL); err != nil {
-	// 	return
-	// }
-	// opts.DialTimeout = c.DialTimeout
-	// opts.IdleTimeout = c.IdleTimeout
-	// opts.ReadTimeout = c.ReadTimeout
-	// opts.WriteTimeout = c.WriteTimeout
-	// opts.RetryStatementTimeout = c.RetryStatementTimeout
-	// opts.MaxRetries = c.MaxRetries
-	// opts.MaxRetryBackoff = c.MaxRetryBackoff
-	// opts.PoolSize = c.PoolSize
-	// opts.PoolTimeout = c.PoolTimeout
+// Options for underlying DB driver by these configurations, connecting to
+// given URL: the primary or a replica.
+func (c *Config) options(rawURL string) (opts []pgdriver.Option, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URL: %w", err)
+	}
+	tlsConfig, err := c.TLS.build(u)
+	if err != nil {
+		return nil, err
+	}
 
-	return
+	// the TLS parameters are handled by build, pgdriver can't verify the
+	// certificates with a custom CA or server name
+	q := u.Query()
+	q.Del("sslmode")
+	q.Del("sslrootcert")
+	u.RawQuery = q.Encode()
+
+	dsn, err := dsnOption(u.String())
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, dsn, pgdriver.WithTLSConfig(tlsConfig))
+
+	if c.DialTimeout > 0 {
+		opts = append(opts, pgdriver.WithDialTimeout(c.DialTimeout))
+	}
+	if c.ReadTimeout > 0 {
+		opts = append(opts, pgdriver.WithReadTimeout(c.ReadTimeout))
+	}
+	if c.WriteTimeout > 0 {
+		opts = append(opts, pgdriver.WithWriteTimeout(c.WriteTimeout))
+	}
+	return opts, nil
+}
+
+// dsnOption returns the option of given DSN, pgdriver panics on the invalid
+// ones when applied.
+func dsnOption(dsn string) (opt pgdriver.Option, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid database URL: %v", r)
+		}
+	}()
+	opt = pgdriver.WithDSN(dsn)
+	opt(&pgdriver.Config{})
+	return opt, nil
 }
 
 // The DB represents DB.
 type DB struct {
-	*bun.DB // underlying go-pg DB instance, embed
+	*bun.DB  // underlying go-pg DB instance, embed
+	log      *log.Logger
+	queryLog QueryLogConfig
 }
 
-// New DB with given configurations and log.
-func New(conf *Config) (db *DB, err error) {
-	// Create the connection with the provided configurations.
-	pgconn := pgdriver.NewConnector(
-		pgdriver.WithNetwork("tcp"),
-		pgdriver.WithTLSConfig(&tls.Config{InsecureSkipVerify: true}),
-		pgdriver.WithDSN(conf.URL),
-		pgdriver.WithTimeout(5*time.Second),
-		pgdriver.WithDialTimeout(5*time.Second),
-		pgdriver.WithReadTimeout(5*time.Second),
-		pgdriver.WithWriteTimeout(5*time.Second),
-	)
-
-	// Create the underlying SQL database connection.
-	sqlDB := sql.OpenDB(pgconn)
+// New DB with given configurations and log. The connection is retried with
+// an exponential backoff while the database is unreachable, e.g. starting.
+func New(conf *Config, logger *log.Logger) (db *DB, err error) {
+	if db, err = open(conf, conf.URL, logger.Named("bun")); err != nil {
+		return nil, err
+	}
 
 	// Ping the database to ensure the connection is successful.
-	if err = sqlDB.PingContext(context.Background()); err != nil {
+	if err = db.connect(context.Background(), db.DB.DB, conf); err != nil {
+		db.DB.Close() //nolint
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
+	return
+}
+
+// NewReplica returns the DB of the read replica at given URL, sharing the
+// other configurations of the primary. It doesn't wait for the replica to be
+// reachable.
+func NewReplica(conf *Config, rawURL string, logger *log.Logger) (*DB, error) {
+	return open(conf, rawURL, logger.Named("bun_replica"))
+}
+
+func open(conf *Config, rawURL string, logger *log.Logger) (*DB, error) {
+	opts, err := conf.options(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the underlying SQL database connection.
+	sqlDB := sql.OpenDB(pgdriver.NewConnector(opts...))
 
 	// Set connection pool limits
-	sqlDB.SetMaxIdleConns(conf.PoolSize)
-	sqlDB.SetMaxOpenConns(conf.PoolSize)
-	sqlDB.SetConnMaxLifetime(conf.IdleTimeout)
+	if conf.PoolSize > 0 {
+		sqlDB.SetMaxIdleConns(conf.PoolSize)
+		sqlDB.SetMaxOpenConns(conf.PoolSize)
+	}
+	sqlDB.SetConnMaxIdleTime(conf.IdleTimeout)
 
 	// Initialize the Bun DB instance.
-	db = &DB{}
+	db := &DB{log: logger, queryLog: conf.QueryLog}
 	db.DB = bun.NewDB(sqlDB, pgdialect.New())
 
-	// Verify Bun DB connection by pinging.
-	if err = db.Ping(context.Background()); err != nil {
-		return nil, fmt.Errorf("failed to ping bun DB: %w", err)
-	}
-
 	// Add query hooks for logging queries.
 	db.DB.AddQueryHook(db)
-	return
+	return db, nil
 }
 
 // Ping DB server.
 func (db *DB) Ping(ctx context.Context) error {
-	return db.DB.Ping()
+	return db.DB.PingContext(ctx)
 }
 
-// BeforeQuery hook (no-op for now).
+// BeforeQuery hook (start the span of the query).
 func (db *DB) BeforeQuery(ctx context.Context, qe *bun.QueryEvent) context.Context {
-	// Add any pre-query actions here if needed.
+	ctx, _ = tracing.Tracer().Start(ctx, qe.Operation(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(qe.Operation()),
+			semconv.DBQueryText(redactQuery(qe.Query)),
+		))
 	return ctx
 }
 
-// AfterQuery hook (log the query operation and any errors).
+// AfterQuery hook (log the query operation and any errors, observe its
+// latency and end its span).
 func (db *DB) AfterQuery(ctx context.Context, qe *bun.QueryEvent) {
-	fmt.Println(qe.Query)
+	operation := qe.Operation()
+	metrics.DBQueryDuration.WithLabelValues(operation).Observe(time.Since(qe.StartTime).Seconds())
+	span := trace.SpanFromContext(ctx)
+	if qe.Err != nil && !errors.Is(qe.Err, sql.ErrNoRows) {
+		metrics.DBQueryErrors.WithLabelValues(operation).Inc()
+		span.RecordError(qe.Err)
+		span.SetStatus(codes.Error, qe.Err.Error())
+	}
+	span.End()
+
+	db.logQuery(ctx, qe)
 }
@@ -0,0 +1,82 @@
+package bundb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSConfigBuild(t *testing.T) {
+	for _, tt := range []struct {
+		url            string
+		tls            TLSConfig
+		disabled       bool
+		insecure       bool
+		verifyChain    bool
+		wantServerName string
+	}{
+		{url: "postgres://db:5432/orders?sslmode=disable", disabled: true},
+		{url: "postgres://db:5432/orders", insecure: true, wantServerName: "db"},
+		{url: "postgres://db:5432/orders?sslmode=require", insecure: true, wantServerName: "db"},
+		{url: "postgres://db:5432/orders?sslmode=verify-ca", insecure: true, verifyChain: true, wantServerName: "db"},
+		{url: "postgres://db:5432/orders?sslmode=verify-full", wantServerName: "db"},
+		{url: "postgres://10.0.0.1/orders?sslmode=verify-full", tls: TLSConfig{ServerName: "db.internal"}, wantServerName: "db.internal"},
+	} {
+		u, err := url.Parse(tt.url)
+		require.NoError(t, err)
+		conf, err := tt.tls.build(u)
+		require.NoError(t, err, tt.url)
+		if tt.disabled {
+			assert.Nil(t, conf, tt.url)
+			continue
+		}
+		require.NotNil(t, conf, tt.url)
+		assert.Equal(t, tt.insecure, conf.InsecureSkipVerify, tt.url)
+		assert.Equal(t, tt.verifyChain, conf.VerifyPeerCertificate != nil, tt.url)
+		assert.Equal(t, tt.wantServerName, conf.ServerName, tt.url)
+	}
+
+	u, _ := url.Parse("postgres://db/orders?sslmode=verify-full")
+	_, err := (&TLSConfig{CAFile: "testdata/missing.pem"}).build(u)
+	assert.Error(t, err)
+
+	u, _ = url.Parse("postgres://db/orders?sslmode=strict")
+	_, err = (&TLSConfig{}).build(u)
+	assert.EqualError(t, err, `unsupported database sslmode "strict"`)
+}
+
+func TestOptions(t *testing.T) {
+	conf := &Config{URL: "postgres://root:secret@db:5432/orders?sslmode=disable&connect_timeout=x"}
+	_, err := conf.options(conf.URL)
+	assert.ErrorContains(t, err, "invalid database URL")
+
+	conf.URL = "postgres://root:secret@db:5432/orders?sslmode=disable&application_name=orders"
+	opts, err := conf.options(conf.URL)
+	require.NoError(t, err)
+	assert.Len(t, opts, 2)
+
+	conf.DialTimeout, conf.ReadTimeout, conf.WriteTimeout = time.Second, time.Second, time.Second
+	opts, err = conf.options(conf.URL)
+	require.NoError(t, err)
+	assert.Len(t, opts, 5)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 250*time.Millisecond, backoff(0, 5*time.Second))
+	assert.Equal(t, time.Second, backoff(2, 5*time.Second))
+	assert.Equal(t, 5*time.Second, backoff(10, 5*time.Second))
+	assert.Equal(t, 2*time.Second, backoff(3, 0))
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, isTransient(fmt.Errorf("dial: %w", syscall.ECONNREFUSED)))
+	assert.True(t, isTransient(context.DeadlineExceeded))
+	assert.False(t, isTransient(errors.New("pgdriver: SSL is not enabled on the server")))
+}
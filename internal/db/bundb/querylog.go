@@ -0,0 +1,167 @@
+package bundb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"go.uber.org/zap"
+)
+
+// explainTimeout bounds the EXPLAIN of a slow query.
+const explainTimeout = 2 * time.Second
+
+// QueryLogConfig of the query logger.
+type QueryLogConfig struct {
+	// Enable logs every query at debug level.
+	Enable bool `json:"enable" yaml:"enable" toml:"enable" mapstructure:"enable"`
+	// SlowThreshold is the duration above which a query is logged as slow at
+	// warn level, 0 disables it.
+	SlowThreshold time.Duration `json:"slow_threshold" yaml:"slow_threshold" toml:"slow_threshold" mapstructure:"slow_threshold"`
+	// Explain logs the plan of the slow queries. It runs an extra query, meant
+	// for development only.
+	Explain bool `json:"explain" yaml:"explain" toml:"explain" mapstructure:"explain"`
+}
+
+// logQuery logs given query event with its parameters redacted: failed
+// queries at error level, slow queries at warn level and all the others at
+// debug level when enabled.
+func (db *DB) logQuery(ctx context.Context, qe *bun.QueryEvent) {
+	var (
+		duration = time.Since(qe.StartTime)
+		conf     = db.queryLog
+		fields   = []zap.Field{
+			zap.String("operation", qe.Operation()),
+			zap.String("query", redactQuery(qe.Query)),
+			zap.Duration("duration", duration),
+		}
+	)
+
+	switch {
+	case qe.Err != nil && !errors.Is(qe.Err, sql.ErrNoRows):
+		if code := errorCode(qe.Err); code != "" {
+			fields = append(fields, zap.String("pg_code", code))
+		}
+		db.log.Error(ctx, "query failed", append(fields, zap.Error(qe.Err))...)
+	case conf.SlowThreshold > 0 && duration >= conf.SlowThreshold:
+		if conf.Explain {
+			fields = append(fields, db.explain(ctx, qe))
+		}
+		db.log.Warn(ctx, "slow query", fields...)
+	case conf.Enable:
+		db.log.Debug(ctx, "query", fields...)
+	}
+}
+
+// explain returns the redacted plan of given query. It bypasses the query
+// hooks so the EXPLAIN isn't logged itself.
+func (db *DB) explain(ctx context.Context, qe *bun.QueryEvent) zap.Field {
+	switch qe.Operation() {
+	case "SELECT", "INSERT", "UPDATE", "DELETE":
+	default:
+		return zap.Skip()
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), explainTimeout)
+	defer cancel()
+
+	rows, err := db.DB.DB.QueryContext(ctx, "EXPLAIN "+qe.Query)
+	if err != nil {
+		return zap.NamedError("explain_error", err)
+	}
+	defer rows.Close() //nolint
+
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err = rows.Scan(&line); err != nil {
+			return zap.NamedError("explain_error", err)
+		}
+		plan = append(plan, redactPlan(line))
+	}
+	if err = rows.Err(); err != nil {
+		return zap.NamedError("explain_error", err)
+	}
+	return zap.String("plan", strings.Join(plan, "\n"))
+}
+
+// errorCode returns the SQLSTATE code of given error, if it comes from
+// Postgres.
+func errorCode(err error) string {
+	var driverErr pgdriver.Error
+	if errors.As(err, &driverErr) {
+		return driverErr.Field('C')
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// redactQuery replaces the string and numeric literals of given query with
+// "?", bun inlines the parameters into the query so they'd leak otherwise.
+// Quoted identifiers are kept as is.
+func redactQuery(query string) string {
+	return redact(query, true)
+}
+
+// redactPlan replaces the string literals of given EXPLAIN line with "?",
+// the costs and row estimates are kept.
+func redactPlan(line string) string {
+	return redact(line, false)
+}
+
+func redact(query string, numbers bool) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			// string literal, '' is an escaped quote
+			for i++; i < len(query); i++ {
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			b.WriteByte('?')
+		case c == '"':
+			// quoted identifier
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case numbers && isDigit(c) && (i == 0 || !isIdentByte(query[i-1])):
+			for i+1 < len(query) && (isDigit(query[i+1]) ||
+				query[i+1] == '.' && i+2 < len(query) && isDigit(query[i+2])) {
+				i++
+			}
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,35 @@
+package bundb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactQuery(t *testing.T) {
+	for _, tt := range []struct {
+		query, want string
+	}{
+		{
+			`SELECT "o"."id" FROM "orders" AS "o" WHERE (recipient_phone = '01711111111') AND (user_id = 42) LIMIT 10`,
+			`SELECT "o"."id" FROM "orders" AS "o" WHERE (recipient_phone = ?) AND (user_id = ?) LIMIT ?`,
+		},
+		{
+			`INSERT INTO "users" ("name", "password") VALUES ('O''Neil', '$2a$10$abc'), ('x', 'y')`,
+			`INSERT INTO "users" ("name", "password") VALUES (?, ?), (?, ?)`,
+		},
+		{
+			`UPDATE "orders" SET "amount_to_collect" = 1200.50 WHERE "t1"."id2" = -3`,
+			`UPDATE "orders" SET "amount_to_collect" = ? WHERE "t1"."id2" = -?`,
+		},
+		{`SELECT "unterminated`, `SELECT "unterminated`},
+	} {
+		assert.Equal(t, tt.want, redactQuery(tt.query))
+	}
+}
+
+func TestRedactPlan(t *testing.T) {
+	assert.Equal(t,
+		`  Filter: ((recipient_phone)::text = ?::text) (cost=0.15..8.17 rows=1 width=8)`,
+		redactPlan(`  Filter: ((recipient_phone)::text = '01711111111'::text) (cost=0.15..8.17 rows=1 width=8)`))
+}
@@ -0,0 +1,85 @@
+package bundb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// minRetryBackoff is the backoff before the first retry, doubled on every
+// retry up to Config.MaxRetryBackoff.
+const minRetryBackoff = 250 * time.Millisecond
+
+// connect pings the database, retrying the transient failures with an
+// exponential backoff as configured.
+func (db *DB) connect(ctx context.Context, sqlDB *sql.DB, conf *Config) error {
+	for attempt := 0; ; attempt++ {
+		err := ping(ctx, sqlDB, conf.PoolTimeout)
+		if err == nil {
+			return nil
+		}
+		if attempt >= conf.MaxRetries || !isTransient(err) {
+			return err
+		}
+
+		wait := backoff(attempt, conf.MaxRetryBackoff)
+		db.log.Warn(ctx, "database unreachable, retrying",
+			zap.Int("attempt", attempt+1), zap.Duration("backoff", wait), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func ping(ctx context.Context, sqlDB *sql.DB, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// backoff returns the wait before the retry following given attempt.
+func backoff(attempt int, max time.Duration) time.Duration {
+	wait := minRetryBackoff
+	for i := 0; i < attempt && (max <= 0 || wait < max); i++ {
+		wait *= 2
+	}
+	if max > 0 && wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// isTransient reports whether given connection error may go away on its own,
+// e.g. while the database is starting. Authentication or TLS failures aren't.
+func isTransient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	switch code := errorCode(err); {
+	case code == "57P03", // cannot_connect_now, starting up or shutting down
+		code == "53300",               // too_many_connections
+		strings.HasPrefix(code, "08"): // connection_exception
+		return true
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package bundb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// SSL modes of the database URL, as libpq.
+const (
+	SSLModeDisable    = "disable"
+	SSLModeAllow      = "allow"
+	SSLModePrefer     = "prefer"
+	SSLModeRequire    = "require"
+	SSLModeVerifyCA   = "verify-ca"
+	SSLModeVerifyFull = "verify-full"
+)
+
+// TLSConfig of the database connections, on top of the sslmode and
+// sslrootcert parameters of the URL.
+type TLSConfig struct {
+	// CAFile is the PEM encoded CA certificates verifying the server, it
+	// overrides sslrootcert. The system ones are used when both are empty.
+	CAFile string `json:"ca_file" yaml:"ca_file" toml:"ca_file" mapstructure:"ca_file"`
+	// ServerName verified by sslmode=verify-full, the host of the URL when
+	// empty.
+	ServerName string `json:"server_name" yaml:"server_name" toml:"server_name" mapstructure:"server_name"`
+}
+
+// ValidSSLMode reports whether given sslmode is supported.
+func ValidSSLMode(mode string) bool {
+	switch mode {
+	case "", SSLModeDisable, SSLModeAllow, SSLModePrefer, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull:
+		return true
+	}
+	return false
+}
+
+// build returns the TLS configuration of given database URL, nil when TLS is
+// disabled:
+//   - disable doesn't use TLS
+//   - allow, prefer and no sslmode use TLS without verifying the server
+//   - require verifies the certificate chain when a CA is given, as libpq
+//   - verify-ca verifies the certificate chain, not the server name
+//   - verify-full verifies the certificate chain and the server name
+func (c *TLSConfig) build(u *url.URL) (*tls.Config, error) {
+	q := u.Query()
+	mode, caFile := q.Get("sslmode"), q.Get("sslrootcert")
+	if c.CAFile != "" {
+		caFile = c.CAFile
+	}
+	serverName := c.ServerName
+	if serverName == "" {
+		serverName = u.Hostname()
+	}
+
+	var roots *x509.CertPool
+	if caFile != "" && mode != SSLModeDisable {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read database CA file: %w", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in database CA file %s", caFile)
+		}
+	}
+
+	conf := &tls.Config{ServerName: serverName, RootCAs: roots, MinVersion: tls.VersionTLS12}
+	switch mode {
+	case SSLModeDisable:
+		return nil, nil
+	case SSLModeAllow, SSLModePrefer, "":
+		conf.InsecureSkipVerify = true
+	case SSLModeRequire:
+		conf.InsecureSkipVerify = true
+		if roots != nil {
+			conf.VerifyPeerCertificate = verifyChain(roots)
+		}
+	case SSLModeVerifyCA:
+		// the default verification checks the server name too, which
+		// verify-ca doesn't
+		conf.InsecureSkipVerify = true
+		conf.VerifyPeerCertificate = verifyChain(roots)
+	case SSLModeVerifyFull:
+	default:
+		return nil, fmt.Errorf("unsupported database sslmode %q", mode)
+	}
+	return conf, nil
+}
+
+// verifyChain returns the verification of the server certificate chain
+// against given roots, the system ones when nil.
+func verifyChain(roots *x509.CertPool) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("no server certificate")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("failed to parse server certificate: %w", err)
+			}
+			certs[i] = cert
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+		return err
+	}
+}
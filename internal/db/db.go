@@ -3,10 +3,13 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"github.com/kaium123/order/internal/db/bundb"
 	"github.com/kaium123/order/internal/log"
 	"github.com/kaium123/order/internal/model"
 	"github.com/uptrace/bun"
+	"go.uber.org/zap"
+	"sync/atomic"
 )
 
 // compiler time check
@@ -15,8 +18,12 @@ var _ model.DB = (*DB)(nil)
 // DB is a database representation
 type DB struct {
 	//DB  *pgdb.DB // underlying go-pg DB wrapper instance
-	*bun.DB // underlying go-pg DB wrapper instance
+	*bun.DB // underlying go-pg DB wrapper instance, the primary
 	log     *log.Logger
+
+	replicas    []*replica
+	next        atomic.Uint64 // round-robin over the replicas
+	stopReplica context.CancelFunc
 }
 
 // Tx represents transactions
@@ -50,7 +57,7 @@ var _ model.Repository = (*Tx)(nil)
 func New(conf *bundb.Config, logger *log.Logger) (db *DB, err error) {
 
 	var pg *bundb.DB
-	if pg, err = bundb.New(conf); err != nil {
+	if pg, err = bundb.New(conf, logger); err != nil {
 		return
 	}
 
@@ -58,7 +65,44 @@ func New(conf *bundb.Config, logger *log.Logger) (db *DB, err error) {
 	db.DB = pg.DB // embed
 	db.registerTables()
 	db.log = logger.Named("db_model")
-	db.log.Info(context.Background(), "db initialization done")
+
+	if db.replicas, err = openReplicas(conf, logger); err != nil {
+		pg.Close() //nolint
+		return nil, err
+	}
+	if len(db.replicas) > 0 {
+		var ctx context.Context
+		ctx, db.stopReplica = context.WithCancel(context.Background())
+		go db.checkReplicas(ctx, conf.ReplicaCheckInterval, conf.ReplicaMaxLag)
+	}
+
+	db.log.Info(context.Background(), "db initialization done", zap.Int("replicas", len(db.replicas)))
+	return
+}
+
+// Close the primary and the replicas.
+func (db *DB) Close() error {
+	if db.stopReplica != nil {
+		db.stopReplica()
+	}
+	var errs []error
+	for _, r := range db.replicas {
+		errs = append(errs, r.DB.Close())
+	}
+	return errors.Join(append(errs, db.DB.Close())...)
+}
+
+// MigrationVersion returns the migration version of the database and whether
+// the last migration failed.
+func (db *DB) MigrationVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	err = db.NewSelect().
+		Table("schema_migrations").
+		Column("version", "dirty").
+		Limit(1).
+		Scan(ctx, &version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
 	return
 }
 
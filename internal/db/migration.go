@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -9,7 +10,6 @@ import (
 	"github.com/golang-migrate/migrate/v4/source/httpfs"
 	"github.com/kaium123/order/internal/config/sqlxdb"
 	"io/fs"
-	"log"
 	"net/http"
 )
 
@@ -40,58 +40,139 @@ func SQLFromUrl(url string) (*sql.DB, error) {
 	return db.DB.DB, nil
 }
 
-// MigrateFromFS performs migration from fs.FS source, wraps MigrateFromSource
-func MigrateFromFS(db *sql.DB, direction Direction, database string,
-	files fs.FS) (err error) {
-	src, err := httpfs.New(http.FS(files), "migrations")
-	if err != nil {
-		fmt.Println("httpsfs init error ", err)
-		return err
-	}
-
-	return MigrateFromSource(db, direction, database, "httpfs", src)
+// Migrator runs the migrations of a database step by step.
+type Migrator struct {
+	m *migrate.Migrate
 }
 
-// MigrateFromSource performs migration from source.Driver
-func MigrateFromSource(db *sql.DB, direction Direction, database string,
-	source string, files source.Driver) (err error) {
+// NewMigrator returns a Migrator of given database with the migrations of
+// given source.Driver.
+func NewMigrator(db *sql.DB, database string, sourceName string,
+	files source.Driver) (*Migrator, error) {
 
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
-		log.Fatalf("Failed to create Postgres instance: %v", err)
+		return nil, fmt.Errorf("failed to create postgres instance: %w", err)
 	}
 
-	m, err := migrate.NewWithInstance(source, files, database, driver)
+	m, err := migrate.NewWithInstance(sourceName, files, database, driver)
 	if err != nil {
-		log.Fatalf("Failed to create migration instance: %v", err)
+		return nil, fmt.Errorf("failed to create migration instance: %w", err)
 	}
-	//version, _, err := m.Version()
-	//if err != nil {
-	//	fmt.Println("error ", err)
-	//	return err
-	//}
 
-	//fmt.Println("current migration version -- ", version)
+	return &Migrator{m: m}, nil
+}
 
-	log.Println("Running migration...")
-	switch direction {
-	case DirectionUp:
-		err = m.Up()
-	case DirectionDown:
-		err = m.Down()
+// NewMigratorFromFS returns a Migrator with the migrations found in the
+// "migrations" directory of given fs.FS.
+func NewMigratorFromFS(db *sql.DB, database string, files fs.FS) (*Migrator, error) {
+	src, err := httpfs.New(http.FS(files), "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	return NewMigrator(db, database, "httpfs", src)
+}
+
+// Up applies given number of migrations, all of them if steps is 0.
+func (m *Migrator) Up(steps int) error {
+	if steps == 0 {
+		return ignoreNoChange(m.m.Up())
+	}
+	return ignoreNoChange(m.m.Steps(steps))
+}
+
+// Down rolls back given number of migrations, all of them if steps is 0.
+func (m *Migrator) Down(steps int) error {
+	if steps == 0 {
+		return ignoreNoChange(m.m.Down())
 	}
+	return ignoreNoChange(m.m.Steps(-steps))
+}
+
+// Goto migrates up or down to given version.
+func (m *Migrator) Goto(version uint) error {
+	return ignoreNoChange(m.m.Migrate(version))
+}
+
+// Version returns the current version of the database, 0 if no migration
+// was applied yet, and whether the last migration failed.
+func (m *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return
+}
 
-	// don't emit errors on no changes made
-	if err == migrate.ErrNoChange || err == migrate.ErrNilVersion {
-		log.Println("No changes were made during the migration")
+// Force sets the version of the database without running any migration and
+// clears the dirty flag. Use -1 to remove the version.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+// Close the source and the database driver, including the *sql.DB given to
+// the Migrator.
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// don't emit errors on no changes made
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) || errors.Is(err, migrate.ErrNilVersion) {
 		return nil
 	}
+	return err
+}
+
+// LatestVersion returns the version of the last migration found in the
+// "migrations" directory of given fs.FS.
+func LatestVersion(files fs.FS) (latest uint, err error) {
+	entries, err := fs.ReadDir(files, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	for _, entry := range entries {
+		m, parseErr := source.Parse(entry.Name())
+		if parseErr != nil {
+			continue // not a migration
+		}
+		if m.Version > latest {
+			latest = m.Version
+		}
+	}
+	return
+}
+
+// MigrateFromFS performs migration from fs.FS source, wraps MigrateFromSource
+func MigrateFromFS(db *sql.DB, direction Direction, database string,
+	files fs.FS) (err error) {
+	src, err := httpfs.New(http.FS(files), "migrations")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
 
+	return MigrateFromSource(db, direction, database, "httpfs", src)
+}
+
+// MigrateFromSource performs all the migrations of source.Driver in given
+// direction.
+func MigrateFromSource(db *sql.DB, direction Direction, database string,
+	source string, files source.Driver) (err error) {
+
+	m, err := NewMigrator(db, database, source, files)
 	if err != nil {
-		log.Printf("Migration failed: %v", err)
 		return err
 	}
+	defer m.Close() //nolint
 
-	log.Println("Migration applied successfully.")
-	return nil
+	switch direction {
+	case DirectionUp:
+		err = m.Up(0)
+	case DirectionDown:
+		err = m.Down(0)
+	}
+	return
 }
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/kaium123/order/internal/db/bundb"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/metrics"
+	"github.com/uptrace/bun"
+	"go.uber.org/zap"
+)
+
+// replicaCheckTimeout bounds every health check of a replica.
+const replicaCheckTimeout = 2 * time.Second
+
+// replicaLagQuery returns the replication lag of a replica in seconds, 0 when
+// it replayed everything it received or isn't a replica.
+const replicaLagQuery = `SELECT COALESCE(CASE
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+END, 0)`
+
+type primaryContextKey struct{}
+
+// WithPrimary returns a copy of given context whose reads go to the primary,
+// for the callers which must see their own writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// ReadsPrimary reports whether the reads of given context go to the primary,
+// see WithPrimary.
+func ReadsPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryContextKey{}).(bool)
+	return primary
+}
+
+// replica is a read replica and its last health check.
+type replica struct {
+	*bun.DB
+	name    string
+	healthy atomic.Bool
+}
+
+// openReplicas opens the replicas of given configurations. They're unhealthy
+// until their first check passes.
+func openReplicas(conf *bundb.Config, logger *log.Logger) ([]*replica, error) {
+	replicas := make([]*replica, 0, len(conf.Replicas))
+	for i, url := range conf.Replicas {
+		pg, err := bundb.NewReplica(conf, url, logger)
+		if err != nil {
+			for _, r := range replicas {
+				r.DB.Close() //nolint
+			}
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+		replicas = append(replicas, &replica{DB: pg.DB, name: strconv.Itoa(i)})
+	}
+	return replicas, nil
+}
+
+// Primary returns the primary database, which every write goes to.
+func (db *DB) Primary() *bun.DB {
+	return db.DB
+}
+
+// Replica returns the next healthy read replica, round-robin, or the primary
+// when none is healthy or configured. The reads may lag behind the writes.
+func (db *DB) Replica() *bun.DB {
+	n := len(db.replicas)
+	if n == 0 {
+		return db.DB
+	}
+	start := db.next.Add(1)
+	for i := 0; i < n; i++ {
+		if r := db.replicas[(start+uint64(i))%uint64(n)]; r.healthy.Load() {
+			return r.DB
+		}
+	}
+	return db.DB
+}
+
+// Reader returns the database the read-only queries of given context go to:
+// the primary when marked by WithPrimary, a replica otherwise.
+func (db *DB) Reader(ctx context.Context) bun.IDB {
+	if ReadsPrimary(ctx) {
+		return db.DB
+	}
+	return db.Replica()
+}
+
+// checkReplicas checks the health of the replicas every interval until given
+// context is done.
+func (db *DB) checkReplicas(ctx context.Context, interval, maxLag time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, r := range db.replicas {
+			db.checkReplica(ctx, r, maxLag)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkReplica marks given replica healthy when it's reachable and lags less
+// than maxLag behind the primary, 0 disabling the lag check.
+func (db *DB) checkReplica(ctx context.Context, r *replica, maxLag time.Duration) {
+	checkCtx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+
+	var (
+		lagSeconds float64
+		err        = r.DB.DB.QueryRowContext(checkCtx, replicaLagQuery).Scan(&lagSeconds)
+		lag        = time.Duration(lagSeconds * float64(time.Second))
+		healthy    = err == nil && (maxLag <= 0 || lag <= maxLag)
+	)
+	if ctx.Err() != nil {
+		// closing
+		return
+	}
+
+	metrics.DBReplicaUp.WithLabelValues(r.name).Set(boolToFloat(healthy))
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+	fields := []zap.Field{zap.String("replica", r.name), zap.Duration("lag", lag)}
+	if healthy {
+		db.log.Info(ctx, "replica healthy", fields...)
+	} else if err != nil {
+		db.log.Warn(ctx, "replica unreachable, reading from the primary", append(fields, zap.Error(err))...)
+	} else {
+		db.log.Warn(ctx, "replica lagging, reading from the primary", fields...)
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+)
+
+func TestReplica(t *testing.T) {
+	primary := new(bun.DB)
+	db := &DB{DB: primary}
+	assert.Same(t, primary, db.Replica(), "no replica")
+
+	first, second := &replica{DB: new(bun.DB)}, &replica{DB: new(bun.DB)}
+	db.replicas = []*replica{first, second}
+	assert.Same(t, primary, db.Replica(), "no healthy replica")
+
+	first.healthy.Store(true)
+	second.healthy.Store(true)
+	a, b := db.Replica(), db.Replica()
+	assert.NotSame(t, a, b, "round-robin")
+	assert.ElementsMatch(t, []*bun.DB{first.DB, second.DB}, []*bun.DB{a, b})
+
+	second.healthy.Store(false)
+	assert.Same(t, first.DB, db.Replica())
+	assert.Same(t, first.DB, db.Replica())
+
+	assert.Same(t, first.DB, db.Reader(context.Background()))
+	assert.Same(t, primary, db.Reader(WithPrimary(context.Background())))
+}
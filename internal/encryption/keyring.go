@@ -0,0 +1,263 @@
+// Package encryption encrypts fields at rest with envelope encryption: every
+// value is sealed with its own random data key, which is sealed in turn with
+// a versioned key of a local keyring. Rotating the active key only requires
+// re-sealing the values, see Keyring.NeedsReencrypt.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// keySize of the key encryption keys and the data keys, AES-256.
+const keySize = 32
+
+// prefix marks the encrypted values, so plaintext values written before the
+// encryption was enabled are still read.
+const prefix = "enc"
+
+// ErrUnknownKey is returned when a value was sealed with a key missing from
+// the keyring.
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Config of the keyring.
+type Config struct {
+	// Enable encrypts the fields on write, encrypted fields are decrypted on
+	// read either way.
+	Enable bool `json:"enable" yaml:"enable" toml:"enable" mapstructure:"enable"`
+	// ActiveKey is the ID of the key sealing the new values.
+	ActiveKey string `json:"active_key" yaml:"active_key" toml:"active_key" mapstructure:"active_key"`
+	// Keys are the base64 encoded 32 bytes keys by ID. Retired keys must be
+	// kept until `reencrypt` ran over every row.
+	Keys map[string]string `json:"keys" yaml:"keys" toml:"keys" mapstructure:"keys"`
+	// BlindIndexKey is the base64 encoded 32 bytes key of the blind indexes.
+	// Changing it requires a `reencrypt`.
+	BlindIndexKey string `json:"blind_index_key" yaml:"blind_index_key" toml:"blind_index_key" mapstructure:"blind_index_key"`
+}
+
+// Problem of a configuration key.
+type Problem struct {
+	Key     string
+	Message string
+}
+
+// Validate the configuration, returning every problem found.
+func (c *Config) Validate() (problems []Problem) {
+	ids := make([]string, 0, len(c.Keys))
+	for id := range c.Keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if id == "" || strings.Contains(id, ":") {
+			problems = append(problems, Problem{"keys." + id, "ID must not be empty or contain ':'"})
+		} else if _, err := decodeKey(c.Keys[id]); err != nil {
+			problems = append(problems, Problem{"keys." + id, err.Error()})
+		}
+	}
+
+	if c.Enable {
+		if _, ok := c.Keys[c.ActiveKey]; !ok {
+			problems = append(problems, Problem{"active_key", fmt.Sprintf("must be one of the keys, got %q", c.ActiveKey)})
+		}
+		if c.BlindIndexKey == "" {
+			problems = append(problems, Problem{"blind_index_key", "is required"})
+		}
+	}
+	if c.BlindIndexKey != "" {
+		if _, err := decodeKey(c.BlindIndexKey); err != nil {
+			problems = append(problems, Problem{"blind_index_key", err.Error()})
+		}
+	}
+	return
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("must be base64 encoded")
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("must be %d bytes, got %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+// Keyring seals and opens the values with its keys.
+type Keyring struct {
+	enable     bool
+	activeKey  string
+	keys       map[string]cipher.AEAD
+	blindIndex []byte
+}
+
+// NewKeyring returns the Keyring of given configuration.
+func NewKeyring(conf *Config) (*Keyring, error) {
+	if problems := conf.Validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid encryption.%s: %s", problems[0].Key, problems[0].Message)
+	}
+
+	kr := &Keyring{
+		enable:    conf.Enable,
+		activeKey: conf.ActiveKey,
+		keys:      make(map[string]cipher.AEAD, len(conf.Keys)),
+	}
+	for id, encoded := range conf.Keys {
+		key, _ := decodeKey(encoded)
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		kr.keys[id] = aead
+	}
+	if conf.BlindIndexKey != "" {
+		kr.blindIndex, _ = decodeKey(conf.BlindIndexKey)
+	}
+	return kr, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Enabled reports whether the new values are encrypted.
+func (kr *Keyring) Enabled() bool {
+	return kr != nil && kr.enable
+}
+
+// Encrypt seals given plaintext with a new data key, itself sealed with the
+// active key. The plaintext is returned as is when the encryption is
+// disabled.
+//
+// The sealed value is "enc:<key ID>:<sealed data key>:<sealed plaintext>",
+// base64 encoded.
+func (kr *Keyring) Encrypt(plaintext string) (string, error) {
+	if !kr.Enabled() || plaintext == "" {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	sealedKey, err := seal(kr.keys[kr.activeKey], dataKey, []byte(kr.activeKey))
+	if err != nil {
+		return "", err
+	}
+	sealedText, err := seal(data, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		prefix,
+		kr.activeKey,
+		base64.RawStdEncoding.EncodeToString(sealedKey),
+		base64.RawStdEncoding.EncodeToString(sealedText),
+	}, ":"), nil
+}
+
+// Decrypt opens given value sealed by Encrypt, values which aren't sealed
+// are returned as is.
+func (kr *Keyring) Decrypt(value string) (string, error) {
+	keyID, sealedKey, sealedText, ok := parse(value)
+	if !ok {
+		return value, nil
+	}
+	if kr == nil {
+		return "", fmt.Errorf("%w %q: no keyring configured", ErrUnknownKey, keyID)
+	}
+
+	kek, ok := kr.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+	dataKey, err := open(kek, sealedKey, []byte(keyID))
+	if err != nil {
+		return "", fmt.Errorf("failed to open data key: %w", err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(data, sealedText, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsReencrypt reports whether given stored value must be written again to
+// follow the configuration: plaintext while the encryption is enabled, sealed
+// with a retired key, or sealed while the encryption is disabled.
+func (kr *Keyring) NeedsReencrypt(value string) bool {
+	keyID, _, _, sealed := parse(value)
+	if !kr.Enabled() {
+		return sealed
+	}
+	return value != "" && (!sealed || keyID != kr.activeKey)
+}
+
+// BlindIndex returns the keyed hash of given value, so it can be searched
+// exactly without being decrypted. It's empty for empty values or without a
+// blind index key.
+func (kr *Keyring) BlindIndex(value string) string {
+	if kr == nil || len(kr.blindIndex) == 0 || value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, kr.blindIndex)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func parse(value string) (keyID string, sealedKey, sealedText []byte, ok bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 4 || parts[0] != prefix {
+		return
+	}
+
+	var err error
+	if sealedKey, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return
+	}
+	if sealedText, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil {
+		return
+	}
+	return parts[1], sealedKey, sealedText, true
+}
+
+// seal returns the random nonce followed by the ciphertext.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sealed value too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
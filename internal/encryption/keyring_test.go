@@ -0,0 +1,102 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, keySize))
+}
+
+func newTestKeyring(t *testing.T, activeKey string) *Keyring {
+	kr, err := NewKeyring(&Config{
+		Enable:        true,
+		ActiveKey:     activeKey,
+		Keys:          map[string]string{"v1": testKey(1), "v2": testKey(2)},
+		BlindIndexKey: testKey(3),
+	})
+	require.NoError(t, err)
+	return kr
+}
+
+func TestKeyring(t *testing.T) {
+	kr := newTestKeyring(t, "v1")
+
+	sealed, err := kr.Encrypt("01711111234")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "enc:v1:"))
+	assert.NotContains(t, sealed, "01711111234")
+
+	again, err := kr.Encrypt("01711111234")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every value has its own data key and nonce")
+
+	plaintext, err := kr.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "01711111234", plaintext)
+
+	plaintext, err = kr.Decrypt("written before the encryption")
+	require.NoError(t, err)
+	assert.Equal(t, "written before the encryption", plaintext)
+
+	tampered := sealed[:len(sealed)-2] + "AA"
+	_, err = kr.Decrypt(tampered)
+	assert.Error(t, err)
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old := newTestKeyring(t, "v1")
+	sealed, err := old.Encrypt("Banani, Dhaka")
+	require.NoError(t, err)
+	assert.False(t, old.NeedsReencrypt(sealed))
+
+	rotated := newTestKeyring(t, "v2")
+	assert.True(t, rotated.NeedsReencrypt(sealed))
+	plaintext, err := rotated.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "Banani, Dhaka", plaintext)
+
+	retired, err := NewKeyring(&Config{Enable: true, ActiveKey: "v2", Keys: map[string]string{"v2": testKey(2)}, BlindIndexKey: testKey(3)})
+	require.NoError(t, err)
+	_, err = retired.Decrypt(sealed)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	assert.True(t, rotated.NeedsReencrypt("plaintext"))
+	assert.False(t, rotated.NeedsReencrypt(""))
+
+	disabled, err := NewKeyring(&Config{Keys: map[string]string{"v1": testKey(1)}})
+	require.NoError(t, err)
+	assert.True(t, disabled.NeedsReencrypt(sealed))
+	assert.False(t, disabled.NeedsReencrypt("plaintext"))
+	plaintext, err = disabled.Encrypt("plaintext")
+	require.NoError(t, err)
+	assert.Equal(t, "plaintext", plaintext)
+}
+
+func TestBlindIndex(t *testing.T) {
+	kr := newTestKeyring(t, "v1")
+	assert.Equal(t, kr.BlindIndex("01711111234"), newTestKeyring(t, "v2").BlindIndex("01711111234"))
+	assert.NotEqual(t, kr.BlindIndex("01711111234"), kr.BlindIndex("01711111235"))
+	assert.Len(t, kr.BlindIndex("01711111234"), 64)
+	assert.Empty(t, kr.BlindIndex(""))
+}
+
+func TestConfigValidate(t *testing.T) {
+	problems := (&Config{
+		Enable:    true,
+		ActiveKey: "v2",
+		Keys:      map[string]string{"v1": "short", "a:b": testKey(1)},
+	}).Validate()
+	assert.Equal(t, []Problem{
+		{"keys.a:b", "ID must not be empty or contain ':'"},
+		{"keys.v1", "must be base64 encoded"},
+		{"active_key", `must be one of the keys, got "v2"`},
+		{"blind_index_key", "is required"},
+	}, problems)
+}
@@ -0,0 +1,62 @@
+package encryption
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+)
+
+// keyring used by the String columns, the database drivers don't pass any
+// context to the value types.
+var keyring atomic.Pointer[Keyring]
+
+// Use sets the Keyring the String columns are encrypted and decrypted with.
+func Use(kr *Keyring) {
+	keyring.Store(kr)
+}
+
+// Default returns the Keyring set by Use, nil if none.
+func Default() *Keyring {
+	return keyring.Load()
+}
+
+// String is a string column encrypted at rest by the Keyring set by Use. It's
+// plaintext everywhere else: in memory, in JSON and in the logs, where it's
+// masked by its pii tag.
+type String string
+
+var _ driver.Valuer = String("")
+
+// Value encrypts the string with the active key.
+func (s String) Value() (driver.Value, error) {
+	return Default().Encrypt(string(s))
+}
+
+// Scan decrypts the stored value, plaintext values are read as is.
+func (s *String) Scan(src any) error {
+	var value string
+	switch src := src.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		value = src
+	case []byte:
+		value = string(src)
+	default:
+		return fmt.Errorf("encryption: can't scan %T into String", src)
+	}
+
+	plaintext, err := Default().Decrypt(value)
+	if err != nil {
+		return err
+	}
+	*s = String(plaintext)
+	return nil
+}
+
+// BlindIndex returns the blind index of given value with the Keyring set by
+// Use, see Keyring.BlindIndex.
+func BlindIndex(value string) string {
+	return Default().BlindIndex(value)
+}
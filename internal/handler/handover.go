@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/pii"
+	"github.com/kaium123/order/internal/service"
+	"github.com/kaium123/order/internal/utils"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// HandoverHandler is the request handler for the handovers of the orders.
+// The merchants reach their own orders, the riders and the staff every order.
+type HandoverHandler interface {
+	HandOver(c echo.Context) error
+	FindHandovers(c echo.Context) error
+}
+
+type InitHandoverHandler struct {
+	Service service.IHandover
+	Log     *log.Logger
+}
+
+type handoverHandler struct {
+	Handler
+	service service.IHandover
+	log     *log.Logger
+}
+
+// NewHandover returns a new instance of the Handover handler.
+func NewHandover(initHandoverHandler *InitHandoverHandler) HandoverHandler {
+	return &handoverHandler{
+		log:     initHandoverHandler.Log,
+		service: initHandoverHandler.Service,
+	}
+}
+
+// HandOver records an order handed over to the hub, a rider or the
+// recipient. The merchants only record the pickup of their orders by the
+// hub.
+func (t *handoverHandler) HandOver(c echo.Context) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+	var req model.HandoverRequest
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	if err := t.MustBind(c, &req); err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, map[string][]string{"invalid_request": []string{err.Error()}}, "Please provide a valid request body"))
+	}
+
+	req.UserId = userId
+	req.OwnerId = ownerScope(c, userId)
+	req.ConsignmentID = c.Param("CONSIGNMENT_ID")
+	to, validationErr := req.Validate()
+	if validationErr != nil {
+		t.log.Error(ctx, "validation errors : ", zap.Any("", validationErr))
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnprocessableEntity, validationErr.Errors, "Please fix the given errors"))
+	}
+
+	role := GetUserRole(c)
+	if !role.CanHandOverTo(to) {
+		return c.JSON(responseErr.GetErrorResponse(http.StatusForbidden, map[string][]string{"to": []string{model.ErrForbiddenHandover.Error()}}, "The handover can't be recorded"))
+	}
+	req.PickupOnly = !role.CanRecordDeliveries()
+
+	res, err := t.service.HandOver(ctx, &req, to)
+	if err != nil {
+		if errors.Is(err, model.ErrForbiddenHandover) {
+			return c.JSON(responseErr.GetErrorResponse(http.StatusForbidden, map[string][]string{"to": []string{err.Error()}}, "The handover can't be recorded"))
+		}
+		if errors.Is(err, model.ErrInvalidHandover) {
+			return c.JSON(responseErr.GetErrorResponse(http.StatusConflict, map[string][]string{"to": []string{err.Error()}}, "The order can't be handed over"))
+		}
+		return t.errorResponse(c, err, "order_handover_error")
+	}
+
+	if !role.CanViewPII() {
+		res = pii.Mask(res)
+	}
+	return c.JSON(http.StatusCreated, utils.GetResponseData(http.StatusOK, res, "Order Handed Over Successfully"))
+}
+
+// FindHandovers lists the handovers of an order, the oldest first.
+func (t *handoverHandler) FindHandovers(c echo.Context) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	res, err := t.service.FindHandovers(ctx, &model.FindHandoversRequest{
+		OwnerId:       ownerScope(c, userId),
+		ConsignmentID: c.Param("CONSIGNMENT_ID"),
+	})
+	if err != nil {
+		return t.errorResponse(c, err, "order_handover_error")
+	}
+
+	if !GetUserRole(c).CanViewPII() {
+		res = pii.Mask(res)
+	}
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, res, "Order Handovers successfully fetched."))
+}
+
+func (t *handoverHandler) errorResponse(c echo.Context, err error, key string) error {
+	var responseErr utils.ResponseError
+	if errors.Is(err, model.ErrNotFound) {
+		return c.JSON(responseErr.GetErrorResponse(http.StatusNotFound, map[string][]string{key: []string{err.Error()}}, "Order not found"))
+	}
+	t.log.Error(c.Request().Context(), err.Error())
+	return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{key: []string{err.Error()}}, "Internal server error"))
+}
+
+// ownerScope returns the user whose orders the user may reach, 0 for the
+// orders of every user reached by the staff and the riders.
+func ownerScope(c echo.Context, userId int64) int64 {
+	if GetUserRole(c).CanRecordDeliveries() {
+		return 0
+	}
+	return userId
+}
@@ -1,23 +1,70 @@
 package handler
 
 import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/internal/log"
 	"github.com/kaium123/order/internal/utils"
+	"go.uber.org/zap"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
+// checkTimeout bounds every readiness check.
+const checkTimeout = 2 * time.Second
+
 // HealthHandler is the request handler for the health endpoint.
 type HealthHandler interface {
 	Healthz(c echo.Context) error
+	Livez(c echo.Context) error
+	Readyz(c echo.Context) error
+}
+
+type InitHealthHandler struct {
+	DB    *db.DB
+	Redis *redis.Client
+	// LatestMigration is the version the database must be migrated to.
+	LatestMigration uint
+	// Draining reports whether the server is shutting down.
+	Draining func() bool
+	Log      *log.Logger
 }
 
-type healthHandler struct{}
+type healthHandler struct {
+	db              *db.DB
+	redis           *redis.Client
+	latestMigration uint
+	draining        func() bool
+	log             *log.Logger
+}
 
 // NewHealth returns a new instance of the health handler.
-func NewHealth() HealthHandler {
-	return &healthHandler{}
+func NewHealth(initHealthHandler *InitHealthHandler) HealthHandler {
+	return &healthHandler{
+		db:              initHealthHandler.DB,
+		redis:           initHealthHandler.Redis,
+		latestMigration: initHealthHandler.LatestMigration,
+		draining:        initHealthHandler.Draining,
+		log:             initHealthHandler.Log,
+	}
+}
+
+// CheckResult is the result of a readiness check.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Readiness is the readiness report of the server.
+type Readiness struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
 }
 
 // @Summary	Health check
@@ -28,3 +75,94 @@ func NewHealth() HealthHandler {
 func (t *healthHandler) Healthz(c echo.Context) error {
 	return c.JSON(http.StatusOK, utils.ResponseData{Data: time.Now()})
 }
+
+// @Summary	Liveness probe, the process is up
+// @Tags		health
+// @Produce	json
+// @Success	200	{object}	ResponseData{data=Readiness}
+// @Router		/livez [get]
+func (t *healthHandler) Livez(c echo.Context) error {
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, &Readiness{Status: "alive"}, "alive"))
+}
+
+// @Summary	Readiness probe, the database, Redis and migrations are ready
+// @Tags		health
+// @Produce	json
+// @Success	200	{object}	ResponseData{data=Readiness}
+// @Failure	503	{object}	ResponseData{data=Readiness}
+// @Router		/readyz [get]
+func (t *healthHandler) Readyz(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if t.draining() {
+		return c.JSON(http.StatusServiceUnavailable, utils.GetResponseData(http.StatusServiceUnavailable,
+			&Readiness{Status: "draining"}, "not ready"))
+	}
+
+	checks := map[string]func(ctx context.Context) error{
+		"database":   t.db.PingContext,
+		"redis":      func(ctx context.Context) error { return t.redis.Ping(ctx).Err() },
+		"migrations": t.checkMigrations,
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		readiness = &Readiness{Status: "ready", Checks: make(map[string]CheckResult, len(checks))}
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) error) {
+			defer wg.Done()
+			result := runCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			readiness.Checks[name] = result
+			if result.Status != "up" {
+				readiness.Status = "not ready"
+				t.log.Error(ctx, "readiness check failed", zap.String("check", name), zap.String("error", result.Error))
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	if readiness.Status != "ready" {
+		code = http.StatusServiceUnavailable
+	}
+	return c.JSON(code, utils.GetResponseData(code, readiness, readiness.Status))
+}
+
+// checkMigrations fails unless the database is cleanly migrated to the
+// latest version.
+func (t *healthHandler) checkMigrations(ctx context.Context) error {
+	version, dirty, err := t.db.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	if version != t.latestMigration {
+		return fmt.Errorf("migration version is %d, expected %d", version, t.latestMigration)
+	}
+	return nil
+}
+
+func runCheck(ctx context.Context, check func(ctx context.Context) error) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{
+		Status:    "up",
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "down"
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -2,21 +2,32 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"github.com/kaium123/order/internal/log"
 	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/pii"
 	"github.com/kaium123/order/internal/service"
 	"github.com/kaium123/order/internal/utils"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 	"net/http"
-	"strconv"
+	"time"
 )
 
 // OrderHandler is the request handler for the Order endpoint.
 type OrderHandler interface {
 	CreateOrder(c echo.Context) error
+	FindOrder(c echo.Context) error
+	UpdateOrder(c echo.Context) error
+	FindOrderHistory(c echo.Context) error
+	RecordAttempt(c echo.Context) error
 	CancelOrder(c echo.Context) error
+	ArchiveOrder(c echo.Context) error
+	UnarchiveOrder(c echo.Context) error
+	ArchiveOrders(c echo.Context) error
+	UnarchiveOrders(c echo.Context) error
 	FindAllOrders(c echo.Context) error
+	SearchOrders(c echo.Context) error
 }
 
 type InitOrderHandler struct {
@@ -71,6 +82,169 @@ func (t *orderHandler) CreateOrder(c echo.Context) error {
 	return c.JSON(http.StatusCreated, utils.GetResponseData(http.StatusOK, Order, "Order Created Successfully"))
 }
 
+// FindOrder returns an order with its delivery attempts. The merchants reach
+// their own orders, the staff every order.
+func (t *orderHandler) FindOrder(c echo.Context) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	res, err := t.service.FindOrder(ctx, &model.FindOrderRequest{
+		OwnerId:       ownerScope(c, userId),
+		ConsignmentID: c.Param("CONSIGNMENT_ID"),
+	})
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return c.JSON(responseErr.GetErrorResponse(http.StatusNotFound, map[string][]string{"order_finding_error": []string{err.Error()}}, "Order not found"))
+		}
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{"order_finding_error": []string{err.Error()}}, "Internal server error"))
+	}
+
+	if !GetUserRole(c).CanViewPII() {
+		res = pii.Mask(res)
+	}
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, res, "Order successfully fetched."))
+}
+
+// RecordAttempt records a delivery attempt of an order out for delivery.
+// The order is handed over to the recipient once delivered, and returned to
+// the merchant after too many failed attempts. Only the riders and the staff
+// record the attempts.
+func (t *orderHandler) RecordAttempt(c echo.Context) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+	var req model.AttemptRequest
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	if !GetUserRole(c).CanRecordDeliveries() {
+		return c.JSON(responseErr.GetErrorResponse(http.StatusForbidden, nil, "Only the riders and the staff record the delivery attempts"))
+	}
+
+	if err := t.MustBind(c, &req); err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, map[string][]string{"invalid_request": []string{err.Error()}}, "Please provide a valid request body"))
+	}
+
+	req.UserId = userId
+	req.OwnerId = ownerScope(c, userId)
+	req.ConsignmentID = c.Param("CONSIGNMENT_ID")
+	if validationErr := req.Validate(time.Now()); validationErr != nil {
+		t.log.Error(ctx, "validation errors : ", zap.Any("", validationErr))
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnprocessableEntity, validationErr.Errors, "Please fix the given errors"))
+	}
+
+	res, err := t.service.RecordAttempt(ctx, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidAttempt):
+			return c.JSON(responseErr.GetErrorResponse(http.StatusConflict, map[string][]string{"outcome": []string{err.Error()}}, "The order can't be attempted"))
+		case errors.Is(err, model.ErrNotFound):
+			return c.JSON(responseErr.GetErrorResponse(http.StatusNotFound, map[string][]string{"delivery_attempt_error": []string{err.Error()}}, "Order not found"))
+		}
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{"delivery_attempt_error": []string{err.Error()}}, "Internal server error"))
+	}
+
+	return c.JSON(http.StatusCreated, utils.GetResponseData(http.StatusOK, res, "Delivery Attempt Recorded Successfully"))
+}
+
+// UpdateOrder edits the recipient details, the item and the amount to
+// collect of an order still pending, given the version it was read at.
+func (t *orderHandler) UpdateOrder(c echo.Context) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+	var req model.UpdateOrderRequest
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	if err := t.MustBind(c, &req); err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, map[string][]string{"invalid_request": []string{err.Error()}}, "Please provide a valid request body"))
+	}
+
+	req.UserId = userId
+	req.ConsignmentID = c.Param("CONSIGNMENT_ID")
+	if validationErr := req.Validate(); validationErr != nil {
+		t.log.Error(ctx, "validation errors : ", zap.Any("", validationErr))
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnprocessableEntity, validationErr.Errors, "Please fix the given errors"))
+	}
+
+	res, err := t.service.UpdateOrder(ctx, &req)
+	if err != nil {
+		var invalidErr *model.InvalidOrderError
+		switch {
+		case errors.As(err, &invalidErr):
+			return c.JSON(responseErr.GetErrorResponse(http.StatusUnprocessableEntity, invalidErr.Response.Errors, "Please fix the given errors"))
+		case errors.Is(err, model.ErrVersionConflict):
+			return c.JSON(responseErr.GetErrorResponse(http.StatusConflict, map[string][]string{"version": []string{err.Error()}}, "The order was changed since"))
+		case errors.Is(err, model.ErrNotEditable):
+			return c.JSON(responseErr.GetErrorResponse(http.StatusConflict, map[string][]string{"order_status": []string{err.Error()}}, "The order can't be edited"))
+		case errors.Is(err, model.ErrNotFound):
+			return c.JSON(responseErr.GetErrorResponse(http.StatusNotFound, map[string][]string{"order_update_error": []string{err.Error()}}, "Order not found"))
+		}
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{"order_update_error": []string{err.Error()}}, "Internal server error"))
+	}
+
+	if !GetUserRole(c).CanViewPII() {
+		res = pii.Mask(res)
+	}
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, res, "Order Updated Successfully"))
+}
+
+// FindOrderHistory lists the editions of an order, the oldest first.
+func (t *orderHandler) FindOrderHistory(c echo.Context) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	res, err := t.service.FindOrderHistory(ctx, &model.FindHistoryRequest{
+		UserId:        userId,
+		ConsignmentID: c.Param("CONSIGNMENT_ID"),
+	})
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return c.JSON(responseErr.GetErrorResponse(http.StatusNotFound, map[string][]string{"order_history_error": []string{err.Error()}}, "Order not found"))
+		}
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{"order_history_error": []string{err.Error()}}, "Internal server error"))
+	}
+
+	// The changes are keyed by field, their recipient details are masked by
+	// the kind of the field
+	if !GetUserRole(c).CanViewPII() {
+		for _, edition := range res {
+			for i, change := range edition.Changes {
+				if kind, ok := pii.KeyKind(change.Field); ok {
+					edition.Changes[i].From = pii.MaskString(kind, fmt.Sprint(change.From))
+					edition.Changes[i].To = pii.MaskString(kind, fmt.Sprint(change.To))
+				}
+			}
+		}
+	}
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, res, "Order History successfully fetched."))
+}
+
 func (t *orderHandler) CancelOrder(c echo.Context) error {
 	ctx := c.Request().Context()
 	var req model.OrderCancelRequest
@@ -94,17 +268,34 @@ func (t *orderHandler) CancelOrder(c echo.Context) error {
 	}
 
 	if err := t.service.CancelOrder(ctx, &req); err != nil {
-		t.log.Error(ctx, err.Error())
-		if errors.Is(err, model.ErrNotFound) {
+		var invalidErr *model.InvalidOrderError
+		switch {
+		case errors.As(err, &invalidErr):
+			return c.JSON(responseErr.GetErrorResponse(http.StatusUnprocessableEntity, invalidErr.Response.Errors, "Please fix the given errors"))
+		case errors.Is(err, model.ErrNotFound):
 			return c.JSON(responseErr.GetErrorResponse(http.StatusNotFound, map[string][]string{"order_cancellation_error": []string{err.Error()}}, "Order not found"))
+		case errors.Is(err, model.ErrAlreadyCancelled), errors.Is(err, model.ErrNotCancellable):
+			return c.JSON(responseErr.GetErrorResponse(http.StatusConflict, map[string][]string{"order_status": []string{err.Error()}}, "The order can't be cancelled"))
 		}
+		t.log.Error(ctx, err.Error())
 		return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{"order_cancellation_error": []string{err.Error()}}, "Internal server error"))
 	}
 
 	return c.JSON(http.StatusCreated, utils.GetResponseData(http.StatusOK, nil, "Order Cancelled Successfully"))
 }
 
-func (t *orderHandler) FindAllOrders(c echo.Context) error {
+// ArchiveOrder archives an order, it's left out of the order listing unless
+// asked for.
+func (t *orderHandler) ArchiveOrder(c echo.Context) error {
+	return t.archiveOrder(c, true)
+}
+
+// UnarchiveOrder unarchives an order, it isn't archived automatically again.
+func (t *orderHandler) UnarchiveOrder(c echo.Context) error {
+	return t.archiveOrder(c, false)
+}
+
+func (t *orderHandler) archiveOrder(c echo.Context, archive bool) error {
 	ctx := c.Request().Context()
 	var responseErr utils.ResponseError
 
@@ -114,20 +305,87 @@ func (t *orderHandler) FindAllOrders(c echo.Context) error {
 		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
 	}
 
-	// Retrieve query parameters for 'task' and 'status'
-	transferStatus := c.QueryParam("transfer_status")
-	archive, err := strconv.Atoi(c.QueryParam("archive"))
-	limit, err := strconv.Atoi(c.QueryParam("limit"))
-	page, err := strconv.Atoi(c.QueryParam("page"))
-
-	// Populate request params model with extracted values
-	reqParams := &model.FindAllRequest{
-		TransferStatus: transferStatus,
-		Archive:        archive,
-		Limit:          limit,
-		Offset:         (page - 1) * limit,
+	res, err := t.service.ArchiveOrders(ctx, &model.ArchiveRequest{
 		UserId:         userId,
+		Archive:        archive,
+		ConsignmentIDs: []string{c.Param("CONSIGNMENT_ID")},
+	})
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{"order_archiving_error": []string{err.Error()}}, "Internal server error"))
+	}
+	if res.Updated == 0 {
+		return c.JSON(responseErr.GetErrorResponse(http.StatusNotFound, map[string][]string{"order_archiving_error": []string{model.ErrNotFound.Error()}}, "Order not found"))
+	}
+
+	if archive {
+		return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, nil, "Order Archived Successfully"))
+	}
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, nil, "Order Unarchived Successfully"))
+}
+
+// ArchiveOrders archives the orders having given consignment IDs, or else
+// matching given filter.
+func (t *orderHandler) ArchiveOrders(c echo.Context) error {
+	return t.archiveOrders(c, true)
+}
+
+// UnarchiveOrders unarchives the orders having given consignment IDs, or
+// else matching given filter.
+func (t *orderHandler) UnarchiveOrders(c echo.Context) error {
+	return t.archiveOrders(c, false)
+}
+
+func (t *orderHandler) archiveOrders(c echo.Context, archive bool) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+	var req model.BulkArchiveRequest
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	if err := t.MustBind(c, &req); err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, map[string][]string{"invalid_request": []string{err.Error()}}, "Please provide a valid request body"))
+	}
+	reqParams, validationErr := req.Validate(userId, archive)
+	if validationErr != nil {
+		t.log.Error(ctx, "validation errors : ", zap.Any("", validationErr))
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnprocessableEntity, validationErr.Errors, "Please fix the given errors"))
+	}
+
+	res, err := t.service.ArchiveOrders(ctx, reqParams)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{"order_archiving_error": []string{err.Error()}}, "Internal server error"))
+	}
+
+	if archive {
+		return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, res, "Orders Archived Successfully"))
 	}
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, res, "Orders Unarchived Successfully"))
+}
+
+func (t *orderHandler) FindAllOrders(c echo.Context) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	// Parse and validate the filters, the sort and the page
+	reqParams, validationErr := model.ParseFindAllRequest(c.QueryParams())
+	if validationErr != nil {
+		t.log.Error(ctx, "validation errors : ", zap.Any("", validationErr))
+		return c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, validationErr.Errors, "Please provide a valid request"))
+	}
+	reqParams.UserId = userId
 
 	// Call the service to find all tasks based on the request params
 	res, err := t.service.FindAllOrders(ctx, reqParams)
@@ -136,10 +394,58 @@ func (t *orderHandler) FindAllOrders(c echo.Context) error {
 		return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{"order_finding_error": []string{err.Error()}}, "Internal server error"))
 	}
 
+	// Mask the recipient details for the roles without PII permission
+	if !GetUserRole(c).CanViewPII() {
+		res = pii.Mask(res)
+	}
+
 	// Return the successful result
 	return c.JSON(http.StatusCreated, utils.GetResponseData(http.StatusOK, res, "Orders successfully fetched."))
 }
 
+// SearchOrders finds the orders by the words, substrings or typos of their
+// fields, the most relevant first, within the scope of the role of the user.
+func (t *orderHandler) SearchOrders(c echo.Context) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+	role := GetUserRole(c)
+
+	reqParams, validationErr := model.ParseSearchOrdersRequest(c.QueryParams(), userId, role)
+	if validationErr != nil {
+		t.log.Error(ctx, "validation errors : ", zap.Any("", validationErr))
+		return c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, validationErr.Errors, "Please provide a valid request"))
+	}
+
+	res, err := t.service.SearchOrders(ctx, reqParams)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{"order_search_error": []string{err.Error()}}, "Internal server error"))
+	}
+
+	// Mask the recipient details, and their highlights, for the roles without
+	// PII permission
+	if !role.CanViewPII() {
+		res = pii.Mask(res)
+	}
+
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, res, "Orders successfully found."))
+}
+
+// GetUserRole returns the role of the authenticated user, merchant if unset.
+func GetUserRole(c echo.Context) model.Role {
+	role, ok := c.Get("role").(model.Role)
+	if !ok {
+		return model.RoleMerchant
+	}
+	return role
+}
+
 func GetUserId(c echo.Context) (int64, error) {
 
 	userID, ok := c.Get("user_id").(int64)
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// attemptOrders records the delivery attempts it's given.
+type attemptOrders struct {
+	service.IOrder
+	attempts []*model.AttemptRequest
+}
+
+func (a *attemptOrders) RecordAttempt(_ context.Context, req *model.AttemptRequest) (*model.DeliveryAttemptResponse, error) {
+	a.attempts = append(a.attempts, req)
+	return &model.DeliveryAttemptResponse{Attempt: 1, Outcome: req.Outcome}, nil
+}
+
+func TestRecordAttemptRole(t *testing.T) {
+	orders := &attemptOrders{}
+	h := NewOrder(&InitOrderHandler{Service: orders, Log: log.New()})
+	e := echo.New()
+	e.Validator = &CustomValidator{validator: validator.New()}
+
+	record := func(role model.Role) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/orders/DA241019XYZ/attempts", strings.NewReader(`{"outcome":"delivered"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("CONSIGNMENT_ID")
+		c.SetParamValues("DA241019XYZ")
+		c.Set("user_id", int64(7))
+		c.Set("role", role)
+		require.NoError(t, h.RecordAttempt(c))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, record(model.RoleMerchant))
+	assert.Empty(t, orders.attempts, "the merchant's attempt isn't recorded")
+
+	assert.Equal(t, http.StatusCreated, record(model.RoleRider))
+	require.Len(t, orders.attempts, 1)
+	assert.Zero(t, orders.attempts[0].OwnerId, "the riders reach the orders of every merchant")
+}
@@ -3,19 +3,32 @@ package handler
 import (
 	"github.com/go-playground/validator/v10"
 	"github.com/go-redis/redis/v8"
+	"github.com/kaium123/order/internal/config"
 	"github.com/kaium123/order/internal/db"
 	"github.com/kaium123/order/internal/log"
 	"github.com/kaium123/order/internal/middleware"
+	"github.com/kaium123/order/internal/model"
 	"github.com/kaium123/order/internal/repository"
 	"github.com/kaium123/order/internal/service"
+	"github.com/kaium123/order/internal/stream"
+	"github.com/kaium123/order/internal/webhook"
 	"github.com/labstack/echo/v4"
+	"time"
 )
 
 type ServiceRegistry struct {
 	EchoEngine  *echo.Echo
 	RedisClient *redis.Client
 	DBInstance  *db.DB
+	Settings    *config.Reloader
 	Log         *log.Logger
+	// LatestMigration is the migration version the database must have to be ready.
+	LatestMigration uint
+	// Draining reports whether the server is shutting down.
+	Draining func() bool
+	// StreamHub fans out the order events to the order streams, nil when
+	// they are disabled.
+	StreamHub *stream.Hub
 }
 
 // Register registers the routes for the application.
@@ -25,8 +38,16 @@ func Register(serviceRegistry *ServiceRegistry) {
 	api := serviceRegistry.EchoEngine.Group("/api/v1")
 
 	// Health check
-	healthHandler := NewHealth()
+	healthHandler := NewHealth(&InitHealthHandler{
+		DB:              serviceRegistry.DBInstance,
+		Redis:           serviceRegistry.RedisClient,
+		LatestMigration: serviceRegistry.LatestMigration,
+		Draining:        serviceRegistry.Draining,
+		Log:             serviceRegistry.Log,
+	})
 	api.GET("/healthz", healthHandler.Healthz)
+	serviceRegistry.EchoEngine.GET("/livez", healthHandler.Livez)
+	serviceRegistry.EchoEngine.GET("/readyz", healthHandler.Readyz)
 
 	// Inject Order Dependency
 	redisRepository := repository.NewRedisCache(&repository.InitRedisCache{
@@ -44,13 +65,68 @@ func Register(serviceRegistry *ServiceRegistry) {
 	orderRepository := repository.NewOrder(&repository.InitOrderRepository{
 		Db: serviceRegistry.DBInstance, Log: serviceRegistry.Log,
 	})
+	storeRepository := repository.NewStore(&repository.InitStoreRepository{
+		Db: serviceRegistry.DBInstance, Log: serviceRegistry.Log,
+	})
 	orderService := service.NewOrder(&service.InitOrderService{
-		Log: serviceRegistry.Log, OrderRepository: orderRepository, RedisCache: redisRepository,
+		Log: serviceRegistry.Log, OrderRepository: orderRepository, StoreRepository: storeRepository,
+		DeliveryRepository: repository.NewDelivery(&repository.InitDeliveryRepository{
+			Db: serviceRegistry.DBInstance, Log: serviceRegistry.Log,
+		}),
+		RedisCache: redisRepository,
+		Pricing:    func() model.Pricing { return serviceRegistry.Settings.Current().Pricing },
+		Cancellation: func() model.Cancellation {
+			return serviceRegistry.Settings.Current().Cancellation
+		},
+		Delivery: func() model.DeliveryRules {
+			return serviceRegistry.Settings.Current().Delivery
+		},
+		Features: func() model.Features { return serviceRegistry.Settings.Current().Features },
 	})
 	orderHandler := NewOrder(&InitOrderHandler{
 		Service: orderService, Log: serviceRegistry.Log,
 	})
 
+	// Inject Order Stream Dependency
+	streamConf := serviceRegistry.Settings.Current().Stream
+	streamHandler := NewStream(&InitStreamHandler{
+		Service: service.NewOrderStream(&service.InitOrderStreamService{
+			Log: serviceRegistry.Log,
+			Hub: serviceRegistry.StreamHub,
+			OutboxRepository: repository.NewOutbox(&repository.InitOutboxRepository{
+				Db: serviceRegistry.DBInstance, Log: serviceRegistry.Log,
+			}),
+			Config: &streamConf,
+		}),
+		Config: &streamConf,
+		Log:    serviceRegistry.Log,
+	})
+
+	// Inject Webhook Dependency
+	webhookConf := serviceRegistry.Settings.Current().Webhook
+	webhookService := service.NewWebhook(&service.InitWebhookService{
+		Log: serviceRegistry.Log,
+		WebhookRepository: repository.NewWebhook(&repository.InitWebhookRepository{
+			Db: serviceRegistry.DBInstance, Log: serviceRegistry.Log,
+		}),
+		StoreRepository: storeRepository,
+		Client:          webhook.NewClient(&webhookConf),
+		Config:          &webhookConf,
+	})
+	webhookHandler := NewWebhook(&InitWebhookHandler{
+		Service: webhookService, Log: serviceRegistry.Log,
+	})
+
+	handoverService := service.NewHandover(&service.InitHandoverService{
+		Log: serviceRegistry.Log,
+		HandoverRepository: repository.NewHandover(&repository.InitHandoverRepository{
+			Db: serviceRegistry.DBInstance, Log: serviceRegistry.Log,
+		}),
+	})
+	handoverHandler := NewHandover(&InitHandoverHandler{
+		Service: handoverService, Log: serviceRegistry.Log,
+	})
+
 	// Inject Auth Dependency
 	userRepository := repository.NewUser(&repository.InitUserRepository{
 		Db: serviceRegistry.DBInstance, Log: serviceRegistry.Log,
@@ -65,12 +141,46 @@ func Register(serviceRegistry *ServiceRegistry) {
 		Service: authService, Log: serviceRegistry.Log,
 	})
 
+	// The order list is read from the replicas, but for the users who wrote
+	// within the lag the replicas may have, at most their maximum lag since
+	// their last check.
+	var readWindow time.Duration
+	if dbConf := serviceRegistry.Settings.Current().DB; len(dbConf.Replicas) > 0 {
+		readWindow = dbConf.ReplicaMaxLag + dbConf.ReplicaCheckInterval
+	}
+	readYourWrites := middleware.NewReadYourWrites(redisRepository, readWindow, serviceRegistry.Log)
+
 	// Add routes for order
 	order := api.Group("/orders", jwtMiddleware)
 	{
-		order.POST("", orderHandler.CreateOrder)
-		order.GET("/all", orderHandler.FindAllOrders)
-		order.PUT("/:CONSIGNMENT_ID/cancel", orderHandler.CancelOrder)
+		order.POST("", orderHandler.CreateOrder, readYourWrites.Write)
+		order.GET("/all", orderHandler.FindAllOrders, readYourWrites.Read)
+		order.GET("/search", orderHandler.SearchOrders, readYourWrites.Read)
+		order.GET("/:CONSIGNMENT_ID", orderHandler.FindOrder)
+		order.PATCH("/:CONSIGNMENT_ID", orderHandler.UpdateOrder, readYourWrites.Write)
+		order.GET("/:CONSIGNMENT_ID/history", orderHandler.FindOrderHistory)
+		order.PUT("/:CONSIGNMENT_ID/cancel", orderHandler.CancelOrder, readYourWrites.Write)
+		order.PUT("/:CONSIGNMENT_ID/archive", orderHandler.ArchiveOrder, readYourWrites.Write)
+		order.PUT("/:CONSIGNMENT_ID/unarchive", orderHandler.UnarchiveOrder, readYourWrites.Write)
+		order.PUT("/archive", orderHandler.ArchiveOrders, readYourWrites.Write)
+		order.PUT("/unarchive", orderHandler.UnarchiveOrders, readYourWrites.Write)
+		order.POST("/:CONSIGNMENT_ID/handovers", handoverHandler.HandOver, readYourWrites.Write)
+		order.GET("/:CONSIGNMENT_ID/handovers", handoverHandler.FindHandovers)
+		order.POST("/:CONSIGNMENT_ID/attempts", orderHandler.RecordAttempt, readYourWrites.Write)
+		if serviceRegistry.StreamHub != nil {
+			order.GET("/stream", streamHandler.StreamOrders)
+		}
+	}
+
+	// Add routes for the webhooks of the stores
+	webhooks := api.Group("/stores/:store_id/webhooks", jwtMiddleware)
+	{
+		webhooks.POST("", webhookHandler.CreateWebhook)
+		webhooks.GET("", webhookHandler.FindWebhooks)
+		webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", webhookHandler.FindDeliveries)
+		webhooks.POST("/:id/deliveries/:delivery_id/replay", webhookHandler.ReplayDelivery)
+		webhooks.POST("/:id/test", webhookHandler.SendTestEvent)
 	}
 
 	// Add routes for auth (login and logout)
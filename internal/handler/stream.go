@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/service"
+	"github.com/kaium123/order/internal/stream"
+	"github.com/kaium123/order/internal/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// streamRetry is the reconnection delay advised to the clients.
+const streamRetry = 3 * time.Second
+
+// StreamHandler is the request handler for the order streams.
+type StreamHandler interface {
+	StreamOrders(c echo.Context) error
+}
+
+type InitStreamHandler struct {
+	Service service.IOrderStream
+	Config  *stream.Config
+	Log     *log.Logger
+}
+
+type streamHandler struct {
+	Handler
+	service service.IOrderStream
+	conf    *stream.Config
+	log     *log.Logger
+}
+
+// NewStream returns a new instance of the Stream handler.
+func NewStream(initStreamHandler *InitStreamHandler) StreamHandler {
+	return &streamHandler{
+		service: initStreamHandler.Service,
+		conf:    initStreamHandler.Config,
+		log:     initStreamHandler.Log,
+	}
+}
+
+// StreamOrders pushes the order events of the user as Server-Sent Events,
+// with the transaction and outbox event IDs as event ID. A stream resumed
+// with Last-Event-ID, or the last_event_id query parameter, first gets the
+// events it missed.
+func (t *streamHandler) StreamOrders(c echo.Context) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.QueryParam("last_event_id")
+	}
+	var (
+		afterTxID string
+		afterID   int64
+	)
+	if lastEventID != "" {
+		if afterTxID, afterID, err = model.ParseStreamID(lastEventID); err != nil {
+			return c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, map[string][]string{"last_event_id": []string{err.Error()}}, "Please provide a valid request"))
+		}
+	}
+
+	// subscribe before the replay so no event falls in between
+	subscription := t.service.Subscribe(userId)
+	defer subscription.Close()
+
+	var replay []*model.OutboxEvent
+	truncated := false
+	if lastEventID != "" {
+		if replay, truncated, err = t.service.Replay(ctx, userId, afterTxID, afterID); err != nil {
+			t.log.Error(ctx, err.Error())
+			return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{"order_stream_error": []string{err.Error()}}, "Internal server error"))
+		}
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+
+	if truncated {
+		fmt.Fprint(w, "event: reset\ndata: {\"reason\":\"too many events missed, fetch the orders again\"}\n\n")
+	}
+	replayed := make(map[int64]bool, len(replay))
+	for _, event := range replay {
+		if err := writeEvent(w, event); err != nil {
+			return nil
+		}
+		replayed[event.ID] = true
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(t.conf.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return nil // too slow or shutting down, the client resumes
+			}
+			if replayed[event.ID] {
+				continue
+			}
+			if err := writeEvent(w, event); err != nil {
+				return nil
+			}
+			w.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return nil
+			}
+			w.Flush()
+		}
+	}
+}
+
+// writeEvent writes given event as a Server-Sent Event named after its type.
+func writeEvent(w *echo.Response, event *model.OutboxEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.StreamID(), event.Type, data)
+	return err
+}
@@ -1,9 +1,7 @@
 package handler
 
 import (
-	"database/sql"
 	"errors"
-	"fmt"
 	"github.com/kaium123/order/internal/log"
 	"github.com/kaium123/order/internal/model"
 	"github.com/kaium123/order/internal/service"
@@ -51,12 +49,9 @@ func (t *authHandler) Login(c echo.Context) error {
 
 	// Call the service to handle login
 	token, err := t.service.Login(ctx, &req)
-	fmt.Println(token)
-	fmt.Println(err)
 	if err != nil {
 		t.log.Error(ctx, err.Error())
-		fmt.Println(err)
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, model.ErrNotFound) {
 			return c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, nil, "The user credentials were incorrect."))
 		}
 		return c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, map[string][]string{"invalid_request": []string{err.Error()}}, "The user credentials were incorrect."))
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/service"
+	"github.com/kaium123/order/internal/utils"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// WebhookHandler is the request handler for the webhooks of the stores.
+type WebhookHandler interface {
+	CreateWebhook(c echo.Context) error
+	FindWebhooks(c echo.Context) error
+	DeleteWebhook(c echo.Context) error
+	FindDeliveries(c echo.Context) error
+	ReplayDelivery(c echo.Context) error
+	SendTestEvent(c echo.Context) error
+}
+
+type InitWebhookHandler struct {
+	Service service.IWebhook
+	Log     *log.Logger
+}
+
+type webhookHandler struct {
+	Handler
+	service service.IWebhook
+	log     *log.Logger
+}
+
+// NewWebhook returns a new instance of the Webhook handler.
+func NewWebhook(initWebhookHandler *InitWebhookHandler) WebhookHandler {
+	return &webhookHandler{
+		log:     initWebhookHandler.Log,
+		service: initWebhookHandler.Service,
+	}
+}
+
+func (t *webhookHandler) CreateWebhook(c echo.Context) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+	var req model.CreateWebhookRequest
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	if err := t.MustBind(c, &req); err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, map[string][]string{"invalid_request": []string{err.Error()}}, "Please provide a valid request body"))
+	}
+
+	req.UserId = userId
+	validationErr := req.Validate()
+	if validationErr != nil {
+		t.log.Error(ctx, "validation errors : ", zap.Any("", validationErr))
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnprocessableEntity, validationErr.Errors, "Please fix the given errors"))
+	}
+
+	res, err := t.service.CreateWebhook(ctx, &req)
+	if err != nil {
+		return t.errorResponse(c, err, "webhook_creation_error", "Store not found")
+	}
+
+	return c.JSON(http.StatusCreated, utils.GetResponseData(http.StatusOK, res, "Webhook Created Successfully"))
+}
+
+func (t *webhookHandler) FindWebhooks(c echo.Context) error {
+	req, err := t.bindWebhook(c)
+	if req == nil {
+		return err
+	}
+
+	res, err := t.service.FindWebhooks(c.Request().Context(), req)
+	if err != nil {
+		return t.errorResponse(c, err, "webhook_finding_error", "Store not found")
+	}
+
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, res, "Webhooks successfully fetched."))
+}
+
+func (t *webhookHandler) DeleteWebhook(c echo.Context) error {
+	req, err := t.bindWebhook(c)
+	if req == nil {
+		return err
+	}
+
+	if err := t.service.DeleteWebhook(c.Request().Context(), req); err != nil {
+		return t.errorResponse(c, err, "webhook_deletion_error", "Webhook not found")
+	}
+
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, nil, "Webhook Deleted Successfully"))
+}
+
+func (t *webhookHandler) FindDeliveries(c echo.Context) error {
+	req, err := t.bindWebhook(c)
+	if req == nil {
+		return err
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page <= 0 {
+		page = 1
+	}
+	reqParams := &model.FindDeliveriesRequest{
+		WebhookRequest: *req,
+		Status:         c.QueryParam("status"),
+		Limit:          limit,
+		Offset:         (page - 1) * limit,
+	}
+
+	res, err := t.service.FindDeliveries(c.Request().Context(), reqParams)
+	if err != nil {
+		return t.errorResponse(c, err, "delivery_finding_error", "Webhook not found")
+	}
+
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, res, "Deliveries successfully fetched."))
+}
+
+func (t *webhookHandler) ReplayDelivery(c echo.Context) error {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+	var req model.WebhookDeliveryRequest
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	if err := t.MustBind(c, &req); err != nil {
+		t.log.Error(ctx, err.Error())
+		return c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, map[string][]string{"invalid_request": []string{err.Error()}}, "Please provide a valid request"))
+	}
+	req.UserId = userId
+
+	if err := t.service.ReplayDelivery(ctx, &req); err != nil {
+		return t.errorResponse(c, err, "delivery_replay_error", "Delivery not found")
+	}
+
+	return c.JSON(http.StatusAccepted, utils.GetResponseData(http.StatusOK, nil, "Delivery Scheduled Successfully"))
+}
+
+func (t *webhookHandler) SendTestEvent(c echo.Context) error {
+	req, err := t.bindWebhook(c)
+	if req == nil {
+		return err
+	}
+
+	res, err := t.service.SendTestEvent(c.Request().Context(), req)
+	if err != nil {
+		return t.errorResponse(c, err, "webhook_test_error", "Webhook not found")
+	}
+
+	return c.JSON(http.StatusOK, utils.GetResponseData(http.StatusOK, res, "Test Event Sent"))
+}
+
+// bindWebhook binds the webhook request of the authenticated user. It writes
+// the error response itself and returns a nil request, along with the error
+// of the write.
+func (t *webhookHandler) bindWebhook(c echo.Context) (*model.WebhookRequest, error) {
+	ctx := c.Request().Context()
+	var responseErr utils.ResponseError
+	var req model.WebhookRequest
+
+	userId, err := GetUserId(c)
+	if err != nil {
+		t.log.Error(ctx, err.Error())
+		return nil, c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
+	}
+
+	if err := t.MustBind(c, &req); err != nil {
+		t.log.Error(ctx, err.Error())
+		return nil, c.JSON(responseErr.GetErrorResponse(http.StatusBadRequest, map[string][]string{"invalid_request": []string{err.Error()}}, "Please provide a valid request"))
+	}
+	req.UserId = userId
+	return &req, nil
+}
+
+// errorResponse writes the response of a service error, with given message
+// if it's model.ErrNotFound.
+func (t *webhookHandler) errorResponse(c echo.Context, err error, key, notFound string) error {
+	var responseErr utils.ResponseError
+	t.log.Error(c.Request().Context(), err.Error())
+	if errors.Is(err, model.ErrNotFound) {
+		return c.JSON(responseErr.GetErrorResponse(http.StatusNotFound, map[string][]string{key: []string{err.Error()}}, notFound))
+	}
+	return c.JSON(responseErr.GetErrorResponse(http.StatusInternalServerError, map[string][]string{key: []string{err.Error()}}, "Internal server error"))
+}
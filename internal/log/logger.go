@@ -7,6 +7,12 @@ import (
 
 var TraceIDContextKey = "trace_id" // keep it short
 
+var RequestIDContextKey = "request_id"
+
+var UserIDContextKey = "user_id"
+
+var RouteContextKey = "route"
+
 // TraceID from context.
 func TraceID(ctx context.Context) (traceID string) {
 	traceID, _ = ctx.Value(TraceIDContextKey).(string)
@@ -18,14 +24,54 @@ func WithTraceID(ctx context.Context, traceID string) context.Context {
 	return context.WithValue(ctx, TraceIDContextKey, traceID) // nolint
 }
 
+// RequestID from context.
+func RequestID(ctx context.Context) (requestID string) {
+	requestID, _ = ctx.Value(RequestIDContextKey).(string)
+	return
+}
+
+// WithRequestID sets given request ID to given context.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, RequestIDContextKey, requestID) // nolint
+}
+
+// UserID from context, 0 if the request isn't authenticated.
+func UserID(ctx context.Context) (userID int64) {
+	userID, _ = ctx.Value(UserIDContextKey).(int64)
+	return
+}
+
+// WithUserID sets given user ID to given context.
+func WithUserID(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, UserIDContextKey, userID) // nolint
+}
+
+// Route from context.
+func Route(ctx context.Context) (route string) {
+	route, _ = ctx.Value(RouteContextKey).(string)
+	return
+}
+
+// WithRoute sets given route to given context.
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, RouteContextKey, route) // nolint
+}
+
+// Config of the logger.
+type Config struct {
+	Level string `json:"level" yaml:"level" toml:"level" mapstructure:"level"`
+}
+
 type Logger struct {
 	*zap.Logger
+	level zap.AtomicLevel // shared by all the derived loggers
 }
 
 func New() *Logger {
 	conf := new(zap.Config)
 	conf.DisableStacktrace = true
 	conf.Encoding = "console"
+	conf.Level = zap.NewAtomicLevel()
 	if err := conf.Level.UnmarshalText([]byte("debug")); err != nil {
 		panic(err)
 	}
@@ -34,7 +80,7 @@ func New() *Logger {
 	conf.OutputPaths = []string{"stdout"}
 
 	//zaplog, err := conf.Build()
-	zaplog, err := conf.Build(zap.AddCaller(), zap.AddCallerSkip(1))
+	zaplog, err := conf.Build(zap.AddCaller(), zap.AddCallerSkip(1), zap.WrapCore(newRedactCore))
 	if err != nil {
 		panic(err)
 	}
@@ -42,14 +88,25 @@ func New() *Logger {
 	log := &Logger{}
 	logger := zaplog
 	log.Logger = logger
+	log.level = conf.Level
 	return log
 }
 
+// SetLevel changes the level of the logger and every logger derived from it.
+// An empty level is ignored.
+func (log *Logger) SetLevel(level string) error {
+	if level == "" {
+		return nil
+	}
+	return log.level.UnmarshalText([]byte(level))
+}
+
 // Named adds a new path segment to the logger's name. Segments are joined by
 // periods. By default, Loggers are unnamed.
 func (log *Logger) Named(s string) *Logger {
 	named := new(Logger)
 	named.Logger = log.Logger.Named(s)
+	named.level = log.level
 
 	return named
 }
@@ -59,6 +116,7 @@ func (log *Logger) Named(s string) *Logger {
 func (log *Logger) WithOptions(opts ...zap.Option) *Logger {
 	n := new(Logger)
 	n.Logger = log.Logger.WithOptions(opts...)
+	n.level = log.level
 	return n
 }
 
@@ -67,9 +125,19 @@ func (log *Logger) WithOptions(opts ...zap.Option) *Logger {
 func (log *Logger) With(fields ...zap.Field) *Logger {
 	n := new(Logger)
 	n.Logger = log.Logger.With(fields...)
+	n.level = log.level
 	return n
 }
 
+// Debug logs a message at DebugLevel. The message includes any fields passed
+// at the log site, as well as any fields accumulated on the logger.
+func (log *Logger) Debug(ctx context.Context, msg string, fields ...zap.Field) {
+	if ctx != nil {
+		fields = AddContextFields(ctx, fields...)
+	}
+	log.Logger.Debug(msg, fields...)
+}
+
 // Info logs a message at InfoLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (log *Logger) Info(ctx context.Context, msg string, fields ...zap.Field) {
@@ -79,6 +147,15 @@ func (log *Logger) Info(ctx context.Context, msg string, fields ...zap.Field) {
 	log.Logger.Info(msg, fields...)
 }
 
+// Warn logs a message at WarnLevel. The message includes any fields passed
+// at the log site, as well as any fields accumulated on the logger.
+func (log *Logger) Warn(ctx context.Context, msg string, fields ...zap.Field) {
+	if ctx != nil {
+		fields = AddContextFields(ctx, fields...)
+	}
+	log.Logger.Warn(msg, fields...)
+}
+
 // Error logs a message at ErrorLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (log *Logger) Error(ctx context.Context, msg string, fields ...zap.Field) {
@@ -100,17 +177,31 @@ func (log *Logger) Fatal(ctx context.Context, msg string, fields ...zap.Field) {
 	log.Logger.Fatal(msg, fields...)
 }
 
-// AddContextFields returns zap.Fields with request_id
-// and trace_id if set in given context.
+// AddContextFields returns zap.Fields with request_id, trace_id, user_id
+// and route if set in given context.
 func AddContextFields(ctx context.Context, flds ...zap.Field) (
 	all []zap.Field) {
 	all = flds
 
-	var traceID = TraceID(ctx)
+	var (
+		requestID = RequestID(ctx)
+		traceID   = TraceID(ctx)
+		userID    = UserID(ctx)
+		route     = Route(ctx)
+	)
 
+	if requestID != "" {
+		all = append(all, zap.String("request_id", requestID))
+	}
 	if traceID != "" {
 		all = append(all, zap.String("trace_id", traceID))
 	}
+	if userID != 0 {
+		all = append(all, zap.Int64("user_id", userID))
+	}
+	if route != "" {
+		all = append(all, zap.String("route", route))
+	}
 
 	return
 }
@@ -0,0 +1,69 @@
+package log
+
+import (
+	"reflect"
+
+	"github.com/kaium123/order/internal/pii"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactCore masks the personally identifiable information of the fields
+// before they're written: string fields by their key, e.g. "email" or
+// "token", and the fields of logged values tagged pii.
+type redactCore struct {
+	zapcore.Core
+}
+
+func newRedactCore(core zapcore.Core) zapcore.Core {
+	return &redactCore{Core: core}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, redactFields(fields))
+}
+
+// redactFields returns given fields with the personally identifiable
+// information masked, given slice is left untouched.
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			kind, ok := pii.KeyKind(f.Key)
+			if !ok {
+				continue
+			}
+			f.String = pii.MaskString(kind, f.String)
+		case zapcore.ReflectType:
+			if kind, ok := pii.KeyKind(f.Key); ok && reflect.ValueOf(f.Interface).Kind() == reflect.String {
+				f = zapcore.Field{Key: f.Key, Type: zapcore.StringType,
+					String: pii.MaskString(kind, reflect.ValueOf(f.Interface).String())}
+				break
+			}
+			f.Interface = pii.Mask(f.Interface)
+		default:
+			continue
+		}
+
+		if out == nil {
+			out = make([]zapcore.Field, len(fields))
+			copy(out, fields)
+		}
+		out[i] = f
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+// Package metrics provides the Prometheus collectors of the application.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "orders"
+
+// Registry holds every collector of the application.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// ConfigReloads counts the configuration reloads by result.
+	ConfigReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Configuration reloads by result.",
+	}, []string{"result"})
+
+	// ConfigInfo is 1 for the hash of the running configuration.
+	ConfigInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_info",
+		Help:      "Hash of the running configuration.",
+	}, []string{"hash"})
+
+	// HTTPRequests counts the HTTP requests by method, route and status.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes the latency of the HTTP requests by method,
+	// route and status.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the HTTP requests by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// DBQueryDuration observes the latency of the database queries by
+	// operation, i.e. SELECT, INSERT, UPDATE or DELETE.
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Latency of the database queries by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	// DBQueryErrors counts the failed database queries by operation.
+	DBQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_errors_total",
+		Help:      "Failed database queries by operation.",
+	}, []string{"operation"})
+
+	// DBReplicaUp is 1 for the read replicas passing their health check.
+	DBReplicaUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "replica_up",
+		Help:      "Whether the read replica passes its health check.",
+	}, []string{"replica"})
+
+	// RedisCommandDuration observes the latency of the Redis commands by name.
+	RedisCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "command_duration_seconds",
+		Help:      "Latency of the Redis commands by name.",
+		Buckets:   []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25},
+	}, []string{"command"})
+
+	// CacheLookups counts the Redis cache lookups by key kind and result,
+	// hit or miss.
+	CacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Redis cache lookups by key kind and result.",
+	}, []string{"kind", "result"})
+
+	// OutboxEventsPublished counts the outbox events published by sink.
+	OutboxEventsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "events_published_total",
+		Help:      "Outbox events published by sink.",
+	}, []string{"sink"})
+
+	// OutboxPublishErrors counts the failed publishing of outbox event
+	// batches by sink.
+	OutboxPublishErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "publish_errors_total",
+		Help:      "Failed publishing of outbox event batches by sink.",
+	}, []string{"sink"})
+
+	// WebhookDeliveries counts the webhook delivery attempts by resulting
+	// status: pending ones are retried.
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhook",
+		Name:      "delivery_attempts_total",
+		Help:      "Webhook delivery attempts by resulting status.",
+	}, []string{"status"})
+
+	// StreamConnections is the number of open order streams.
+	StreamConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "stream",
+		Name:      "connections",
+		Help:      "Open order streams.",
+	})
+
+	// OrdersCreated counts the created orders.
+	OrdersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "created_total",
+		Help:      "Created orders.",
+	})
+
+	// OrdersCancelled counts the cancelled orders by reason.
+	OrdersCancelled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cancelled_total",
+		Help:      "Cancelled orders by reason.",
+	}, []string{"reason"})
+
+	// OrdersArchived counts the archived orders by who archived them.
+	OrdersArchived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "archived_total",
+		Help:      "Archived orders by who archived them, the merchant or the archiver.",
+	}, []string{"by"})
+
+	// OrderHandovers counts the handovers of the orders by the transfer
+	// status they lead to.
+	OrderHandovers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "handovers_total",
+		Help:      "Handovers of the orders by the transfer status they lead to.",
+	}, []string{"to"})
+
+	// DeliveryAttempts counts the delivery attempts of the orders by outcome.
+	DeliveryAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "delivery_attempts_total",
+		Help:      "Delivery attempts of the orders by outcome, delivered or failed.",
+	}, []string{"outcome"})
+
+	// CODAmountBooked sums the amount to collect on delivery of the created
+	// orders.
+	CODAmountBooked = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cod_amount_booked_total",
+		Help:      "Amount to collect on delivery of the created orders.",
+	})
+)
+
+// Cache lookup results.
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// Who archived the orders.
+const (
+	ArchivedByMerchant = "merchant"
+	ArchivedByArchiver = "archiver"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ConfigReloads,
+		ConfigInfo,
+		HTTPRequests,
+		HTTPRequestDuration,
+		DBQueryDuration,
+		DBQueryErrors,
+		DBReplicaUp,
+		RedisCommandDuration,
+		CacheLookups,
+		OutboxEventsPublished,
+		OutboxPublishErrors,
+		WebhookDeliveries,
+		StreamConnections,
+		OrdersCreated,
+		OrdersCancelled,
+		OrdersArchived,
+		OrderHandovers,
+		DeliveryAttempts,
+		CODAmountBooked,
+	)
+}
+
+// SetConfigHash replaces the hash reported by ConfigInfo.
+func SetConfigHash(hash string) {
+	ConfigInfo.Reset()
+	ConfigInfo.WithLabelValues(hash).Set(1)
+}
@@ -63,7 +63,6 @@ func NewJWTMiddleware(config JWTConfig, log *log.Logger) echo.MiddlewareFunc {
 				// Extract the user_id from the claims
 				if userID, ok := claims["user_id"].(float64); ok {
 					key := fmt.Sprintf("access_token:%s", tokenString)
-					fmt.Println("key : ", key)
 					getToken, err := config.RedisCache.GetToken(ctx, key)
 					if err != nil || getToken == "" {
 						log.Error(ctx, "failed to found token from redis")
@@ -82,15 +81,21 @@ func NewJWTMiddleware(config JWTConfig, log *log.Logger) echo.MiddlewareFunc {
 						}
 					}
 
-					fmt.Println("get token from redis - ", tokenString)
-
 					// Set the user_id in the context
 					c.Set("user_id", int64(userID))
+					setRequestUserID(c, int64(userID))
 				} else {
 					log.Error(ctx, "User ID not found in token claims")
 					return c.JSON(responseErr.GetErrorResponse(http.StatusUnauthorized, nil, "Unauthorized"))
 				}
 
+				// Set the role, tokens issued before the roles belong to merchants
+				role := model.RoleMerchant
+				if claim, ok := claims["role"].(string); ok && claim != "" {
+					role = model.Role(claim)
+				}
+				c.Set("role", role)
+
 				// Optionally set all claims in the context if needed
 				c.Set("user_claims", claims)
 
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kaium123/order/internal/utils"
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitConfig holds the per client rate limit.
+type RateLimitConfig struct {
+	Enable    bool          `json:"enable" yaml:"enable" toml:"enable" mapstructure:"enable"`
+	Rate      float64       `json:"rate" yaml:"rate" toml:"rate" mapstructure:"rate"` // requests per second
+	Burst     int           `json:"burst" yaml:"burst" toml:"burst" mapstructure:"burst"`
+	ExpiresIn time.Duration `json:"expires_in" yaml:"expires_in" toml:"expires_in" mapstructure:"expires_in"`
+}
+
+// RateLimiter limits requests per client IP. Its configuration can be
+// replaced at runtime with Update.
+type RateLimiter struct {
+	store atomic.Pointer[rateLimitStore]
+}
+
+type rateLimitStore struct {
+	enable bool
+	*echomiddleware.RateLimiterMemoryStore
+}
+
+// NewRateLimiter returns a new RateLimiter with given configurations.
+func NewRateLimiter(conf RateLimitConfig) *RateLimiter {
+	r := new(RateLimiter)
+	r.Update(conf)
+	return r
+}
+
+// Update replaces the configurations of the RateLimiter. Counters of the
+// clients are reset.
+func (r *RateLimiter) Update(conf RateLimitConfig) {
+	r.store.Store(&rateLimitStore{
+		enable: conf.Enable,
+		RateLimiterMemoryStore: echomiddleware.NewRateLimiterMemoryStoreWithConfig(
+			echomiddleware.RateLimiterMemoryStoreConfig{
+				Rate:      rate.Limit(conf.Rate),
+				Burst:     conf.Burst,
+				ExpiresIn: conf.ExpiresIn,
+			}),
+	})
+}
+
+// Allow implements echo middleware.RateLimiterStore.
+func (r *RateLimiter) Allow(identifier string) (bool, error) {
+	store := r.store.Load()
+	if !store.enable {
+		return true, nil
+	}
+	return store.Allow(identifier)
+}
+
+// Middleware returns the echo middleware.
+func (r *RateLimiter) Middleware() echo.MiddlewareFunc {
+	return echomiddleware.RateLimiterWithConfig(echomiddleware.RateLimiterConfig{
+		Store: r,
+		DenyHandler: func(c echo.Context, _ string, _ error) error {
+			var responseErr utils.ResponseError
+			return c.JSON(responseErr.GetErrorResponse(http.StatusTooManyRequests, nil, "Too many requests"))
+		},
+		ErrorHandler: func(c echo.Context, _ error) error {
+			var responseErr utils.ResponseError
+			return c.JSON(responseErr.GetErrorResponse(http.StatusForbidden, nil, "Forbidden"))
+		},
+	})
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/repository"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ReadYourWrites sends the reads of the users who wrote recently to the
+// primary, so that they see their writes before the replicas catch up. The
+// writes are marked in Redis, shared by the instances of the service.
+type ReadYourWrites struct {
+	cache repository.IRedisCache
+	// window the reads of a user go to the primary for after their write, 0
+	// when there is no replica to lag behind.
+	window time.Duration
+	log    *log.Logger
+}
+
+// NewReadYourWrites returns a new ReadYourWrites keeping the reads on the
+// primary for given window after a write.
+func NewReadYourWrites(cache repository.IRedisCache, window time.Duration, logger *log.Logger) *ReadYourWrites {
+	return &ReadYourWrites{cache: cache, window: window, log: logger}
+}
+
+// Write marks the user of the successful requests of the handler as having
+// written. The mark is recorded before the response is sent, so that the
+// reads following it go to the primary.
+func (m *ReadYourWrites) Write(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, ok := c.Get("user_id").(int64)
+		if m.window <= 0 || !ok {
+			return next(c)
+		}
+		c.Response().Before(func() {
+			if c.Response().Status >= http.StatusBadRequest {
+				return
+			}
+			// the request succeeded, the mark failing only risks a stale read
+			if err := m.cache.MarkWrite(c.Request().Context(), userID, m.window); err != nil {
+				m.log.Warn(c.Request().Context(), "marking the write", zap.Error(err))
+			}
+		})
+		return next(c)
+	}
+}
+
+// Read sends the reads of the handler to the primary for the users who wrote
+// recently, or when their writes can't be looked up.
+func (m *ReadYourWrites) Read(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, ok := c.Get("user_id").(int64)
+		if m.window <= 0 || !ok {
+			return next(c)
+		}
+		ctx := c.Request().Context()
+		wrote, err := m.cache.WroteRecently(ctx, userID)
+		if err != nil {
+			m.log.Warn(ctx, "reading from the primary", zap.Error(err))
+		}
+		if wrote || err != nil {
+			c.SetRequest(c.Request().WithContext(db.WithPrimary(ctx)))
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeMarks keeps the write marks in memory.
+type writeMarks struct {
+	repository.IRedisCache
+	marks map[int64]time.Duration
+}
+
+func (w *writeMarks) MarkWrite(_ context.Context, userID int64, window time.Duration) error {
+	w.marks[userID] = window
+	return nil
+}
+
+func (w *writeMarks) WroteRecently(_ context.Context, userID int64) (bool, error) {
+	_, ok := w.marks[userID]
+	return ok, nil
+}
+
+// markedRecorder records whether the user was marked when the response
+// header was written.
+type markedRecorder struct {
+	*httptest.ResponseRecorder
+	marks  *writeMarks
+	userID int64
+	marked bool
+}
+
+func (r *markedRecorder) WriteHeader(code int) {
+	_, r.marked = r.marks.marks[r.userID]
+	r.ResponseRecorder.WriteHeader(code)
+}
+
+func TestReadYourWrites(t *testing.T) {
+	marks := &writeMarks{marks: map[int64]time.Duration{}}
+	m := NewReadYourWrites(marks, 10*time.Second, log.New())
+	e := echo.New()
+
+	serve := func(middleware echo.MiddlewareFunc, userID int64, handler echo.HandlerFunc) (primary, marked bool) {
+		rec := &markedRecorder{ResponseRecorder: httptest.NewRecorder(), marks: marks, userID: userID}
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		c.Set("user_id", userID)
+		if err := middleware(func(c echo.Context) error {
+			primary = db.ReadsPrimary(c.Request().Context())
+			return handler(c)
+		})(c); err != nil {
+			e.HTTPErrorHandler(err, c)
+		}
+		return primary, rec.marked
+	}
+	status := func(code int) echo.HandlerFunc {
+		return func(c echo.Context) error { return c.NoContent(code) }
+	}
+	read := func(userID int64) bool {
+		primary, _ := serve(m.Read, userID, status(http.StatusOK))
+		return primary
+	}
+
+	serve(m.Write, 7, status(http.StatusUnprocessableEntity))
+	serve(m.Write, 7, func(echo.Context) error { return echo.NewHTTPError(http.StatusConflict) })
+	assert.Empty(t, marks.marks, "the failed writes aren't marked")
+	assert.False(t, read(7))
+
+	_, marked := serve(m.Write, 7, status(http.StatusCreated))
+	assert.True(t, marked, "the write is marked before the response is sent")
+	assert.Equal(t, map[int64]time.Duration{7: 10 * time.Second}, marks.marks)
+	assert.True(t, read(7), "the writer reads the primary")
+	assert.False(t, read(8), "the others read the replicas")
+
+	// without replicas nothing is marked
+	serve(NewReadYourWrites(marks, 0, log.New()).Write, 9, status(http.StatusCreated))
+	assert.NotContains(t, marks.marks, int64(9))
+}
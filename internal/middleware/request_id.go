@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"github.com/google/uuid"
+	"github.com/kaium123/order/internal/log"
+	"github.com/labstack/echo/v4"
+)
+
+// maxRequestIDLength bounds the accepted X-Request-ID headers.
+const maxRequestIDLength = 128
+
+// RequestID accepts the X-Request-ID header of the request, or generates one,
+// echoes it back in the response and sets it along with the route to the
+// request context so every log line of the request carries them.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			requestID := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(requestID) {
+				requestID = uuid.NewString()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+
+			ctx := log.WithRequestID(req.Context(), requestID)
+			if route := c.Path(); route != "" {
+				ctx = log.WithRoute(ctx, route)
+			}
+			c.SetRequest(req.WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// validRequestID reports whether given request ID is short and made of
+// printable ASCII characters only, so it's safe to log and echo back.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// setRequestUserID sets given user ID to the request context for the logs.
+func setRequestUserID(c echo.Context, userID int64) {
+	c.SetRequest(c.Request().WithContext(log.WithUserID(c.Request().Context(), userID)))
+}
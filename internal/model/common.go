@@ -11,4 +11,10 @@ type PaginationResponse struct {
 	PerPage     int `json:"per_page"`
 	TotalInPage int `json:"total_in_page"`
 	LastPage    int `json:"last_page"`
+	// TotalEstimated is set when Total is a planner estimate.
+	TotalEstimated bool `json:"total_estimated,omitempty"`
+	// NextCursor and PrevCursor are set on the keyset paginated listings
+	// having more in either direction.
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
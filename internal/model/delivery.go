@@ -0,0 +1,147 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kaium123/order/internal/utils"
+	"github.com/uptrace/bun"
+)
+
+// ErrInvalidAttempt is returned for a delivery attempt of an order which
+// isn't out for delivery.
+var ErrInvalidAttempt = errors.New("invalid delivery attempt")
+
+// Outcomes of the delivery attempts.
+const (
+	AttemptDelivered = "delivered"
+	AttemptFailed    = "failed"
+)
+
+// FailureReasons are the reasons a delivery attempt may fail for.
+var FailureReasons = []string{
+	"recipient_unreachable", "recipient_unavailable", "recipient_refused",
+	"wrong_address", "payment_not_ready", "other",
+}
+
+// DeliveryRules holds the rules of the delivery attempts.
+type DeliveryRules struct {
+	// MaxFailedAttempts is the number of failed attempts returning an order
+	// to the merchant.
+	MaxFailedAttempts int `json:"max_failed_attempts" yaml:"max_failed_attempts" toml:"max_failed_attempts" mapstructure:"max_failed_attempts"`
+}
+
+// DeliveryAttempt records an attempt of a rider to deliver an order.
+type DeliveryAttempt struct {
+	bun.BaseModel `bun:"table:delivery_attempts"`
+
+	ID      int64 `bun:"id,pk,autoincrement"`
+	OrderID int64 `bun:"order_id,notnull"`
+	// Attempt is the number of the attempt of the order, from 1.
+	Attempt       int       `bun:"attempt,notnull"`
+	Outcome       string    `bun:"outcome,notnull"`
+	FailureReason string    `bun:"failure_reason,nullzero"`
+	RiderRef      string    `bun:"rider_ref,nullzero"`
+	RiderNotes    string    `bun:"rider_notes,nullzero"`
+	RecordedBy    int64     `bun:"recorded_by,notnull"`
+	AttemptedAt   time.Time `bun:"attempted_at,notnull"`
+	CreatedAt     time.Time `bun:"created_at,default:current_timestamp,notnull"`
+}
+
+// AttemptRequest is the body of a delivery attempt of an order. UserId and
+// OwnerId are as in HandoverRequest.
+type AttemptRequest struct {
+	UserId        int64  `json:"-"`
+	OwnerId       int64  `json:"-"`
+	ConsignmentID string `json:"-"`
+	Outcome       string `json:"outcome"`
+	FailureReason string `json:"failure_reason"`
+	RiderRef      string `json:"rider_ref"`
+	RiderNotes    string `json:"rider_notes"`
+	// AttemptedAt defaults to now, it may not be in the future.
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// Validate validates the delivery attempt.
+func (r *AttemptRequest) Validate(now time.Time) *utils.ResponseError {
+	responseError := &utils.ResponseError{
+		Code:    "422",
+		Message: "Please fix the given errors",
+		Type:    "error",
+		Errors:  make(map[string][]string),
+	}
+
+	r.Outcome = strings.ToLower(strings.TrimSpace(r.Outcome))
+	r.FailureReason = strings.ToLower(strings.TrimSpace(r.FailureReason))
+	switch r.Outcome {
+	case AttemptDelivered:
+		if r.FailureReason != "" {
+			responseError.AddValidationError("failure_reason", "The failure reason is only given for the failed attempts.")
+		}
+	case AttemptFailed:
+		if r.FailureReason == "" {
+			responseError.AddValidationError("failure_reason", "The failure reason field is required.")
+		} else if !slices.Contains(FailureReasons, r.FailureReason) {
+			responseError.AddValidationError("failure_reason", "The failure reason must be one of "+strings.Join(FailureReasons, ", ")+".")
+		}
+	default:
+		responseError.AddValidationError("outcome", "The outcome must be delivered or failed.")
+	}
+	if len(r.RiderRef) > 50 {
+		responseError.AddValidationError("rider_ref", "The rider ref may not be greater than 50 characters.")
+	}
+	if len(r.RiderNotes) > 500 {
+		responseError.AddValidationError("rider_notes", "The rider notes may not be greater than 500 characters.")
+	}
+	if r.AttemptedAt.IsZero() {
+		r.AttemptedAt = now
+	} else if r.AttemptedAt.After(now) {
+		responseError.AddValidationError("attempted_at", "The attempted at may not be in the future.")
+	}
+
+	if len(responseError.Errors) > 0 {
+		return responseError
+	}
+	return nil
+}
+
+type DeliveryAttemptResponse struct {
+	Attempt       int       `json:"attempt"`
+	Outcome       string    `json:"outcome"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	RiderRef      string    `json:"rider_ref,omitempty"`
+	RiderNotes    string    `json:"rider_notes,omitempty"`
+	RecordedBy    int64     `json:"recorded_by"`
+	AttemptedAt   time.Time `json:"attempted_at"`
+}
+
+// NewDeliveryAttemptResponse returns the response of given attempt.
+func NewDeliveryAttemptResponse(attempt *DeliveryAttempt) *DeliveryAttemptResponse {
+	return &DeliveryAttemptResponse{
+		Attempt:       attempt.Attempt,
+		Outcome:       attempt.Outcome,
+		FailureReason: attempt.FailureReason,
+		RiderRef:      attempt.RiderRef,
+		RiderNotes:    attempt.RiderNotes,
+		RecordedBy:    attempt.RecordedBy,
+		AttemptedAt:   attempt.AttemptedAt,
+	}
+}
+
+// FindOrderRequest reads an order, OwnerId as in HandoverRequest.
+type FindOrderRequest struct {
+	OwnerId       int64
+	ConsignmentID string
+}
+
+// OrderDetailResponse is an order with its delivery attempts, the oldest
+// first.
+type OrderDetailResponse struct {
+	*OrderResponse
+	UserID         int64                      `json:"user_id"`
+	RTOFee         float64                    `json:"rto_fee"`
+	FailedAttempts int                        `json:"failed_attempts"`
+	Attempts       []*DeliveryAttemptResponse `json:"attempts"`
+}
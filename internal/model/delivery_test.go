@@ -0,0 +1,60 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttemptRequestValidate(t *testing.T) {
+	now := time.Date(2024, 11, 17, 10, 0, 0, 0, time.UTC)
+
+	req := &AttemptRequest{Outcome: " Failed ", FailureReason: "Recipient_Unreachable"}
+	require.Nil(t, req.Validate(now))
+	assert.Equal(t, AttemptFailed, req.Outcome)
+	assert.Equal(t, "recipient_unreachable", req.FailureReason)
+	assert.Equal(t, now, req.AttemptedAt)
+
+	require.Nil(t, (&AttemptRequest{Outcome: "delivered"}).Validate(now))
+
+	for _, test := range []struct {
+		req  *AttemptRequest
+		keys []string
+	}{
+		{&AttemptRequest{}, []string{"outcome"}},
+		{&AttemptRequest{Outcome: "failed"}, []string{"failure_reason"}},
+		{&AttemptRequest{Outcome: "failed", FailureReason: "rain"}, []string{"failure_reason"}},
+		{&AttemptRequest{Outcome: "delivered", FailureReason: "other"}, []string{"failure_reason"}},
+		{&AttemptRequest{Outcome: "delivered", AttemptedAt: now.Add(time.Hour)}, []string{"attempted_at"}},
+	} {
+		responseError := test.req.Validate(now)
+		require.NotNil(t, responseError)
+		for _, key := range test.keys {
+			assert.Contains(t, responseError.Errors, key)
+		}
+	}
+}
+
+func TestOrderApplyRTO(t *testing.T) {
+	order := &Order{DeliveryFee: 100, CodFee: 10, TotalFee: 110}
+	order.ApplyRTO(Pricing{RTOPercentage: 50})
+	assert.Equal(t, 50.0, order.RTOFee)
+	assert.Zero(t, order.CodFee)
+	assert.Equal(t, 150.0, order.TotalFee)
+}
+
+func TestReturnedOrderHandovers(t *testing.T) {
+	order := &Order{OrderStatus: ReturnToOrigin, TransferStatus: WithRider}
+	assert.True(t, order.CanHandOverTo(AtHub))
+	assert.False(t, order.CanHandOverTo(WithRecipient), "a returned order isn't delivered")
+	assert.Equal(t, ReturnToOrigin, order.HandedOverStatus(AtHub))
+
+	order.TransferStatus = AtHub
+	assert.True(t, order.CanHandOverTo(AtMerchant))
+	assert.False(t, order.CanHandOverTo(WithRider))
+
+	assert.False(t, (&Order{OrderStatus: Cancelled, TransferStatus: AtMerchant}).CanHandOverTo(AtHub))
+	assert.True(t, (&Order{OrderStatus: Pending, TransferStatus: AtMerchant}).CanHandOverTo(AtHub))
+}
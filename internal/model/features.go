@@ -0,0 +1,15 @@
+package model
+
+// Names of the feature flags.
+const (
+	// FeatureSearchHighlights marks the matches in the order search results.
+	FeatureSearchHighlights = "search_highlights"
+)
+
+// Features are the feature flags by name.
+type Features map[string]bool
+
+// Enabled reports whether given feature is on.
+func (f Features) Enabled(name string) bool {
+	return f[name]
+}
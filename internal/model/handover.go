@@ -0,0 +1,237 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kaium123/order/internal/utils"
+	"github.com/uptrace/bun"
+)
+
+var (
+	// ErrInvalidHandover is returned for a handover not following the
+	// current transfer status of the order, or an order handed back by the
+	// rider before a failed delivery attempt.
+	ErrInvalidHandover = errors.New("invalid handover")
+	// ErrForbiddenHandover is returned for a handover the user may not
+	// record, such as a merchant handing over an order past its pickup.
+	ErrForbiddenHandover = errors.New("the merchants only record the pickup of their orders by the hub")
+)
+
+// TransferStatus is the party holding an order along its handovers, from the
+// merchant to the recipient.
+type TransferStatus int64
+
+const (
+	UnknownTransferStatus TransferStatus = iota // 0
+	AtMerchant                                  // 1, the new orders
+	AtHub                                       // 2
+	WithRider                                   // 3
+	WithRecipient                               // 4
+)
+
+// String provides a string representation of the TransferStatus enum.
+func (s TransferStatus) String() string {
+	switch s {
+	case AtMerchant:
+		return "At Merchant"
+	case AtHub:
+		return "At Hub"
+	case WithRider:
+		return "With Rider"
+	case WithRecipient:
+		return "With Recipient"
+	default:
+		return "Unknown"
+	}
+}
+
+// handovers maps the transfer statuses to those an order may be handed over
+// to from them. A rider failing to deliver brings the order back to the hub,
+// the delivered orders reach the recipient by their delivery attempt only.
+var handovers = map[TransferStatus][]TransferStatus{
+	AtMerchant: {AtHub},
+	AtHub:      {WithRider},
+	WithRider:  {AtHub},
+}
+
+// CanHandOverTo reports whether an order at s may be handed over to given
+// status.
+func (s TransferStatus) CanHandOverTo(to TransferStatus) bool {
+	for _, next := range handovers[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// returnHandovers maps the transfer statuses of the orders returned to the
+// merchant to those they may be handed over to, back through the hub.
+var returnHandovers = map[TransferStatus][]TransferStatus{
+	WithRider: {AtHub},
+	AtHub:     {AtMerchant},
+}
+
+// CanHandOverTo reports whether the order may be handed over to given
+// status. The cancelled orders stay at the merchant, the orders returned go
+// back to it.
+func (o *Order) CanHandOverTo(to TransferStatus) bool {
+	switch o.OrderStatus {
+	case Cancelled:
+		return false
+	case ReturnToOrigin:
+		return slices.Contains(returnHandovers[o.TransferStatus], to)
+	default:
+		return o.TransferStatus.CanHandOverTo(to)
+	}
+}
+
+// HandedOverStatus returns the status of the order once handed over to
+// given status, the orders returned keep theirs.
+func (o *Order) HandedOverStatus(to TransferStatus) OrderStatus {
+	if o.OrderStatus == ReturnToOrigin {
+		return ReturnToOrigin
+	}
+	return to.OrderStatus()
+}
+
+// OrderStatus returns the status of an order handed over to s: processed
+// until it reaches the recipient.
+func (s TransferStatus) OrderStatus() OrderStatus {
+	switch s {
+	case AtMerchant:
+		return Pending
+	case WithRecipient:
+		return Completed
+	default:
+		return Processing
+	}
+}
+
+// ParseTransferStatus returns the transfer status with given name, case,
+// spaces and underscores ignored, or number.
+func ParseTransferStatus(s string) (TransferStatus, bool) {
+	name := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(s))
+	for _, status := range []TransferStatus{AtMerchant, AtHub, WithRider, WithRecipient} {
+		if name == strings.ToLower(strings.ReplaceAll(status.String(), " ", "")) || s == strconv.Itoa(int(status)) {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+// handoverParties maps the receiving parties of the handovers to the
+// transfer status of the orders they receive, the merchant receives the
+// orders returned. The recipient receives the orders by their delivery
+// attempt.
+var handoverParties = map[string]TransferStatus{
+	"hub":      AtHub,
+	"rider":    WithRider,
+	"merchant": AtMerchant,
+}
+
+// OrderHandover records an order handed over to a party.
+type OrderHandover struct {
+	bun.BaseModel `bun:"table:order_handovers"`
+
+	ID      int64          `bun:"id,pk,autoincrement"`
+	OrderID int64          `bun:"order_id,notnull"`
+	From    TransferStatus `bun:"from_status,notnull"`
+	To      TransferStatus `bun:"to_status,notnull"`
+	// ReceivedBy is the name of the receiving party, ReceiverRef the ID of
+	// the hub or rider.
+	ReceivedBy  string    `bun:"received_by,notnull"`
+	ReceiverRef string    `bun:"receiver_ref,nullzero"`
+	Note        string    `bun:"note,nullzero"`
+	RecordedBy  int64     `bun:"recorded_by,notnull"`
+	CreatedAt   time.Time `bun:"created_at,default:current_timestamp,notnull"`
+}
+
+// HandoverRequest is the body of the handover of an order to a party.
+type HandoverRequest struct {
+	// UserId is the user recording the handover, OwnerId the user the order
+	// belongs to, 0 for the staff and the riders recording the handovers of
+	// any order.
+	UserId  int64 `json:"-"`
+	OwnerId int64 `json:"-"`
+	// PickupOnly restricts the handover to the pickup of the order at the
+	// merchant, for the merchants.
+	PickupOnly    bool   `json:"-"`
+	ConsignmentID string `json:"-"`
+	To            string `json:"to"`
+	ReceivedBy    string `json:"received_by"`
+	ReceiverRef   string `json:"receiver_ref"`
+	Note          string `json:"note"`
+}
+
+// Validate validates the handover and returns the transfer status of the
+// order once handed over.
+func (r *HandoverRequest) Validate() (TransferStatus, *utils.ResponseError) {
+	responseError := &utils.ResponseError{
+		Code:    "422",
+		Message: "Please fix the given errors",
+		Type:    "error",
+		Errors:  make(map[string][]string),
+	}
+
+	to, ok := handoverParties[strings.ToLower(r.To)]
+	if !ok {
+		responseError.AddValidationError("to", "The to must be hub, rider or merchant.")
+	}
+	r.ReceivedBy = strings.TrimSpace(r.ReceivedBy)
+	if r.ReceivedBy == "" {
+		responseError.AddValidationError("received_by", "The received by field is required.")
+	} else if len(r.ReceivedBy) > 100 {
+		responseError.AddValidationError("received_by", "The received by may not be greater than 100 characters.")
+	}
+	if len(r.ReceiverRef) > 50 {
+		responseError.AddValidationError("receiver_ref", "The receiver ref may not be greater than 50 characters.")
+	}
+	if (to == AtHub || to == WithRider) && r.ReceiverRef == "" {
+		responseError.AddValidationError("receiver_ref", "The receiver ref, the ID of the hub or rider, is required.")
+	}
+	if len(r.Note) > 500 {
+		responseError.AddValidationError("note", "The note may not be greater than 500 characters.")
+	}
+
+	if len(responseError.Errors) > 0 {
+		return 0, responseError
+	}
+	return to, nil
+}
+
+// FindHandoversRequest lists the handovers of an order, OwnerId as in
+// HandoverRequest.
+type FindHandoversRequest struct {
+	OwnerId       int64
+	ConsignmentID string
+}
+
+type HandoverResponse struct {
+	ID          int64     `json:"id"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	ReceivedBy  string    `json:"received_by" pii:"name"`
+	ReceiverRef string    `json:"receiver_ref,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	RecordedBy  int64     `json:"recorded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewHandoverResponse returns the response of given handover.
+func NewHandoverResponse(handover *OrderHandover) *HandoverResponse {
+	return &HandoverResponse{
+		ID:          handover.ID,
+		From:        handover.From.String(),
+		To:          handover.To.String(),
+		ReceivedBy:  handover.ReceivedBy,
+		ReceiverRef: handover.ReceiverRef,
+		Note:        handover.Note,
+		RecordedBy:  handover.RecordedBy,
+		CreatedAt:   handover.CreatedAt,
+	}
+}
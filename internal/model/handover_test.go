@@ -0,0 +1,57 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferStatusHandovers(t *testing.T) {
+	assert.True(t, AtMerchant.CanHandOverTo(AtHub))
+	assert.True(t, AtHub.CanHandOverTo(WithRider))
+	assert.False(t, WithRider.CanHandOverTo(WithRecipient), "delivered by attempt only")
+	assert.True(t, WithRider.CanHandOverTo(AtHub), "back to the hub")
+	assert.False(t, AtMerchant.CanHandOverTo(WithRider), "no stage is skipped")
+	assert.False(t, WithRecipient.CanHandOverTo(AtHub))
+	assert.False(t, UnknownTransferStatus.CanHandOverTo(AtHub))
+
+	assert.Equal(t, Processing, AtHub.OrderStatus())
+	assert.Equal(t, Completed, WithRecipient.OrderStatus())
+
+	for _, s := range []string{"At Hub", "at_hub", "AtHub", "2"} {
+		status, ok := ParseTransferStatus(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, AtHub, status, s)
+	}
+	_, ok := ParseTransferStatus("0")
+	assert.False(t, ok)
+
+	assert.True(t, RoleMerchant.CanHandOverTo(AtHub), "the pickup")
+	assert.False(t, RoleMerchant.CanHandOverTo(WithRider))
+	assert.True(t, RoleRider.CanHandOverTo(WithRider))
+	assert.True(t, RoleSupport.CanHandOverTo(AtMerchant))
+}
+
+func TestHandoverRequestValidate(t *testing.T) {
+	to, responseError := (&HandoverRequest{To: "Rider", ReceivedBy: " Rahim ", ReceiverRef: "R-12"}).Validate()
+	require.Nil(t, responseError)
+	assert.Equal(t, WithRider, to)
+
+	to, responseError = (&HandoverRequest{To: "merchant", ReceivedBy: "kaium"}).Validate()
+	require.Nil(t, responseError)
+	assert.Equal(t, AtMerchant, to)
+
+	_, responseError = (&HandoverRequest{To: "recipient", ReceivedBy: "kaium"}).Validate()
+	require.NotNil(t, responseError)
+	assert.Contains(t, responseError.Errors, "to")
+
+	_, responseError = (&HandoverRequest{To: "hub"}).Validate()
+	require.NotNil(t, responseError)
+	assert.Contains(t, responseError.Errors, "received_by")
+	assert.Contains(t, responseError.Errors, "receiver_ref")
+
+	_, responseError = (&HandoverRequest{To: "warehouse", ReceivedBy: "x"}).Validate()
+	require.NotNil(t, responseError)
+	assert.Contains(t, responseError.Errors, "to")
+}
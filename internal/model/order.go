@@ -1,6 +1,8 @@
 package model
 
 import (
+	"context"
+	"github.com/kaium123/order/internal/encryption"
 	"github.com/kaium123/order/internal/utils"
 	"github.com/uptrace/bun"
 	"math"
@@ -12,35 +14,44 @@ import (
 type Order struct {
 	bun.BaseModel `bun:"table:orders"`
 
-	ID                 int64       `json:"id" bun:"id,pk,autoincrement"`
-	StoreID            int64       `json:"store_id" bun:"store_id,notnull"`
-	MerchantOrderID    string      `json:"merchant_order_id,omitempty" bun:"merchant_order_id"`
-	RecipientName      string      `json:"recipient_name" bun:"recipient_name,notnull" `
-	RecipientPhone     string      `json:"recipient_phone" bun:"recipient_phone,notnull" `
-	RecipientAddress   string      `json:"recipient_address" bun:"recipient_address,notnull" `
-	RecipientCity      int64       `json:"recipient_city" bun:"recipient_city,notnull" `
-	RecipientZone      int64       `json:"recipient_zone" bun:"recipient_zone,notnull" `
-	RecipientArea      int64       `json:"recipient_area" bun:"recipient_area,notnull" `
-	DeliveryType       OrderType   `json:"delivery_type" bun:"delivery_type,notnull" `
-	ItemType           ItemType    `json:"item_type" bun:"item_type,notnull" `
-	SpecialInstruction string      `json:"special_instruction,omitempty" bun:"special_instruction"`
-	ItemQuantity       int         `json:"item_quantity" bun:"item_quantity,notnull" `
-	ItemWeight         float64     `json:"item_weight" bun:"item_weight,notnull" `
-	AmountToCollect    float64     `json:"amount_to_collect" bun:"amount_to_collect,notnull" `
-	ItemDescription    string      `json:"item_description,omitempty" bun:"item_description"`
-	OrderConsignmentID string      `json:"order_consignment_id" bun:"order_consignment_id,notnull"`
-	OrderTypeID        int         `json:"order_type_id" bun:"order_type_id"`
-	CodFee             float64     `json:"cod_fee" bun:"cod_fee"`
-	PromoDiscount      float64     `json:"promo_discount" bun:"promo_discount"`
-	Discount           float64     `json:"discount" bun:"discount"`
-	DeliveryFee        float64     `json:"delivery_fee" bun:"delivery_fee"`
-	OrderStatus        OrderStatus `json:"order_status" bun:"order_status,notnull"`
-	OrderType          OrderType   `json:"order_type" bun:"order_type"`
-	OrderAmount        float64     `json:"order_amount" bun:"order_amount"`
-	TotalFee           float64     `json:"total_fee" bun:"total_fee"`
-	UserID             int64       `json:"user_id" bun:"user_id"`
-	TransferStatus     int64       `json:"transfer_status" bun:"transfer_status"`
-	Archive            int64       `json:"archive" bun:"archive"`
+	ID                 int64             `json:"id" bun:"id,pk,autoincrement"`
+	StoreID            int64             `json:"store_id" bun:"store_id,notnull"`
+	MerchantOrderID    string            `json:"merchant_order_id,omitempty" bun:"merchant_order_id"`
+	RecipientName      string            `json:"recipient_name" bun:"recipient_name,notnull" pii:"name"`
+	RecipientPhone     encryption.String `json:"recipient_phone" bun:"recipient_phone,notnull" pii:"phone"`
+	RecipientPhoneHash string            `json:"-" bun:"recipient_phone_hash"` // blind index of RecipientPhone
+	RecipientAddress   encryption.String `json:"recipient_address" bun:"recipient_address,notnull" pii:"address"`
+	RecipientCity      int64             `json:"recipient_city" bun:"recipient_city,notnull" `
+	RecipientZone      int64             `json:"recipient_zone" bun:"recipient_zone,notnull" `
+	RecipientArea      int64             `json:"recipient_area" bun:"recipient_area,notnull" `
+	DeliveryType       OrderType         `json:"delivery_type" bun:"delivery_type,notnull" `
+	ItemType           ItemType          `json:"item_type" bun:"item_type,notnull" `
+	SpecialInstruction string            `json:"special_instruction,omitempty" bun:"special_instruction"`
+	ItemQuantity       int               `json:"item_quantity" bun:"item_quantity,notnull" `
+	ItemWeight         float64           `json:"item_weight" bun:"item_weight,notnull" `
+	AmountToCollect    float64           `json:"amount_to_collect" bun:"amount_to_collect,notnull" `
+	ItemDescription    string            `json:"item_description,omitempty" bun:"item_description"`
+	OrderConsignmentID string            `json:"order_consignment_id" bun:"order_consignment_id,notnull"`
+	OrderTypeID        int               `json:"order_type_id" bun:"order_type_id"`
+	CodFee             float64           `json:"cod_fee" bun:"cod_fee"`
+	PromoDiscount      float64           `json:"promo_discount" bun:"promo_discount"`
+	Discount           float64           `json:"discount" bun:"discount"`
+	DeliveryFee        float64           `json:"delivery_fee" bun:"delivery_fee"`
+	RTOFee             float64           `json:"rto_fee" bun:"rto_fee"`
+	OrderStatus        OrderStatus       `json:"order_status" bun:"order_status,notnull"`
+	OrderType          OrderType         `json:"order_type" bun:"order_type"`
+	OrderAmount        float64           `json:"order_amount" bun:"order_amount"`
+	TotalFee           float64           `json:"total_fee" bun:"total_fee"`
+	UserID             int64             `json:"user_id" bun:"user_id"`
+	TransferStatus     TransferStatus    `json:"transfer_status" bun:"transfer_status"`
+	Archive            int64             `json:"archive" bun:"archive"`
+	ArchivedAt         time.Time         `json:"archived_at,omitempty" bun:"archived_at,nullzero"`
+	UnarchivedAt       time.Time         `json:"-" bun:"unarchived_at,nullzero"` // kept out of the automatic archiving once set
+	CancelledAt        time.Time         `json:"cancelled_at,omitempty" bun:"cancelled_at,nullzero"`
+	CancelReason       string            `json:"cancel_reason,omitempty" bun:"cancel_reason,nullzero"`
+	CancelNote         string            `json:"cancel_note,omitempty" bun:"cancel_note,nullzero"`
+	// Version is bumped by every edition of the order, see UpdateOrderRequest.
+	Version int `json:"version" bun:"version,nullzero,notnull,default:1"`
 
 	// Timestamps
 	CreatedAt time.Time `json:"created_at" bun:"created_at,default:current_timestamp,notnull"`                             // Created timestamp
@@ -48,6 +59,18 @@ type Order struct {
 	DeletedAt time.Time `json:"deleted_at" bun:"deleted_at,soft_delete,nullzero"`                                          // Soft delete timestamp
 }
 
+var _ bun.BeforeAppendModelHook = (*Order)(nil)
+
+// BeforeAppendModel keeps the blind index of the recipient phone in sync
+// before the order is written.
+func (o *Order) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery, *bun.UpdateQuery:
+		o.RecipientPhoneHash = encryption.BlindIndex(string(o.RecipientPhone))
+	}
+	return nil
+}
+
 func (o *Order) CalculateDeliveryFee(baseDeliveryFee float64) {
 	if o.ItemWeight <= 0.5 {
 		o.DeliveryFee = baseDeliveryFee
@@ -59,12 +82,27 @@ func (o *Order) CalculateDeliveryFee(baseDeliveryFee float64) {
 	}
 }
 
-func (o *Order) CalculateCodFee() {
-	o.CodFee = utils.CalculatePercentage(o.AmountToCollect, 1)
+func (o *Order) CalculateCodFee(percentage float64) {
+	o.CodFee = utils.CalculatePercentage(o.AmountToCollect, percentage)
 }
 
 func (o *Order) CalculateTotalFee() {
-	o.TotalFee = o.CodFee + o.DeliveryFee
+	o.TotalFee = o.CodFee + o.DeliveryFee + o.RTOFee
+}
+
+// ApplyPricing calculates the delivery, COD and total fees of the order.
+func (o *Order) ApplyPricing(pricing Pricing) {
+	o.CalculateDeliveryFee(pricing.BaseDeliveryFee(o.RecipientCity))
+	o.CalculateCodFee(pricing.CODPercentage)
+	o.CalculateTotalFee()
+}
+
+// ApplyRTO charges the return of the order to the merchant. Nothing is
+// collected from the recipient, the COD fee is waived.
+func (o *Order) ApplyRTO(pricing Pricing) {
+	o.RTOFee = utils.CalculatePercentage(o.DeliveryFee, pricing.RTOPercentage)
+	o.CodFee = 0
+	o.CalculateTotalFee()
 }
 
 // Validate validates the Order fields and returns errors in the required format.
@@ -89,7 +127,7 @@ func (o *Order) Validate() *utils.ResponseError {
 	}
 	if o.RecipientPhone == "" {
 		responseError.Errors["recipient_phone"] = append(responseError.Errors["recipient_phone"], "The recipient phone field is required.")
-	} else if !phoneRegex.MatchString(o.RecipientPhone) {
+	} else if !phoneRegex.MatchString(string(o.RecipientPhone)) {
 		responseError.Errors["recipient_phone"] = append(responseError.Errors["recipient_phone"], "The recipient phone number is invalid.")
 	}
 	if o.RecipientAddress == "" {
@@ -133,14 +171,42 @@ type FindRequest struct {
 type OrderCancelRequest struct {
 	UserId        int64 `param:"user_id" validate:"required"`
 	ConsignmentID string
+	// Reason is a code of Cancellation.Reasons, Note details it.
+	Reason string `json:"reason"`
+	Note   string `json:"note"`
 }
 
+// FindAllRequest is the request parameter for listing the orders of a user,
+// see ParseFindAllRequest. The zero values don't filter.
 type FindAllRequest struct {
 	UserId         int64 `param:"user_id" validate:"required"`
-	TransferStatus string
-	Archive        int
-	Limit          int
-	Offset         int
+	TransferStatus *TransferStatus
+	Archive        *int64
+	OrderStatuses  []OrderStatus
+	StoreID        int64
+	RecipientCity  int64
+	RecipientZone  int64
+	RecipientArea  int64
+	CreatedFrom    time.Time
+	CreatedBefore  time.Time
+	CODMin         *float64
+	CODMax         *float64
+	// Phone is a prefix of the recipient phone, or the full phone when
+	// they are encrypted.
+	Phone string
+	// ConsignmentID is a prefix of the consignment ID.
+	ConsignmentID string
+	// Sort is a key of OrderSortColumns, Order is asc or desc.
+	Sort   string
+	Order  string
+	Limit  int
+	Offset int
+	// Keyset paginates by (created_at, id) from Cursor, the first page when
+	// nil, instead of Offset.
+	Keyset bool
+	Cursor *OrderCursor
+	// Count is CountExact, CountEstimate or CountNone.
+	Count string
 }
 
 type CreateOrderResponse struct {
@@ -151,12 +217,17 @@ type CreateOrderResponse struct {
 }
 
 type FindAllResponse struct {
-	Orders      []*OrderResponse `json:"orders"`
-	Total       int              `json:"total"`
-	CurrentPage int              `json:"current_page"`
-	PerPage     int              `json:"per_page"`
-	TotalInPage int              `json:"total_in_page"`
-	LastPage    int              `json:"last_page"`
+	Orders []*OrderResponse `json:"orders"`
+	// Total and LastPage are left out when not counted, CurrentPage and
+	// LastPage with the cursor pagination.
+	Total          *int   `json:"total,omitempty"`
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
+	CurrentPage    *int   `json:"current_page,omitempty"`
+	PerPage        int    `json:"per_page"`
+	TotalInPage    int    `json:"total_in_page"`
+	LastPage       *int   `json:"last_page,omitempty"`
+	NextCursor     string `json:"next_cursor,omitempty"`
+	PrevCursor     string `json:"prev_cursor,omitempty"`
 }
 
 // Order represents the structure of the order response.
@@ -165,9 +236,9 @@ type OrderResponse struct {
 	OrderCreatedAt     time.Time `json:"order_created_at"`
 	OrderDescription   string    `json:"order_description"`
 	MerchantOrderID    string    `json:"merchant_order_id"`
-	RecipientName      string    `json:"recipient_name"`
-	RecipientAddress   string    `json:"recipient_address"`
-	RecipientPhone     string    `json:"recipient_phone"`
+	RecipientName      string    `json:"recipient_name" pii:"name"`
+	RecipientAddress   string    `json:"recipient_address" pii:"address"`
+	RecipientPhone     string    `json:"recipient_phone" pii:"phone"`
 	OrderAmount        float64   `json:"order_amount"`
 	TotalFee           float64   `json:"total_fee"`
 	Instruction        string    `json:"instruction"`
@@ -177,6 +248,8 @@ type OrderResponse struct {
 	Discount           float64   `json:"discount"`
 	DeliveryFee        float64   `json:"delivery_fee"`
 	OrderStatus        string    `json:"order_status"`
+	TransferStatus     string    `json:"transfer_status"`
+	Version            int       `json:"version"`
 	OrderType          string    `json:"order_type"`
 	ItemType           string    `json:"item_type"`
 }
@@ -184,9 +257,11 @@ type OrderResponse struct {
 type OrderStatus int
 
 const (
-	Pending    OrderStatus = iota // 0
-	Processing                    // 1
-	Completed                     // 2
+	Pending        OrderStatus = iota // 0
+	Processing                        // 1
+	Completed                         // 2
+	Cancelled                         // 3
+	ReturnToOrigin                    // 4, after too many failed delivery attempts
 )
 
 // String provides a string representation of the OrderStatus enum.
@@ -198,6 +273,10 @@ func (s OrderStatus) String() string {
 		return "Processing"
 	case Completed:
 		return "Completed"
+	case Cancelled:
+		return "Cancelled"
+	case ReturnToOrigin:
+		return "Return"
 	default:
 		return "Unknown"
 	}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/kaium123/order/internal/utils"
+)
+
+// Values of the archive flag of the orders.
+const (
+	NotArchived int64 = 0
+	Archived    int64 = 1
+)
+
+// MaxBulkArchiveIDs is the maximum number of consignment IDs of a bulk
+// archiving, a filter matches any number.
+const MaxBulkArchiveIDs = 500
+
+// pageKeys are the parameters of the order listing not filtering the orders.
+var pageKeys = map[string]bool{
+	"page": true, "limit": true, "sort": true, "order": true, "pagination": true, "cursor": true, "count": true,
+}
+
+// filterKeys are the parameters of the order listing filtering the orders.
+var filterKeys = map[string]bool{
+	"transfer_status": true, "order_status": true, "archive": true, "store_id": true,
+	"recipient_city": true, "recipient_zone": true, "recipient_area": true,
+	"created_from": true, "created_to": true, "cod_min": true, "cod_max": true,
+	"phone": true, "consignment_id": true,
+}
+
+// ArchiveRequest archives, or unarchives, the orders of a user having given
+// consignment IDs, or else matching given filter.
+type ArchiveRequest struct {
+	UserId         int64
+	Archive        bool
+	ConsignmentIDs []string
+	Filter         *FindAllRequest
+}
+
+// BulkArchiveRequest is the body of the bulk archiving. Filter takes the
+// filters of the order listing, one at least besides the archive.
+type BulkArchiveRequest struct {
+	ConsignmentIDs []string          `json:"consignment_ids"`
+	Filter         map[string]string `json:"filter"`
+}
+
+type ArchiveResponse struct {
+	// Updated is the number of orders matched, archived or unarchived
+	// already included.
+	Updated int `json:"updated"`
+}
+
+// Validate returns the ArchiveRequest of given user, the filter of the
+// unarchived orders matching the archived ones unless narrowed by archive.
+func (r *BulkArchiveRequest) Validate(userID int64, archive bool) (*ArchiveRequest, *utils.ResponseError) {
+	responseError := &utils.ResponseError{
+		Code:    "422",
+		Message: "Please fix the given errors",
+		Type:    "error",
+		Errors:  make(map[string][]string),
+	}
+	req := &ArchiveRequest{UserId: userID, Archive: archive}
+
+	switch {
+	case len(r.ConsignmentIDs) > 0 && len(r.Filter) > 0:
+		responseError.AddValidationError("filter", "Give either the consignment_ids or a filter.")
+	case len(r.ConsignmentIDs) > MaxBulkArchiveIDs:
+		responseError.AddValidationError("consignment_ids", "The consignment_ids may not have more than 500 IDs.")
+	case len(r.ConsignmentIDs) > 0:
+		for _, id := range r.ConsignmentIDs {
+			if strings.TrimSpace(id) == "" {
+				responseError.AddValidationError("consignment_ids", "The consignment_ids must not be empty.")
+				break
+			}
+		}
+		req.ConsignmentIDs = r.ConsignmentIDs
+	case len(r.Filter) > 0:
+		query := make(url.Values, len(r.Filter)+1)
+		narrowed := false
+		for key, value := range r.Filter {
+			switch {
+			case pageKeys[key]:
+				responseError.AddValidationError("filter."+key, "The "+key+" isn't a filter.")
+			case !filterKeys[key]:
+				responseError.AddValidationError("filter."+key, "Unknown filter "+key+".")
+			case key != "archive" && strings.TrimSpace(value) != "":
+				narrowed = true
+			}
+			query.Set(key, value)
+		}
+		// a filter matching every order of the user is most likely a mistake
+		if !narrowed && len(responseError.Errors) == 0 {
+			responseError.AddValidationError("filter", "The filter must narrow the orders down, give a filter other than the archive.")
+		}
+		if query.Get("archive") == "" {
+			query.Set("archive", "all")
+		}
+		filter, filterErr := ParseFindAllRequest(query)
+		if filterErr != nil {
+			for key, messages := range filterErr.Errors {
+				responseError.Errors["filter."+key] = messages
+			}
+		}
+		req.Filter = filter
+	default:
+		responseError.AddValidationError("consignment_ids", "Give the consignment_ids or a filter.")
+	}
+
+	if len(responseError.Errors) > 0 {
+		return nil, responseError
+	}
+	return req, nil
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkArchiveRequestValidate(t *testing.T) {
+	req, responseError := (&BulkArchiveRequest{ConsignmentIDs: []string{"DA1", "DA2"}}).Validate(7, true)
+	require.Nil(t, responseError)
+	assert.Equal(t, &ArchiveRequest{UserId: 7, Archive: true, ConsignmentIDs: []string{"DA1", "DA2"}}, req)
+
+	// the filter matches the archived and unarchived orders unless narrowed
+	req, responseError = (&BulkArchiveRequest{Filter: map[string]string{"order_status": "Completed", "created_to": "2024-11-01"}}).Validate(7, false)
+	require.Nil(t, responseError)
+	assert.Nil(t, req.Filter.Archive)
+	assert.Equal(t, []OrderStatus{Completed}, req.Filter.OrderStatuses)
+
+	for _, bulk := range []*BulkArchiveRequest{
+		{},
+		{ConsignmentIDs: []string{"DA1"}, Filter: map[string]string{"store_id": "1"}},
+		{ConsignmentIDs: []string{""}},
+		{Filter: map[string]string{"limit": "10"}},
+		{Filter: map[string]string{"order_status": "lost"}},
+		{Filter: map[string]string{"archive": "all"}},
+		{Filter: map[string]string{"store_id": ""}},
+	} {
+		_, responseError = bulk.Validate(7, true)
+		assert.NotNil(t, responseError, "%+v", bulk)
+	}
+
+	// a typo isn't ignored, it would archive every order
+	_, responseError = (&BulkArchiveRequest{Filter: map[string]string{"stauts": "Delivered"}}).Validate(7, true)
+	require.NotNil(t, responseError)
+	assert.Contains(t, responseError.Errors, "filter.stauts")
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/kaium123/order/internal/utils"
+)
+
+var (
+	// ErrAlreadyCancelled is returned for the cancellation of an order
+	// cancelled already.
+	ErrAlreadyCancelled = errors.New("the order is cancelled already")
+	// ErrNotCancellable is returned for the cancellation of an order picked
+	// up already.
+	ErrNotCancellable = errors.New("the order can only be cancelled while pending at the merchant")
+)
+
+// Cancellation holds the rules of the order cancellation.
+type Cancellation struct {
+	// Reasons are the codes of the reasons an order may be cancelled for.
+	Reasons []string `json:"reasons" yaml:"reasons" toml:"reasons" mapstructure:"reasons"`
+}
+
+// Validate validates the cancellation against given rules.
+func (r *OrderCancelRequest) Validate(rules Cancellation) *utils.ResponseError {
+	responseError := &utils.ResponseError{
+		Code:    "422",
+		Message: "Please fix the given errors",
+		Type:    "error",
+		Errors:  make(map[string][]string),
+	}
+
+	r.Reason = strings.ToLower(strings.TrimSpace(r.Reason))
+	if r.Reason == "" {
+		responseError.AddValidationError("reason", "The reason field is required.")
+	} else if !slices.Contains(rules.Reasons, r.Reason) {
+		responseError.AddValidationError("reason", "The reason must be one of "+strings.Join(rules.Reasons, ", ")+".")
+	}
+	if len(r.Note) > 500 {
+		responseError.AddValidationError("note", "The note may not be greater than 500 characters.")
+	}
+
+	if len(responseError.Errors) > 0 {
+		return responseError
+	}
+	return nil
+}
+
+// Cancellable reports whether the order may still be cancelled, it's pending
+// and hasn't left the merchant.
+func (o *Order) Cancellable() bool {
+	return o.OrderStatus == Pending && o.TransferStatus == AtMerchant
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderCancelRequestValidate(t *testing.T) {
+	rules := Cancellation{Reasons: []string{"customer_request", "other"}}
+
+	req := &OrderCancelRequest{Reason: " Customer_Request "}
+	require.Nil(t, req.Validate(rules))
+	assert.Equal(t, "customer_request", req.Reason)
+
+	responseError := (&OrderCancelRequest{}).Validate(rules)
+	require.NotNil(t, responseError)
+	assert.Contains(t, responseError.Errors, "reason")
+
+	responseError = (&OrderCancelRequest{Reason: "changed_mind"}).Validate(rules)
+	require.NotNil(t, responseError)
+	assert.Equal(t, []string{"The reason must be one of customer_request, other."}, responseError.Errors["reason"])
+}
+
+func TestOrderCancellable(t *testing.T) {
+	assert.True(t, (&Order{OrderStatus: Pending, TransferStatus: AtMerchant}).Cancellable())
+	assert.False(t, (&Order{OrderStatus: Processing, TransferStatus: AtHub}).Cancellable())
+	assert.False(t, (&Order{OrderStatus: Cancelled, TransferStatus: AtMerchant}).Cancellable())
+
+	status, ok := ParseOrderStatus("cancelled")
+	assert.True(t, ok)
+	assert.Equal(t, Cancelled, status)
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor not issued by the order listing.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// The ways of counting the orders of a listing.
+const (
+	// CountExact counts every matching order, the default of the paged
+	// listings.
+	CountExact = "exact"
+	// CountEstimate takes the planner estimate, out of the table statistics,
+	// for tenants too large to count on every request.
+	CountEstimate = "estimate"
+	// CountNone skips the count, the default of the cursor listings.
+	CountNone = "none"
+)
+
+// OrderCursor is the position of a keyset paginated order listing, the
+// (created_at, id) of the order it starts after, or before.
+type OrderCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"i"`
+	// Before is set on the previous page cursors.
+	Before bool `json:"b,omitempty"`
+	// Order is the sort direction the cursor was issued for.
+	Order string `json:"o"`
+}
+
+// NewOrderCursor returns the cursor of the listing after, or before, given
+// order.
+func NewOrderCursor(order *Order, before bool, direction string) *OrderCursor {
+	return &OrderCursor{CreatedAt: order.CreatedAt, ID: order.ID, Before: before, Order: direction}
+}
+
+// Encode returns the opaque form of c given to the clients.
+func (c *OrderCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeOrderCursor parses a cursor returned by Encode.
+func DecodeOrderCursor(s string) (*OrderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c OrderCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 || c.CreatedAt.IsZero() || (c.Order != "asc" && c.Order != "desc") {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -0,0 +1,273 @@
+package model
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kaium123/order/internal/encryption"
+	"github.com/kaium123/order/internal/utils"
+)
+
+// Pagination of the order listing.
+const (
+	DefaultOrderLimit = 10
+	MaxOrderLimit     = 100
+)
+
+// OrderSortColumns maps the sort parameter of the order listing to the
+// columns.
+var OrderSortColumns = map[string]string{
+	"created_at":        "created_at",
+	"updated_at":        "updated_at",
+	"amount_to_collect": "amount_to_collect",
+	"delivery_fee":      "delivery_fee",
+	"total_fee":         "total_fee",
+	"consignment_id":    "order_consignment_id",
+}
+
+var (
+	phonePrefixRegex   = regexp.MustCompile(`^[0-9]{3,11}$`)
+	consignmentIDRegex = regexp.MustCompile(`^[A-Za-z0-9]{2,50}$`)
+)
+
+// ParseOrderStatus returns the status with given name, case insensitive, or
+// number.
+func ParseOrderStatus(s string) (OrderStatus, bool) {
+	for _, status := range []OrderStatus{Pending, Processing, Completed, Cancelled, ReturnToOrigin} {
+		if strings.EqualFold(s, status.String()) || s == strconv.Itoa(int(status)) {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+// ParseFindAllRequest reads the filters, the sort and the page of the order
+// listing from given query parameters. The errors are keyed by parameter.
+func ParseFindAllRequest(query url.Values) (*FindAllRequest, *utils.ResponseError) {
+	responseError := &utils.ResponseError{
+		Code:    "400",
+		Message: "Please provide a valid request",
+		Type:    "error",
+		Errors:  make(map[string][]string),
+	}
+	req := &FindAllRequest{Limit: DefaultOrderLimit, Sort: "created_at", Order: "desc"}
+
+	parseInt := func(key string, min int64) (int64, bool) {
+		value := query.Get(key)
+		if value == "" {
+			return 0, false
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < min {
+			responseError.AddValidationError(key, "The "+key+" must be an integer of at least "+strconv.FormatInt(min, 10)+".")
+			return 0, false
+		}
+		return n, true
+	}
+	parseAmount := func(key string) *float64 {
+		value := query.Get(key)
+		if value == "" {
+			return nil
+		}
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil || amount < 0 {
+			responseError.AddValidationError(key, "The "+key+" must be a non negative amount.")
+			return nil
+		}
+		return &amount
+	}
+
+	page := int64(1)
+	if n, ok := parseInt("page", 1); ok {
+		page = n
+	}
+	if n, ok := parseInt("limit", 1); ok {
+		if n > MaxOrderLimit {
+			responseError.AddValidationError("limit", "The limit may not be greater than "+strconv.Itoa(MaxOrderLimit)+".")
+		}
+		req.Limit = int(n)
+	}
+	req.Offset = pageOffset(responseError, page, req.Limit)
+
+	if value := query.Get("transfer_status"); value != "" {
+		if status, ok := ParseTransferStatus(value); ok {
+			req.TransferStatus = &status
+		} else {
+			responseError.AddValidationError("transfer_status", "Unknown transfer status "+value+".")
+		}
+	}
+	// the archived orders are left out unless asked for
+	switch query.Get("archive") {
+	case "", "0":
+		notArchived := NotArchived
+		req.Archive = &notArchived
+	case "1":
+		archived := Archived
+		req.Archive = &archived
+	case "all":
+	default:
+		responseError.AddValidationError("archive", "The archive must be 0, 1 or all.")
+	}
+	req.StoreID, _ = parseInt("store_id", 1)
+	req.RecipientCity, _ = parseInt("recipient_city", 1)
+	req.RecipientZone, _ = parseInt("recipient_zone", 1)
+	req.RecipientArea, _ = parseInt("recipient_area", 1)
+
+	// repeated or comma separated
+	for _, values := range query["order_status"] {
+		for _, value := range strings.Split(values, ",") {
+			status, ok := ParseOrderStatus(strings.TrimSpace(value))
+			if !ok {
+				responseError.AddValidationError("order_status", "Unknown order status "+value+".")
+				continue
+			}
+			req.OrderStatuses = append(req.OrderStatuses, status)
+		}
+	}
+
+	var createdTo time.Time
+	req.CreatedFrom, _ = parseDate(responseError, query, "created_from")
+	if to, dateOnly := parseDate(responseError, query, "created_to"); dateOnly {
+		createdTo = to
+		req.CreatedBefore = to.AddDate(0, 0, 1) // the whole day
+	} else if !to.IsZero() {
+		createdTo = to
+		req.CreatedBefore = to.Add(time.Microsecond) // the precision of the timestamps
+	}
+	if !req.CreatedFrom.IsZero() && !createdTo.IsZero() && createdTo.Before(req.CreatedFrom) {
+		responseError.AddValidationError("created_to", "The created_to must not be before created_from.")
+	}
+
+	req.CODMin = parseAmount("cod_min")
+	req.CODMax = parseAmount("cod_max")
+	if req.CODMin != nil && req.CODMax != nil && *req.CODMax < *req.CODMin {
+		responseError.AddValidationError("cod_max", "The cod_max must not be less than cod_min.")
+	}
+
+	if phone := query.Get("phone"); phone != "" {
+		switch {
+		case !phonePrefixRegex.MatchString(phone):
+			responseError.AddValidationError("phone", "The phone must be 3 to 11 digits.")
+		case encryption.Default().Enabled() && len(phone) != 11:
+			responseError.AddValidationError("phone", "The full phone number is required, the phones are encrypted.")
+		default:
+			req.Phone = phone
+		}
+	}
+	if consignmentID := query.Get("consignment_id"); consignmentID != "" {
+		if !consignmentIDRegex.MatchString(consignmentID) {
+			responseError.AddValidationError("consignment_id", "The consignment_id must be 2 to 50 letters or digits.")
+		} else {
+			req.ConsignmentID = strings.ToUpper(consignmentID)
+		}
+	}
+
+	if sortKey := query.Get("sort"); sortKey != "" {
+		if _, ok := OrderSortColumns[sortKey]; !ok {
+			responseError.AddValidationError("sort", "The sort must be one of "+strings.Join(sortNames(), ", ")+".")
+		}
+		req.Sort = sortKey
+	}
+	if order := strings.ToLower(query.Get("order")); order != "" {
+		if order != "asc" && order != "desc" {
+			responseError.AddValidationError("order", "The order must be asc or desc.")
+		}
+		req.Order = order
+	}
+
+	parsePagination(responseError, query, req)
+
+	if len(responseError.Errors) > 0 {
+		return nil, responseError
+	}
+	return req, nil
+}
+
+// pageOffset returns the offset of given page of limit orders. The pages
+// past the largest offset Postgres takes are rejected, rather than
+// overflowing.
+func pageOffset(responseError *utils.ResponseError, page int64, limit int) int {
+	if page-1 > math.MaxInt32/int64(limit) {
+		responseError.AddValidationError("page", "The page is too large, the offset may not be greater than "+strconv.Itoa(math.MaxInt32)+".")
+		return 0
+	}
+	return int(page-1) * limit
+}
+
+// parsePagination reads the pagination mode and the count of the listing.
+// The cursor pagination is opted in with pagination=cursor, or a cursor, and
+// sorts by created_at in the direction of the cursor.
+func parsePagination(responseError *utils.ResponseError, query url.Values, req *FindAllRequest) {
+	switch query.Get("pagination") {
+	case "", "offset":
+	case "cursor":
+		req.Keyset = true
+	default:
+		responseError.AddValidationError("pagination", "The pagination must be offset or cursor.")
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		c, err := DecodeOrderCursor(cursor)
+		if err != nil {
+			responseError.AddValidationError("cursor", "The cursor is invalid, start again from the first page.")
+		} else {
+			if query.Get("order") != "" && c.Order != req.Order {
+				responseError.AddValidationError("order", "The order must be the one of the cursor.")
+			}
+			req.Cursor = c
+			req.Order = c.Order
+		}
+		req.Keyset = true
+	}
+
+	req.Count = CountExact
+	if req.Keyset {
+		if query.Get("page") != "" {
+			responseError.AddValidationError("page", "The page can't be combined with the cursor pagination.")
+		}
+		if req.Sort != "created_at" {
+			responseError.AddValidationError("sort", "The cursor pagination sorts by created_at only.")
+		}
+		req.Offset = 0
+		req.Count = CountNone
+	}
+	switch count := query.Get("count"); count {
+	case "":
+	case CountExact, CountEstimate, CountNone:
+		req.Count = count
+	default:
+		responseError.AddValidationError("count", "The count must be exact, estimate or none.")
+	}
+}
+
+// parseDate parses the date, in UTC, or RFC 3339 time of given parameter.
+// dateOnly reports whether a valid date without time was given.
+func parseDate(responseError *utils.ResponseError, query url.Values, key string) (t time.Time, dateOnly bool) {
+	value := query.Get(key)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		responseError.AddValidationError(key, "The "+key+" must be a date (2006-01-02) or an RFC 3339 time.")
+		return time.Time{}, false
+	}
+	return t.UTC(), false
+}
+
+// sortNames returns the sort parameters in alphabetical order.
+func sortNames() []string {
+	names := make([]string, 0, len(OrderSortColumns))
+	for name := range OrderSortColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
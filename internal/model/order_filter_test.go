@@ -0,0 +1,109 @@
+package model
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFindAllRequest(t *testing.T) {
+	req, responseError := ParseFindAllRequest(url.Values{})
+	require.Nil(t, responseError)
+	notArchived := NotArchived
+	assert.Equal(t, &FindAllRequest{Archive: &notArchived, Limit: DefaultOrderLimit, Sort: "created_at", Order: "desc", Count: CountExact}, req)
+
+	query, err := url.ParseQuery("page=3&limit=20&transfer_status=at_hub&order_status=pending,2&order_status=Processing" +
+		"&store_id=4&recipient_city=1&created_from=2024-11-01&created_to=2024-11-17&cod_min=10&cod_max=500.5" +
+		"&phone=0187&consignment_id=da2411&sort=amount_to_collect&order=ASC")
+	require.NoError(t, err)
+	req, responseError = ParseFindAllRequest(query)
+	require.Nil(t, responseError)
+	assert.Equal(t, 20, req.Limit)
+	assert.Equal(t, 40, req.Offset)
+	require.NotNil(t, req.TransferStatus)
+	assert.Equal(t, AtHub, *req.TransferStatus)
+	assert.Equal(t, NotArchived, *req.Archive, "the archived orders are left out by default")
+	assert.Equal(t, []OrderStatus{Pending, Completed, Processing}, req.OrderStatuses)
+	assert.Equal(t, int64(4), req.StoreID)
+	assert.Equal(t, int64(1), req.RecipientCity)
+	assert.Equal(t, time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), req.CreatedFrom)
+	assert.Equal(t, time.Date(2024, 11, 18, 0, 0, 0, 0, time.UTC), req.CreatedBefore)
+	assert.Equal(t, 10.0, *req.CODMin)
+	assert.Equal(t, 500.5, *req.CODMax)
+	assert.Equal(t, "0187", req.Phone)
+	assert.Equal(t, "DA2411", req.ConsignmentID)
+	assert.Equal(t, "amount_to_collect", req.Sort)
+	assert.Equal(t, "asc", req.Order)
+
+	query, err = url.ParseQuery("page=x&limit=0&transfer_status=lost&archive=-1&order_status=lost&created_from=2024-11-17" +
+		"&created_to=2024-11-01&cod_min=5&cod_max=1&phone=01a&consignment_id=DA-1&sort=name&order=up")
+	require.NoError(t, err)
+	req, responseError = ParseFindAllRequest(query)
+	assert.Nil(t, req)
+	require.NotNil(t, responseError)
+	for _, key := range []string{"page", "limit", "transfer_status", "archive", "order_status", "created_to", "cod_max", "phone", "consignment_id", "sort", "order"} {
+		assert.Contains(t, responseError.Errors, key)
+	}
+
+	req, responseError = ParseFindAllRequest(url.Values{"archive": {"all"}})
+	require.Nil(t, responseError)
+	assert.Nil(t, req.Archive)
+
+	req, responseError = ParseFindAllRequest(url.Values{"archive": {"1"}})
+	require.Nil(t, responseError)
+	assert.Equal(t, Archived, *req.Archive)
+
+	for _, archive := range []string{"2", "01", "yes"} {
+		_, responseError = ParseFindAllRequest(url.Values{"archive": {archive}})
+		require.NotNil(t, responseError, archive)
+		assert.Contains(t, responseError.Errors, "archive", archive)
+	}
+
+	_, responseError = ParseFindAllRequest(url.Values{"limit": {"101"}})
+	require.NotNil(t, responseError)
+	assert.Contains(t, responseError.Errors, "limit")
+}
+
+func TestParseFindAllRequestPageOverflow(t *testing.T) {
+	req, responseError := ParseFindAllRequest(url.Values{"page": {"21474837"}, "limit": {"100"}})
+	require.Nil(t, responseError)
+	assert.Equal(t, 2147483600, req.Offset)
+
+	for _, page := range []string{"21474838", "9223372036854775807"} {
+		_, responseError = ParseFindAllRequest(url.Values{"page": {page}, "limit": {"100"}})
+		require.NotNil(t, responseError, page)
+		assert.Contains(t, responseError.Errors, "page", page)
+	}
+}
+
+func TestParseFindAllRequestCursor(t *testing.T) {
+	req, responseError := ParseFindAllRequest(url.Values{"pagination": {"cursor"}, "order": {"asc"}})
+	require.Nil(t, responseError)
+	assert.True(t, req.Keyset)
+	assert.Nil(t, req.Cursor)
+	assert.Equal(t, CountNone, req.Count)
+
+	cursor := NewOrderCursor(&Order{ID: 7, CreatedAt: time.Date(2024, 11, 17, 17, 34, 7, 32741000, time.UTC)}, true, "asc")
+	req, responseError = ParseFindAllRequest(url.Values{"cursor": {cursor.Encode()}, "limit": {"5"}, "count": {"estimate"}})
+	require.Nil(t, responseError)
+	assert.True(t, req.Keyset)
+	assert.Equal(t, cursor, req.Cursor)
+	assert.Equal(t, "asc", req.Order, "the order of the cursor")
+	assert.Equal(t, CountEstimate, req.Count)
+	assert.Equal(t, 0, req.Offset)
+
+	_, responseError = ParseFindAllRequest(url.Values{"cursor": {cursor.Encode()}, "order": {"desc"}, "page": {"2"}, "sort": {"total_fee"}})
+	require.NotNil(t, responseError)
+	for _, key := range []string{"order", "page", "sort"} {
+		assert.Contains(t, responseError.Errors, key)
+	}
+
+	_, responseError = ParseFindAllRequest(url.Values{"cursor": {"eyJpIjoxfQ"}, "pagination": {"pages"}, "count": {"some"}})
+	require.NotNil(t, responseError)
+	for _, key := range []string{"cursor", "pagination", "count"} {
+		assert.Contains(t, responseError.Errors, key)
+	}
+}
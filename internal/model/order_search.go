@@ -0,0 +1,220 @@
+package model
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kaium123/order/internal/utils"
+)
+
+// Bounds of the order search.
+const (
+	MinSearchLength    = 2
+	MaxSearchLength    = 100
+	DefaultSearchLimit = 20
+)
+
+// Highlight marks of the matches in the search highlights.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// fuzzyMatchThreshold is the trigram similarity from which a word is
+// highlighted as a fuzzy match of a searched word.
+const fuzzyMatchThreshold = 0.4
+
+// SearchOrdersRequest is a search of the orders by the recipient name and
+// address, the merchant order ID, the description and the consignment ID.
+type SearchOrdersRequest struct {
+	Query string
+	// UserId is the user whose orders are searched, 0 for the orders of every
+	// user, which only the staff may search.
+	UserId  int64
+	StoreID int64
+	Limit   int
+	Offset  int
+}
+
+// OrderSearchRow is an order found by a search, with its relevance.
+type OrderSearchRow struct {
+	Order `bun:",extend"`
+	Rank  float64 `bun:"rank,scanonly"`
+}
+
+// OrderSearchHighlights are the fields of an order matching a search, HTML
+// escaped with the matches marked by HighlightStart and HighlightStop.
+type OrderSearchHighlights struct {
+	RecipientName      string `json:"recipient_name,omitempty" pii:"name"`
+	RecipientAddress   string `json:"recipient_address,omitempty" pii:"address"`
+	MerchantOrderID    string `json:"merchant_order_id,omitempty"`
+	ItemDescription    string `json:"item_description,omitempty"`
+	OrderConsignmentID string `json:"order_consignment_id,omitempty"`
+}
+
+type OrderSearchResult struct {
+	*OrderResponse
+	UserID     int64                  `json:"user_id"`
+	Rank       float64                `json:"rank"`
+	Highlights *OrderSearchHighlights `json:"highlights,omitempty"`
+}
+
+type SearchOrdersResponse struct {
+	Orders      []*OrderSearchResult `json:"orders"`
+	CurrentPage int                  `json:"current_page"`
+	PerPage     int                  `json:"per_page"`
+	TotalInPage int                  `json:"total_in_page"`
+}
+
+// ParseSearchOrdersRequest reads the search of given user from given query
+// parameters. The merchants search their own orders, the staff search the
+// orders of every user unless narrowed by user_id.
+func ParseSearchOrdersRequest(query url.Values, userID int64, role Role) (*SearchOrdersRequest, *utils.ResponseError) {
+	responseError := &utils.ResponseError{
+		Code:    "400",
+		Message: "Please provide a valid request",
+		Type:    "error",
+		Errors:  make(map[string][]string),
+	}
+	req := &SearchOrdersRequest{UserId: userID, Limit: DefaultSearchLimit}
+
+	req.Query = strings.Join(strings.Fields(query.Get("q")), " ")
+	if n := utf8.RuneCountInString(req.Query); n < MinSearchLength || n > MaxSearchLength {
+		responseError.AddValidationError("q", "The q must be "+strconv.Itoa(MinSearchLength)+" to "+strconv.Itoa(MaxSearchLength)+" characters.")
+	}
+
+	parseInt := func(key string, min int64) (int64, bool) {
+		value := query.Get(key)
+		if value == "" {
+			return 0, false
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < min {
+			responseError.AddValidationError(key, "The "+key+" must be an integer of at least "+strconv.FormatInt(min, 10)+".")
+			return 0, false
+		}
+		return n, true
+	}
+
+	page := int64(1)
+	if n, ok := parseInt("page", 1); ok {
+		page = n
+	}
+	if n, ok := parseInt("limit", 1); ok {
+		if n > MaxOrderLimit {
+			responseError.AddValidationError("limit", "The limit may not be greater than "+strconv.Itoa(MaxOrderLimit)+".")
+		}
+		req.Limit = int(n)
+	}
+	req.Offset = pageOffset(responseError, page, req.Limit)
+	req.StoreID, _ = parseInt("store_id", 1)
+
+	if role.IsStaff() {
+		req.UserId, _ = parseInt("user_id", 1)
+	} else if query.Get("user_id") != "" {
+		responseError.AddValidationError("user_id", "The user_id may only be given by the staff.")
+	}
+
+	if len(responseError.Errors) > 0 {
+		return nil, responseError
+	}
+	return req, nil
+}
+
+// HighlightMatches returns given value HTML escaped with the matches of given
+// search marked, the substrings of it and the words similar to its words, or
+// "" when nothing matches.
+func HighlightMatches(value, search string) string {
+	if value == "" || search == "" {
+		return ""
+	}
+
+	// the whole search as a substring, as matched by ILIKE
+	lowerValue, lowerSearch := strings.ToLower(value), strings.ToLower(search)
+	if len(lowerValue) == len(value) {
+		if i := strings.Index(lowerValue, lowerSearch); i >= 0 {
+			return html.EscapeString(value[:i]) + HighlightStart + html.EscapeString(value[i:i+len(search)]) + HighlightStop + html.EscapeString(value[i+len(search):])
+		}
+	}
+
+	// the words similar to a searched word, as matched by pg_trgm
+	searchWords := strings.FieldsFunc(lowerSearch, isWordSeparator)
+	var (
+		b       strings.Builder
+		matched bool
+		last    int
+	)
+	for _, word := range wordSpans(value) {
+		lowerWord := strings.ToLower(value[word[0]:word[1]])
+		for _, searchWord := range searchWords {
+			if strings.Contains(lowerWord, searchWord) || trigramSimilarity(lowerWord, searchWord) >= fuzzyMatchThreshold {
+				b.WriteString(html.EscapeString(value[last:word[0]]))
+				b.WriteString(HighlightStart + html.EscapeString(value[word[0]:word[1]]) + HighlightStop)
+				last, matched = word[1], true
+				break
+			}
+		}
+	}
+	if !matched {
+		return ""
+	}
+	b.WriteString(html.EscapeString(value[last:]))
+	return b.String()
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// wordSpans returns the start and end byte offsets of the words of s.
+func wordSpans(s string) [][2]int {
+	var (
+		spans [][2]int
+		start = -1
+	)
+	for i, r := range s {
+		switch {
+		case !isWordSeparator(r) && start < 0:
+			start = i
+		case isWordSeparator(r) && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(s)})
+	}
+	return spans
+}
+
+// trigramSimilarity returns the similarity of two lower case words as
+// computed by pg_trgm, the share of their trigrams in common.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	common := 0
+	for t := range ta {
+		if tb[t] {
+			common++
+		}
+	}
+	union := len(ta) + len(tb) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+// trigrams returns the trigrams of a word, padded with two spaces before and
+// one after as pg_trgm does.
+func trigrams(word string) map[string]bool {
+	runes := []rune("  " + word + " ")
+	set := make(map[string]bool, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = true
+	}
+	return set
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSearchOrdersRequest(t *testing.T) {
+	req, responseError := ParseSearchOrdersRequest(url.Values{"q": {"  kaium   banani "}, "page": {"2"}, "store_id": {"3"}}, 7, RoleMerchant)
+	require.Nil(t, responseError)
+	assert.Equal(t, &SearchOrdersRequest{Query: "kaium banani", UserId: 7, StoreID: 3, Limit: DefaultSearchLimit, Offset: DefaultSearchLimit}, req)
+
+	_, responseError = ParseSearchOrdersRequest(url.Values{"q": {"k"}, "user_id": {"8"}, "limit": {"0"}}, 7, RoleMerchant)
+	require.NotNil(t, responseError)
+	for _, key := range []string{"q", "user_id", "limit"} {
+		assert.Contains(t, responseError.Errors, key)
+	}
+
+	// the staff search every user, unless narrowed
+	req, responseError = ParseSearchOrdersRequest(url.Values{"q": {"kaium"}}, 1, RoleSupport)
+	require.Nil(t, responseError)
+	assert.Zero(t, req.UserId)
+	req, responseError = ParseSearchOrdersRequest(url.Values{"q": {"kaium"}, "user_id": {"8"}}, 1, RoleAdmin)
+	require.Nil(t, responseError)
+	assert.Equal(t, int64(8), req.UserId)
+}
+
+func TestHighlightMatches(t *testing.T) {
+	for _, test := range []struct {
+		value, search, want string
+	}{
+		{"Kaium Hossain", "kai", "<mark>Kai</mark>um Hossain"},
+		{"banani, gulshan 2, dhaka", "gulshn dhaka", "banani, <mark>gulshan</mark> 2, <mark>dhaka</mark>"},
+		{"ORD-123456", "2345", "ORD-1<mark>2345</mark>6"},
+		{"banani, gulshan 2, dhaka", "mirpur", ""},
+		{"", "kaium", ""},
+		{`<img src=x onerror="alert(1)">kaium`, "kaium", "&lt;img src=x onerror=&#34;alert(1)&#34;&gt;<mark>kaium</mark>"},
+		{"<script>kaium</script> banani", "banan", "&lt;script&gt;kaium&lt;/script&gt; <mark>banan</mark>i"},
+		{"<b>hossain</b> & co", "hosain", "&lt;b&gt;<mark>hossain</mark>&lt;/b&gt; &amp; co"},
+	} {
+		assert.Equal(t, test.want, HighlightMatches(test.value, test.search), test.value)
+	}
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kaium123/order/internal/encryption"
+	"github.com/kaium123/order/internal/utils"
+	"github.com/uptrace/bun"
+)
+
+var (
+	// ErrVersionConflict is returned for a change based on a version of the
+	// order changed since.
+	ErrVersionConflict = errors.New("the order was changed since, fetch it again")
+	// ErrNotEditable is returned for a change of an order picked up already.
+	ErrNotEditable = errors.New("the order can only be edited while pending at the merchant")
+)
+
+// InvalidOrderError is returned for a change of an order breaking its rules,
+// e.g. an edition leaving it invalid. Response holds the errors by field.
+type InvalidOrderError struct {
+	Response *utils.ResponseError
+}
+
+func (e *InvalidOrderError) Error() string {
+	return e.Response.Message
+}
+
+// UpdateOrderRequest is the body of the edition of an order. The fields left
+// out are kept, Version is the version of the order edited.
+type UpdateOrderRequest struct {
+	UserId           int64    `json:"-"`
+	ConsignmentID    string   `json:"-"`
+	Version          int      `json:"version"`
+	RecipientName    *string  `json:"recipient_name"`
+	RecipientPhone   *string  `json:"recipient_phone"`
+	RecipientAddress *string  `json:"recipient_address"`
+	RecipientCity    *int64   `json:"recipient_city"`
+	RecipientZone    *int64   `json:"recipient_zone"`
+	RecipientArea    *int64   `json:"recipient_area"`
+	ItemWeight       *float64 `json:"item_weight"`
+	ItemQuantity     *int     `json:"item_quantity"`
+	AmountToCollect  *float64 `json:"amount_to_collect"`
+}
+
+// Validate validates the fields of the request itself, the edited order is
+// validated by Order.Validate.
+func (r *UpdateOrderRequest) Validate() *utils.ResponseError {
+	responseError := &utils.ResponseError{
+		Code:    "422",
+		Message: "Please fix the given errors",
+		Type:    "error",
+		Errors:  make(map[string][]string),
+	}
+
+	if r.Version <= 0 {
+		responseError.AddValidationError("version", "The version of the order edited is required.")
+	}
+	if r.RecipientName == nil && r.RecipientPhone == nil && r.RecipientAddress == nil &&
+		r.RecipientCity == nil && r.RecipientZone == nil && r.RecipientArea == nil &&
+		r.ItemWeight == nil && r.ItemQuantity == nil && r.AmountToCollect == nil {
+		responseError.AddValidationError("order", "Give at least one field to edit.")
+	}
+
+	if len(responseError.Errors) > 0 {
+		return responseError
+	}
+	return nil
+}
+
+// Apply sets the fields given on given order.
+func (r *UpdateOrderRequest) Apply(o *Order) {
+	if r.RecipientName != nil {
+		o.RecipientName = *r.RecipientName
+	}
+	if r.RecipientPhone != nil {
+		o.RecipientPhone = encryption.String(*r.RecipientPhone)
+	}
+	if r.RecipientAddress != nil {
+		o.RecipientAddress = encryption.String(*r.RecipientAddress)
+	}
+	if r.RecipientCity != nil {
+		o.RecipientCity = *r.RecipientCity
+	}
+	if r.RecipientZone != nil {
+		o.RecipientZone = *r.RecipientZone
+	}
+	if r.RecipientArea != nil {
+		o.RecipientArea = *r.RecipientArea
+	}
+	if r.ItemWeight != nil {
+		o.ItemWeight = *r.ItemWeight
+	}
+	if r.ItemQuantity != nil {
+		o.ItemQuantity = *r.ItemQuantity
+	}
+	if r.AmountToCollect != nil {
+		o.AmountToCollect = *r.AmountToCollect
+	}
+}
+
+// Editable reports whether the order may still be edited, it's pending and
+// hasn't left the merchant.
+func (o *Order) Editable() bool {
+	return o.OrderStatus == Pending && o.TransferStatus == AtMerchant
+}
+
+// OrderChange is the change of a field of an order.
+type OrderChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// DiffOrders returns the changes of the editable fields, and of the fees,
+// from an order to another.
+func DiffOrders(from, to *Order) []OrderChange {
+	var changes []OrderChange
+	add := func(field string, a, b any) {
+		if a != b {
+			changes = append(changes, OrderChange{Field: field, From: a, To: b})
+		}
+	}
+	add("recipient_name", from.RecipientName, to.RecipientName)
+	add("recipient_phone", string(from.RecipientPhone), string(to.RecipientPhone))
+	add("recipient_address", string(from.RecipientAddress), string(to.RecipientAddress))
+	add("recipient_city", from.RecipientCity, to.RecipientCity)
+	add("recipient_zone", from.RecipientZone, to.RecipientZone)
+	add("recipient_area", from.RecipientArea, to.RecipientArea)
+	add("item_weight", from.ItemWeight, to.ItemWeight)
+	add("item_quantity", from.ItemQuantity, to.ItemQuantity)
+	add("amount_to_collect", from.AmountToCollect, to.AmountToCollect)
+	add("delivery_fee", from.DeliveryFee, to.DeliveryFee)
+	add("cod_fee", from.CodFee, to.CodFee)
+	add("total_fee", from.TotalFee, to.TotalFee)
+	return changes
+}
+
+// OrderHistory records an edition of an order, Version is the version it
+// led to.
+type OrderHistory struct {
+	bun.BaseModel `bun:"table:order_history"`
+
+	ID        int64         `json:"id" bun:"id,pk,autoincrement"`
+	OrderID   int64         `json:"-" bun:"order_id,notnull"`
+	Version   int           `json:"version" bun:"version,notnull"`
+	ChangedBy int64         `json:"changed_by" bun:"changed_by,notnull"`
+	Changes   []OrderChange `json:"changes" bun:"changes,type:jsonb,notnull"`
+	CreatedAt time.Time     `json:"created_at" bun:"created_at,default:current_timestamp,notnull"`
+}
+
+// FindHistoryRequest lists the editions of an order of a user.
+type FindHistoryRequest struct {
+	UserId        int64
+	ConsignmentID string
+}
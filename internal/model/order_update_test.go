@@ -0,0 +1,51 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateOrderRequestValidate(t *testing.T) {
+	responseError := (&UpdateOrderRequest{}).Validate()
+	require.NotNil(t, responseError)
+	assert.Contains(t, responseError.Errors, "version")
+	assert.Contains(t, responseError.Errors, "order")
+
+	weight := 1.5
+	assert.Nil(t, (&UpdateOrderRequest{Version: 2, ItemWeight: &weight}).Validate())
+}
+
+func TestUpdateOrderRequestApply(t *testing.T) {
+	order := &Order{
+		RecipientName:   "Kaium",
+		RecipientPhone:  "01711111111",
+		RecipientCity:   1,
+		ItemWeight:      0.5,
+		AmountToCollect: 1000,
+		DeliveryFee:     60,
+		CodFee:          10,
+		TotalFee:        70,
+	}
+	before := *order
+
+	phone, weight := "01811111111", 2.0
+	(&UpdateOrderRequest{RecipientPhone: &phone, ItemWeight: &weight}).Apply(order)
+	order.ApplyPricing(Pricing{InsideCityID: 1, InsideCityFee: 60, CODPercentage: 1})
+	assert.Equal(t, "Kaium", order.RecipientName)
+
+	assert.Equal(t, []OrderChange{
+		{Field: "recipient_phone", From: "01711111111", To: "01811111111"},
+		{Field: "item_weight", From: 0.5, To: 2.0},
+		{Field: "delivery_fee", From: 60.0, To: 85.0},
+		{Field: "total_fee", From: 70.0, To: 95.0},
+	}, DiffOrders(&before, order))
+	assert.Empty(t, DiffOrders(order, order))
+}
+
+func TestOrderEditable(t *testing.T) {
+	assert.True(t, (&Order{OrderStatus: Pending, TransferStatus: AtMerchant}).Editable())
+	assert.False(t, (&Order{OrderStatus: Processing, TransferStatus: AtHub}).Editable())
+	assert.False(t, (&Order{OrderStatus: Pending, TransferStatus: AtHub}).Editable())
+}
@@ -0,0 +1,152 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Types of the order events.
+const (
+	EventOrderCreated       = "order.created"
+	EventOrderCancelled     = "order.cancelled"
+	EventOrderStatusChanged = "order.status_changed"
+	EventOrderHandedOver    = "order.handed_over"
+	EventOrderUpdated       = "order.updated"
+	EventDeliveryAttempted  = "order.delivery_attempted"
+	EventOrderReturned      = "order.returned"
+)
+
+// OutboxEvent is an event written in the transaction of the change it
+// describes, then published by the relay. Events are ordered by transaction
+// and ID.
+type OutboxEvent struct {
+	bun.BaseModel `bun:"table:outbox"`
+
+	ID int64 `json:"id" bun:"id,pk,autoincrement"`
+	// TxID is the ID of the writing transaction, the events of transactions
+	// still running are never skipped by the relay when ordered by it.
+	TxID        string          `json:"txid,omitempty" bun:"txid,scanonly"`
+	Type        string          `json:"type" bun:"event_type,notnull"`
+	AggregateID string          `json:"aggregate_id" bun:"aggregate_id,notnull"`
+	Payload     json.RawMessage `json:"payload" bun:"payload,type:jsonb,notnull"`
+	CreatedAt   time.Time       `json:"created_at" bun:"created_at,default:current_timestamp,notnull"`
+}
+
+// NewOutboxEvent returns the event of given type about given aggregate, e.g.
+// the consignment ID of an order, with given payload encoded to JSON.
+func NewOutboxEvent(eventType, aggregateID string, payload any) (*OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{Type: eventType, AggregateID: aggregateID, Payload: data}, nil
+}
+
+// StreamID returns the ID of the event in the order streams, its transaction
+// and event IDs in the order the relay publishes them.
+func (e *OutboxEvent) StreamID() string {
+	return e.TxID + "-" + strconv.FormatInt(e.ID, 10)
+}
+
+// ParseStreamID returns the transaction and event IDs of given stream ID.
+func ParseStreamID(streamID string) (txID string, id int64, err error) {
+	txID, idPart, ok := strings.Cut(streamID, "-")
+	if !ok {
+		return "", 0, errors.New("must be a transaction and an event ID separated by '-'")
+	}
+	if _, err = strconv.ParseUint(txID, 10, 64); err != nil {
+		return "", 0, errors.New("must start with a transaction ID")
+	}
+	if id, err = strconv.ParseInt(idPart, 10, 64); err != nil || id < 0 {
+		return "", 0, errors.New("must end with an event ID")
+	}
+	return txID, id, nil
+}
+
+// OrderCreatedEvent is the payload of order.created. The recipient details
+// are left out, the events leave the encrypted storage.
+type OrderCreatedEvent struct {
+	ConsignmentID   string    `json:"consignment_id"`
+	MerchantOrderID string    `json:"merchant_order_id,omitempty"`
+	StoreID         int64     `json:"store_id"`
+	UserID          int64     `json:"user_id"`
+	RecipientCity   int64     `json:"recipient_city"`
+	OrderStatus     string    `json:"order_status"`
+	AmountToCollect float64   `json:"amount_to_collect"`
+	DeliveryFee     float64   `json:"delivery_fee"`
+	TotalFee        float64   `json:"total_fee"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// OrderCancelledEvent is the payload of order.cancelled.
+type OrderCancelledEvent struct {
+	ConsignmentID string    `json:"consignment_id"`
+	StoreID       int64     `json:"store_id"`
+	UserID        int64     `json:"user_id"`
+	Reason        string    `json:"reason"`
+	CancelledAt   time.Time `json:"cancelled_at"`
+}
+
+// OrderStatusChangedEvent is the payload of order.status_changed.
+type OrderStatusChangedEvent struct {
+	ConsignmentID string    `json:"consignment_id"`
+	StoreID       int64     `json:"store_id"`
+	UserID        int64     `json:"user_id"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	ChangedAt     time.Time `json:"changed_at"`
+}
+
+// OrderHandedOverEvent is the payload of order.handed_over. The name of the
+// receiving party is left out, it's the one of the recipient at last.
+type OrderHandedOverEvent struct {
+	ConsignmentID string    `json:"consignment_id"`
+	StoreID       int64     `json:"store_id"`
+	UserID        int64     `json:"user_id"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	ReceiverRef   string    `json:"receiver_ref,omitempty"`
+	HandedOverAt  time.Time `json:"handed_over_at"`
+}
+
+// OrderUpdatedEvent is the payload of order.updated. Only the names of the
+// fields changed are given, the recipient details stay out of the events.
+type OrderUpdatedEvent struct {
+	ConsignmentID string    `json:"consignment_id"`
+	StoreID       int64     `json:"store_id"`
+	UserID        int64     `json:"user_id"`
+	Version       int       `json:"version"`
+	Fields        []string  `json:"fields"`
+	DeliveryFee   float64   `json:"delivery_fee"`
+	TotalFee      float64   `json:"total_fee"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// DeliveryAttemptedEvent is the payload of order.delivery_attempted. The
+// notes of the rider are left out, they may name the recipient.
+type DeliveryAttemptedEvent struct {
+	ConsignmentID string    `json:"consignment_id"`
+	StoreID       int64     `json:"store_id"`
+	UserID        int64     `json:"user_id"`
+	Attempt       int       `json:"attempt"`
+	Outcome       string    `json:"outcome"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	AttemptedAt   time.Time `json:"attempted_at"`
+}
+
+// OrderReturnedEvent is the payload of order.returned, the order goes back
+// to the merchant after FailedAttempts failed delivery attempts.
+type OrderReturnedEvent struct {
+	ConsignmentID  string    `json:"consignment_id"`
+	StoreID        int64     `json:"store_id"`
+	UserID         int64     `json:"user_id"`
+	FailedAttempts int       `json:"failed_attempts"`
+	RTOFee         float64   `json:"rto_fee"`
+	TotalFee       float64   `json:"total_fee"`
+	ReturnedAt     time.Time `json:"returned_at"`
+}
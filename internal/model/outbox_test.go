@@ -0,0 +1,21 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStreamID(t *testing.T) {
+	event := &OutboxEvent{ID: 42, TxID: "7421"}
+	txID, id, err := ParseStreamID(event.StreamID())
+	require.NoError(t, err)
+	assert.Equal(t, "7421", txID)
+	assert.Equal(t, int64(42), id)
+
+	for _, streamID := range []string{"42", "-42", "x-42", "7421-", "7421--1", "7421-42-1"} {
+		_, _, err := ParseStreamID(streamID)
+		assert.Error(t, err, streamID)
+	}
+}
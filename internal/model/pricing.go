@@ -0,0 +1,20 @@
+package model
+
+// Pricing holds the parameters of the order fee calculation.
+type Pricing struct {
+	InsideCityID   int64   `json:"inside_city_id" yaml:"inside_city_id" toml:"inside_city_id" mapstructure:"inside_city_id"`
+	InsideCityFee  float64 `json:"inside_city_fee" yaml:"inside_city_fee" toml:"inside_city_fee" mapstructure:"inside_city_fee"`
+	OutsideCityFee float64 `json:"outside_city_fee" yaml:"outside_city_fee" toml:"outside_city_fee" mapstructure:"outside_city_fee"`
+	CODPercentage  float64 `json:"cod_percentage" yaml:"cod_percentage" toml:"cod_percentage" mapstructure:"cod_percentage"`
+	// RTOPercentage is the share of the delivery fee charged for the return
+	// of an order to the merchant.
+	RTOPercentage float64 `json:"rto_percentage" yaml:"rto_percentage" toml:"rto_percentage" mapstructure:"rto_percentage"`
+}
+
+// BaseDeliveryFee returns the base delivery fee for given recipient city.
+func (p Pricing) BaseDeliveryFee(city int64) float64 {
+	if city == p.InsideCityID {
+		return p.InsideCityFee
+	}
+	return p.OutsideCityFee
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Store is a pickup point of a merchant.
+type Store struct {
+	bun.BaseModel `bun:"table:stores"`
+
+	ID           int64     `json:"id" bun:"id,pk,autoincrement"`
+	UserID       int64     `json:"user_id" bun:"user_id,notnull"`
+	Name         string    `json:"name" bun:"name,notnull"`
+	ContactPhone string    `json:"contact_phone,omitempty" bun:"contact_phone" pii:"phone"`
+	Address      string    `json:"address,omitempty" bun:"address" pii:"address"`
+	CreatedAt    time.Time `json:"created_at" bun:"created_at,default:current_timestamp,notnull"`
+	UpdatedAt    time.Time `json:"updated_at" bun:"updated_at,nullzero"`
+	DeletedAt    time.Time `json:"deleted_at" bun:"deleted_at,soft_delete,nullzero"`
+}
+
+// RateCard holds the fees of deliveries to a city. It overrides the
+// configured Pricing for that city.
+type RateCard struct {
+	bun.BaseModel `bun:"table:rate_cards"`
+
+	ID              int64     `json:"id" bun:"id,pk,autoincrement"`
+	CityID          int64     `json:"city_id" bun:"city_id,notnull"`
+	BaseDeliveryFee float64   `json:"base_delivery_fee" bun:"base_delivery_fee,notnull"`
+	CODPercentage   float64   `json:"cod_percentage" bun:"cod_percentage,notnull"`
+	CreatedAt       time.Time `json:"created_at" bun:"created_at,default:current_timestamp,notnull"`
+	UpdatedAt       time.Time `json:"updated_at" bun:"updated_at,nullzero"`
+}
+
+// Pricing returns given defaults with the fees of the rate card for its city.
+func (r *RateCard) Pricing(defaults Pricing) Pricing {
+	return Pricing{
+		InsideCityID:   r.CityID,
+		InsideCityFee:  r.BaseDeliveryFee,
+		OutsideCityFee: defaults.OutsideCityFee,
+		CODPercentage:  r.CODPercentage,
+		RTOPercentage:  defaults.RTOPercentage,
+	}
+}
@@ -12,8 +12,9 @@ type User struct {
 
 	ID           int64     `json:"id" bun:"id,pk,autoincrement"`
 	UserName     string    `json:"user_name" bun:"user_name"`
-	Email        string    `json:"email" bun:"email"`
-	PasswordHash string    `json:"-" bun:"password_hash"`
+	Email        string    `json:"email" bun:"email" pii:"email"`
+	PasswordHash string    `json:"-" bun:"password_hash" pii:"secret"`
+	Role         Role      `json:"role" bun:"role,notnull,default:'merchant'"`
 	CreatedAt    time.Time `json:"created_at" bun:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" bun:"updated_at"`
 	DeletedAt    time.Time `json:"deleted_at" bun:"deleted_at,soft_delete,nullzero"`
@@ -24,7 +25,7 @@ type AccessToken struct {
 	bun.BaseModel `bun:"table:access_tokens"`
 
 	ID        int64     `json:"id" bun:"id,pk,autoincrement"`
-	Token     string    `json:"token" bun:"token"`
+	Token     string    `json:"token" bun:"token" pii:"secret"`
 	UserID    int64     `json:"user_id" bun:"user_id"`
 	Expiry    time.Time `json:"expiry" bun:"expiry"`
 	CreatedAt time.Time `json:"created_at" bun:"created_at"`
@@ -36,7 +37,7 @@ type RefreshToken struct {
 	bun.BaseModel `bun:"table:refresh_tokens"`
 
 	ID        int64     `json:"id" bun:"id,pk,autoincrement"`
-	Token     string    `json:"token" bun:"token"`
+	Token     string    `json:"token" bun:"token" pii:"secret"`
 	UserID    int64     `json:"user_id" bun:"user_id"`
 	Expiry    time.Time `json:"expiry" bun:"expiry"`
 	CreatedAt time.Time `json:"created_at" bun:"created_at"`
@@ -46,16 +47,58 @@ type RefreshToken struct {
 // UserLoginRequest represents the login request payload.
 type UserLoginRequest struct {
 	Username string `json:"username"`
-	Password string `json:"password"`
-	Email    string `json:"email"`
+	Password string `json:"password" pii:"secret"`
+	Email    string `json:"email" pii:"email"`
 }
 
 // UserLoginResponse represents the utils with tokens after a successful login.
 type UserLoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token" pii:"secret"`
+	RefreshToken string `json:"refresh_token" pii:"secret"`
 	TokenType    string `json:"token_type"`
 	ExpireIn     int64  `json:"expire_in"`
 }
 
 var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Role of a user, it grants permissions.
+type Role string
+
+// Roles of the users.
+const (
+	// RoleMerchant books and manages the orders of their stores.
+	RoleMerchant Role = "merchant"
+	// RoleSupport assists the merchants, without access to the personal
+	// information of the recipients.
+	RoleSupport Role = "support"
+	// RoleAdmin manages the platform.
+	RoleAdmin Role = "admin"
+	// RoleRider delivers the orders of every merchant.
+	RoleRider Role = "rider"
+)
+
+// CanViewPII reports whether the role may see personally identifiable
+// information, such as the recipient phone numbers, unmasked. Unknown roles
+// may not.
+func (r Role) CanViewPII() bool {
+	return r == RoleMerchant || r == RoleAdmin
+}
+
+// IsStaff reports whether the role works for the platform rather than a
+// merchant, it may look into the orders of every merchant.
+func (r Role) IsStaff() bool {
+	return r == RoleSupport || r == RoleAdmin
+}
+
+// CanRecordDeliveries reports whether the role may record the delivery
+// attempts of the orders, the riders and the staff may.
+func (r Role) CanRecordDeliveries() bool {
+	return r == RoleRider || r.IsStaff()
+}
+
+// CanHandOverTo reports whether the role may record the handovers of the
+// orders to given transfer status. The merchants only record the pickup of
+// their orders by the hub, the riders and the staff every handover.
+func (r Role) CanHandOverTo(to TransferStatus) bool {
+	return r.CanRecordDeliveries() || to == AtHub
+}
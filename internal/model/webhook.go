@@ -0,0 +1,175 @@
+package model
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/kaium123/order/internal/encryption"
+	"github.com/kaium123/order/internal/utils"
+	"github.com/uptrace/bun"
+)
+
+// EventWebhookTest is the type of the test events sent on request to a
+// webhook, whatever its event types.
+const EventWebhookTest = "webhook.test"
+
+// WebhookEventTypes are the event types a webhook can subscribe to.
+var WebhookEventTypes = []string{
+	EventOrderCreated, EventOrderCancelled, EventOrderStatusChanged, EventOrderHandedOver,
+	EventOrderUpdated, EventDeliveryAttempted, EventOrderReturned,
+}
+
+// Statuses of the webhook deliveries.
+const (
+	// DeliveryPending deliveries are attempted at NextAttemptAt.
+	DeliveryPending = "pending"
+	// DeliverySucceeded deliveries got a 2xx response.
+	DeliverySucceeded = "succeeded"
+	// DeliveryFailed deliveries ran out of attempts, they can be replayed.
+	DeliveryFailed = "failed"
+)
+
+// WebhookSubscription is an endpoint of a store receiving the order events of
+// given types.
+type WebhookSubscription struct {
+	bun.BaseModel `bun:"table:webhook_subscriptions"`
+
+	ID      int64  `json:"id" bun:"id,pk,autoincrement"`
+	StoreID int64  `json:"store_id" bun:"store_id,notnull"`
+	UserID  int64  `json:"user_id" bun:"user_id,notnull"`
+	URL     string `json:"url" bun:"url,notnull"`
+	// Secret signs the payloads, it's only returned on creation.
+	Secret     encryption.String `json:"-" bun:"secret,notnull"`
+	EventTypes []string          `json:"event_types" bun:"event_types,array,notnull"`
+	CreatedAt  time.Time         `json:"created_at" bun:"created_at,default:current_timestamp,notnull"`
+	UpdatedAt  time.Time         `json:"updated_at" bun:"updated_at,nullzero"`
+	DeletedAt  time.Time         `json:"deleted_at" bun:"deleted_at,soft_delete,nullzero"`
+}
+
+// Subscribes reports whether the subscription receives the events of given
+// type.
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	return eventType == EventWebhookTest || slices.Contains(s.EventTypes, eventType)
+}
+
+// WebhookDelivery is the delivery of an event to a subscription, along with
+// the outcome of its last attempt.
+type WebhookDelivery struct {
+	bun.BaseModel `bun:"table:webhook_deliveries"`
+
+	ID             int64 `json:"id" bun:"id,pk,autoincrement"`
+	SubscriptionID int64 `json:"subscription_id" bun:"subscription_id,notnull"`
+	// EventID is the ID of the outbox event, 0 for the test events.
+	EventID        int64           `json:"event_id,omitempty" bun:"event_id,nullzero"`
+	EventType      string          `json:"event_type" bun:"event_type,notnull"`
+	Payload        json.RawMessage `json:"payload" bun:"payload,type:jsonb,notnull"`
+	Status         string          `json:"status" bun:"status,notnull"`
+	Attempts       int             `json:"attempts" bun:"attempts,notnull"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" bun:"next_attempt_at,notnull"`
+	LastStatusCode int             `json:"last_status_code,omitempty" bun:"last_status_code,nullzero"`
+	LastError      string          `json:"last_error,omitempty" bun:"last_error,nullzero"`
+	// LastResponse is the beginning of the body of the last response.
+	LastResponse string    `json:"last_response,omitempty" bun:"last_response,nullzero"`
+	DurationMs   int64     `json:"duration_ms" bun:"duration_ms,nullzero"`
+	CreatedAt    time.Time `json:"created_at" bun:"created_at,default:current_timestamp,notnull"`
+	UpdatedAt    time.Time `json:"updated_at" bun:"updated_at,nullzero"`
+	DeliveredAt  time.Time `json:"delivered_at,omitempty" bun:"delivered_at,nullzero"`
+}
+
+// WebhookPayload is the JSON body posted to the webhooks. Receivers dedupe by
+// EventID, a replayed delivery keeps its event.
+type WebhookPayload struct {
+	DeliveryID int64           `json:"delivery_id"`
+	EventID    int64           `json:"event_id,omitempty"`
+	Type       string          `json:"type"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// WebhookTestEvent is the payload of webhook.test.
+type WebhookTestEvent struct {
+	StoreID int64     `json:"store_id"`
+	Message string    `json:"message"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// CreateWebhookRequest is the request for subscribing a store to the order
+// events. A secret is generated unless given.
+type CreateWebhookRequest struct {
+	UserId     int64    `json:"-"`
+	StoreID    int64    `param:"store_id" validate:"required"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+}
+
+// Validate validates the webhook fields and returns errors in the required
+// format.
+func (r *CreateWebhookRequest) Validate() *utils.ResponseError {
+	responseError := &utils.ResponseError{
+		Code:    "422",
+		Message: "Please fix the given errors",
+		Type:    "error",
+		Errors:  make(map[string][]string),
+	}
+
+	if r.URL == "" {
+		responseError.AddValidationError("url", "The url field is required.")
+	} else if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		responseError.AddValidationError("url", "The url must be an absolute http or https URL.")
+	}
+	if len(r.EventTypes) == 0 {
+		responseError.AddValidationError("event_types", "The event types field is required.")
+	}
+	for _, eventType := range r.EventTypes {
+		if !slices.Contains(WebhookEventTypes, eventType) {
+			responseError.AddValidationError("event_types", "Unknown event type "+eventType+".")
+		}
+	}
+	if r.Secret != "" && len(r.Secret) < 16 {
+		responseError.AddValidationError("secret", "The secret must be at least 16 characters.")
+	}
+
+	if len(responseError.Errors) > 0 {
+		return responseError
+	}
+
+	return nil
+}
+
+// CreateWebhookResponse is the created subscription along with its secret.
+type CreateWebhookResponse struct {
+	*WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// WebhookRequest is the request parameter for a webhook of a store.
+type WebhookRequest struct {
+	UserId  int64 `json:"-"`
+	StoreID int64 `param:"store_id" validate:"required"`
+	ID      int64 `param:"id"`
+}
+
+// WebhookDeliveryRequest is the request parameter for a delivery of a
+// webhook.
+type WebhookDeliveryRequest struct {
+	WebhookRequest
+	DeliveryID int64 `param:"delivery_id" validate:"required"`
+}
+
+// FindDeliveriesRequest is the request parameter for listing the deliveries
+// of a webhook, latest first.
+type FindDeliveriesRequest struct {
+	WebhookRequest
+	Status string
+	Limit  int
+	Offset int
+}
+
+// FindDeliveriesResponse is a page of deliveries.
+type FindDeliveriesResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	PaginationResponse
+}
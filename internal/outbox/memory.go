@@ -0,0 +1,55 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kaium123/order/internal/model"
+)
+
+// MemorySink keeps the events in memory, for the tests.
+type MemorySink struct {
+	name string
+
+	mu     sync.Mutex
+	events []*model.OutboxEvent
+	err    error
+}
+
+// NewMemorySink returns an empty MemorySink with given name.
+func NewMemorySink(name string) *MemorySink {
+	return &MemorySink{name: name}
+}
+
+func (s *MemorySink) Name() string {
+	return s.name
+}
+
+// Publish appends the events, unless failing as set by Fail.
+func (s *MemorySink) Publish(_ context.Context, events []*model.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+// Fail makes the publishing fail with given error, until called with nil.
+func (s *MemorySink) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Events returns the events published so far.
+func (s *MemorySink) Events() []*model.OutboxEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*model.OutboxEvent(nil), s.events...)
+}
+
+func (s *MemorySink) Close() error {
+	return nil
+}
@@ -0,0 +1,83 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/kaium123/order/internal/model"
+	"github.com/nats-io/nats.go"
+)
+
+// NATSConfig of the NATS sink.
+type NATSConfig struct {
+	Enable bool   `json:"enable" yaml:"enable" toml:"enable" mapstructure:"enable"`
+	URL    string `json:"url" yaml:"url" toml:"url" mapstructure:"url"`
+	// SubjectPrefix of the subjects, the events of a type are published to
+	// "<prefix>.<type>", e.g. orders.order.created.
+	SubjectPrefix string `json:"subject_prefix" yaml:"subject_prefix" toml:"subject_prefix" mapstructure:"subject_prefix"`
+	// JetStream publishes to a JetStream stream, which must capture the
+	// subjects, and waits for its acknowledgement. Core NATS only waits for
+	// the server to receive the events: they are lost without subscribers.
+	JetStream bool `json:"jetstream" yaml:"jetstream" toml:"jetstream" mapstructure:"jetstream"`
+}
+
+// natsSink publishes the events to NATS subjects by type.
+type natsSink struct {
+	conn *nats.Conn
+	js   nats.JetStreamContext
+	conf *NATSConfig
+}
+
+// NewNATSSink connects to the configured NATS server and returns the sink
+// publishing to it.
+func NewNATSSink(conf *NATSConfig) (Sink, error) {
+	conn, err := nats.Connect(conf.URL, nats.Name("orders-outbox"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	s := &natsSink{conn: conn, conf: conf}
+	if conf.JetStream {
+		if s.js, err = conn.JetStream(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to open JetStream: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func (s *natsSink) Name() string {
+	return "nats"
+}
+
+// Publish sends every event as JSON with its ID as Nats-Msg-Id, so JetStream
+// drops the ones published twice within its duplicate window.
+func (s *natsSink) Publish(ctx context.Context, events []*model.OutboxEvent) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		msg := nats.NewMsg(s.conf.SubjectPrefix + "." + event.Type)
+		msg.Data = data
+		msg.Header.Set(nats.MsgIdHdr, strconv.FormatInt(event.ID, 10))
+
+		if s.js != nil {
+			_, err = s.js.PublishMsg(msg, nats.Context(ctx))
+		} else {
+			err = s.conn.PublishMsg(msg)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to publish event %d: %w", event.ID, err)
+		}
+	}
+	if s.js == nil {
+		return s.conn.FlushWithContext(ctx)
+	}
+	return nil
+}
+
+func (s *natsSink) Close() error {
+	return s.conn.Drain()
+}
@@ -0,0 +1,174 @@
+// Package outbox relays the events written to the outbox table to the
+// configured sinks, at least once and in order.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/metrics"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/repository"
+	"go.uber.org/zap"
+)
+
+// purgeInterval between the purges of the published events.
+const purgeInterval = time.Minute
+
+// Config of the relay and its sinks.
+type Config struct {
+	// Enable runs the relay in this instance. The events are written either
+	// way, the relays of several instances take turns on every sink.
+	Enable bool `json:"enable" yaml:"enable" toml:"enable" mapstructure:"enable"`
+	// PollInterval between the reads of the new events once all were relayed.
+	PollInterval time.Duration `json:"poll_interval" yaml:"poll_interval" toml:"poll_interval" mapstructure:"poll_interval"`
+	// BatchSize is the maximum number of events published at once.
+	BatchSize int `json:"batch_size" yaml:"batch_size" toml:"batch_size" mapstructure:"batch_size"`
+	// PublishTimeout bounds the publishing of a batch to a sink.
+	PublishTimeout time.Duration `json:"publish_timeout" yaml:"publish_timeout" toml:"publish_timeout" mapstructure:"publish_timeout"`
+	// Retention of the events published to every sink, 0 keeps them.
+	Retention time.Duration `json:"retention" yaml:"retention" toml:"retention" mapstructure:"retention"`
+
+	Redis RedisConfig `json:"redis" yaml:"redis" toml:"redis" mapstructure:"redis"`
+	NATS  NATSConfig  `json:"nats" yaml:"nats" toml:"nats" mapstructure:"nats"`
+}
+
+// Sink publishes the events somewhere. Publish must fail unless every event
+// was accepted: failed batches are published again, so a sink may see an
+// event twice but never misses one. Consumers dedupe by event ID.
+type Sink interface {
+	// Name of the sink, its offset is tracked under it.
+	Name() string
+	Publish(ctx context.Context, events []*model.OutboxEvent) error
+	Close() error
+}
+
+// NewSinks returns the sinks enabled by given configuration.
+func NewSinks(conf *Config, redisClient *redis.Client) (sinks []Sink, err error) {
+	if conf.Redis.Enable {
+		sinks = append(sinks, NewRedisSink(redisClient, &conf.Redis))
+	}
+	if conf.NATS.Enable {
+		sink, err := NewNATSSink(&conf.NATS)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+type InitRelay struct {
+	OutboxRepository repository.IOutbox
+	Sinks            []Sink
+	Config           *Config
+	Log              *log.Logger
+}
+
+// Relay publishes the events of the outbox to the sinks.
+type Relay struct {
+	outboxRepository repository.IOutbox
+	sinks            []Sink
+	conf             *Config
+	log              *log.Logger
+}
+
+// NewRelay returns a new Relay.
+func NewRelay(initRelay *InitRelay) *Relay {
+	return &Relay{
+		outboxRepository: initRelay.OutboxRepository,
+		sinks:            initRelay.Sinks,
+		conf:             initRelay.Config,
+		log:              initRelay.Log,
+	}
+}
+
+// Run relays the events until given context is done.
+func (r *Relay) Run(ctx context.Context) {
+	var lastPurge time.Time
+	for {
+		if _, err := r.RelayOnce(ctx); err != nil && ctx.Err() == nil {
+			r.log.Error(ctx, "failed to relay the outbox events", zap.Error(err))
+		}
+		if r.conf.Retention > 0 && time.Since(lastPurge) >= purgeInterval {
+			lastPurge = time.Now()
+			r.purge(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.conf.PollInterval):
+		}
+	}
+}
+
+// RelayOnce publishes every pending event to every sink, batch by batch. A
+// failing sink doesn't hold the others back. It returns the number of events
+// published.
+func (r *Relay) RelayOnce(ctx context.Context) (published int, err error) {
+	var errs []error
+	for _, sink := range r.sinks {
+		for ctx.Err() == nil {
+			n, err := r.outboxRepository.RelayEvents(ctx, sink.Name(), r.conf.BatchSize,
+				func(ctx context.Context, events []*model.OutboxEvent) error {
+					return r.publish(ctx, sink, events)
+				})
+			published += n
+			if err != nil {
+				errs = append(errs, fmt.Errorf("sink %s: %w", sink.Name(), err))
+				break
+			}
+			if n < r.conf.BatchSize {
+				break
+			}
+		}
+	}
+	return published, errors.Join(errs...)
+}
+
+func (r *Relay) publish(ctx context.Context, sink Sink, events []*model.OutboxEvent) error {
+	if r.conf.PublishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.conf.PublishTimeout)
+		defer cancel()
+	}
+
+	if err := sink.Publish(ctx, events); err != nil {
+		metrics.OutboxPublishErrors.WithLabelValues(sink.Name()).Inc()
+		return err
+	}
+	metrics.OutboxEventsPublished.WithLabelValues(sink.Name()).Add(float64(len(events)))
+	r.log.Debug(ctx, "outbox events published", zap.String("sink", sink.Name()),
+		zap.Int("count", len(events)), zap.Int64("last_id", events[len(events)-1].ID))
+	return nil
+}
+
+// purge deletes the events published to every sink past the retention.
+func (r *Relay) purge(ctx context.Context) {
+	names := make([]string, len(r.sinks))
+	for i, sink := range r.sinks {
+		names[i] = sink.Name()
+	}
+	purged, err := r.outboxRepository.PurgeEvents(ctx, names, time.Now().UTC().Add(-r.conf.Retention))
+	if err != nil {
+		r.log.Error(ctx, "failed to purge the outbox events", zap.Error(err))
+		return
+	}
+	if purged > 0 {
+		r.log.Info(ctx, "outbox events purged", zap.Int64("count", purged))
+	}
+}
+
+// Close the sinks.
+func (r *Relay) Close() error {
+	var errs []error
+	for _, sink := range r.sinks {
+		errs = append(errs, sink.Close())
+	}
+	return errors.Join(errs...)
+}
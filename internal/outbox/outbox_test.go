@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOutbox is an outbox repository keeping the events and the offsets in
+// memory.
+type memoryOutbox struct {
+	mu      sync.Mutex
+	events  []*model.OutboxEvent
+	offsets map[string]int64
+}
+
+func (m *memoryOutbox) add(eventType, aggregateID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, &model.OutboxEvent{ID: int64(len(m.events) + 1), Type: eventType, AggregateID: aggregateID})
+}
+
+func (m *memoryOutbox) RelayEvents(ctx context.Context, sink string, limit int,
+	publish func(ctx context.Context, events []*model.OutboxEvent) error) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var batch []*model.OutboxEvent
+	for _, event := range m.events {
+		if event.ID > m.offsets[sink] && len(batch) < limit {
+			batch = append(batch, event)
+		}
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := publish(ctx, batch); err != nil {
+		return 0, err
+	}
+	m.offsets[sink] = batch[len(batch)-1].ID
+	return len(batch), nil
+}
+
+func (m *memoryOutbox) PurgeEvents(context.Context, []string, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *memoryOutbox) FindUserEvents(context.Context, int64, string, int64, int) ([]*model.OutboxEvent, bool, error) {
+	return nil, false, nil
+}
+
+func eventIDs(events []*model.OutboxEvent) (ids []int64) {
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return
+}
+
+func TestRelayOnce(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		repo  = &memoryOutbox{offsets: map[string]int64{}}
+		good  = NewMemorySink("good")
+		flaky = NewMemorySink("flaky")
+		relay = NewRelay(&InitRelay{
+			OutboxRepository: repo,
+			Sinks:            []Sink{good, flaky},
+			Config:           &Config{BatchSize: 2},
+			Log:              log.New(),
+		})
+	)
+	for _, id := range []string{"DA1", "DA2", "DA3"} {
+		repo.add(model.EventOrderCreated, id)
+	}
+
+	flaky.Fail(errors.New("unreachable"))
+	published, err := relay.RelayOnce(ctx)
+	assert.ErrorContains(t, err, "sink flaky: unreachable")
+	assert.Equal(t, 3, published, "the failing sink doesn't hold the others back")
+	assert.Equal(t, []int64{1, 2, 3}, eventIDs(good.Events()))
+	assert.Empty(t, flaky.Events())
+
+	// the failed events are published again, the others aren't
+	flaky.Fail(nil)
+	repo.add(model.EventOrderCancelled, "DA1")
+	published, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 5, published)
+	assert.Equal(t, []int64{1, 2, 3, 4}, eventIDs(good.Events()))
+	assert.Equal(t, []int64{1, 2, 3, 4}, eventIDs(flaky.Events()))
+
+	published, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+}
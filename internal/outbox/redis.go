@@ -0,0 +1,82 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/kaium123/order/internal/model"
+)
+
+// RedisConfig of the Redis Streams sink.
+type RedisConfig struct {
+	Enable bool `json:"enable" yaml:"enable" toml:"enable" mapstructure:"enable"`
+	// Stream the events are added to.
+	Stream string `json:"stream" yaml:"stream" toml:"stream" mapstructure:"stream"`
+	// MaxLen trims the stream to about this many entries, 0 keeps them all.
+	MaxLen int64 `json:"max_len" yaml:"max_len" toml:"max_len" mapstructure:"max_len"`
+	// Channel the events are also published to as JSON, for the order
+	// streams of the API instances. Empty disables it.
+	Channel string `json:"channel" yaml:"channel" toml:"channel" mapstructure:"channel"`
+}
+
+// redisSink adds the events to a Redis stream, one entry per event.
+type redisSink struct {
+	client *redis.Client
+	conf   *RedisConfig
+}
+
+// NewRedisSink returns the sink adding the events to the configured Redis
+// stream.
+func NewRedisSink(client *redis.Client, conf *RedisConfig) Sink {
+	return &redisSink{client: client, conf: conf}
+}
+
+func (s *redisSink) Name() string {
+	return "redis"
+}
+
+// Publish adds the events in a single round trip, and publishes them to the
+// channel if any.
+func (s *redisSink) Publish(ctx context.Context, events []*model.OutboxEvent) error {
+	cmds, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, event := range events {
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: s.conf.Stream,
+				MaxLen: s.conf.MaxLen,
+				Approx: s.conf.MaxLen > 0,
+				Values: map[string]interface{}{
+					"id":           strconv.FormatInt(event.ID, 10),
+					"type":         event.Type,
+					"aggregate_id": event.AggregateID,
+					"payload":      string(event.Payload),
+					"created_at":   event.CreatedAt.Format(time.RFC3339Nano),
+				},
+			})
+			if s.conf.Channel != "" {
+				data, err := json.Marshal(event)
+				if err != nil {
+					return err
+				}
+				pipe.Publish(ctx, s.conf.Channel, data)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close leaves the client open, it's shared.
+func (s *redisSink) Close() error {
+	return nil
+}
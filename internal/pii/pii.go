@@ -0,0 +1,207 @@
+// Package pii masks the personally identifiable information of the values
+// tagged with `pii:"<kind>"`, e.g.
+//
+//	RecipientPhone string `json:"recipient_phone" pii:"phone"`
+package pii
+
+import (
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kinds of personally identifiable information, they select the masking
+// rule of a value.
+const (
+	Phone   = "phone"
+	Email   = "email"
+	Name    = "name"
+	Address = "address"
+	Secret  = "secret"
+)
+
+// Redacted replaces the secrets entirely.
+const Redacted = "[REDACTED]"
+
+// keyKinds maps the keys of log fields and the like to their kind.
+var keyKinds = map[string]string{
+	"phone":             Phone,
+	"recipient_phone":   Phone,
+	"email":             Email,
+	"user_name":         Email, // the user names are often emails
+	"username":          Email,
+	"name":              Name,
+	"recipient_name":    Name,
+	"address":           Address,
+	"recipient_address": Address,
+	"password":          Secret,
+	"password_hash":     Secret,
+	"token":             Secret,
+	"access_token":      Secret,
+	"refresh_token":     Secret,
+	"authorization":     Secret,
+}
+
+// KeyKind returns the kind of information a field named by given key holds,
+// false if it isn't personally identifiable.
+func KeyKind(key string) (kind string, ok bool) {
+	kind, ok = keyKinds[strings.ToLower(key)]
+	return
+}
+
+// MaskString masks given value by the rule of given kind:
+//   - phone keeps the last 3 digits, "********123"
+//   - email keeps the first letter and the domain, "j***@example.com"
+//   - name and address keep the first letter, "B***"
+//   - secret and unknown kinds are replaced entirely
+//
+// Empty values are kept empty.
+func MaskString(kind, value string) string {
+	if value == "" {
+		return ""
+	}
+
+	switch kind {
+	case Phone:
+		const keep = 3
+		if len(value) <= keep {
+			return strings.Repeat("*", len(value))
+		}
+		return strings.Repeat("*", len(value)-keep) + value[len(value)-keep:]
+	case Email:
+		at := strings.LastIndexByte(value, '@')
+		if at <= 0 {
+			return firstRune(value) + "***"
+		}
+		return firstRune(value[:at]) + "***" + value[at:]
+	case Name, Address:
+		return firstRune(value) + "***"
+	default:
+		return Redacted
+	}
+}
+
+func firstRune(s string) string {
+	_, size := utf8.DecodeRuneInString(s)
+	return s[:size]
+}
+
+// Mask returns a copy of given value with the string fields tagged pii
+// masked, through nested structs, pointers, slices and maps. The value
+// itself is left untouched.
+func Mask[T any](v T) T {
+	rv := reflect.ValueOf(&v).Elem()
+	masked, changed := mask(rv)
+	if !changed {
+		return v
+	}
+	return masked.Interface().(T)
+}
+
+// mask returns a masked copy of given value and whether anything was masked.
+// Values without pii fields are returned as is, they aren't copied.
+func mask(v reflect.Value) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, changed := mask(v.Elem())
+		if !changed {
+			return v, false
+		}
+		ptr := reflect.New(elem.Type())
+		ptr.Elem().Set(elem)
+		return ptr, true
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, changed := mask(v.Elem())
+		if !changed {
+			return v, false
+		}
+		iface := reflect.New(v.Type()).Elem()
+		iface.Set(elem)
+		return iface, true
+
+	case reflect.Struct:
+		var out reflect.Value
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			var (
+				masked  reflect.Value
+				changed bool
+			)
+			if kind, ok := field.Tag.Lookup("pii"); ok && field.Type.Kind() == reflect.String {
+				masked = reflect.ValueOf(MaskString(kind, v.Field(i).String())).Convert(field.Type)
+				changed = masked.String() != v.Field(i).String()
+			} else {
+				masked, changed = mask(v.Field(i))
+			}
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.New(v.Type()).Elem()
+				out.Set(v)
+			}
+			out.Field(i).Set(masked)
+		}
+		if !out.IsValid() {
+			return v, false
+		}
+		return out, true
+
+	case reflect.Slice, reflect.Array:
+		var out reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			masked, changed := mask(v.Index(i))
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				if v.Kind() == reflect.Slice {
+					out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				} else {
+					out = reflect.New(v.Type()).Elem()
+				}
+				reflect.Copy(out, v)
+			}
+			out.Index(i).Set(masked)
+		}
+		if !out.IsValid() {
+			return v, false
+		}
+		return out, true
+
+	case reflect.Map:
+		var out reflect.Value
+		iter := v.MapRange()
+		for iter.Next() {
+			masked, changed := mask(iter.Value())
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.MakeMapWithSize(v.Type(), v.Len())
+				copyIter := v.MapRange()
+				for copyIter.Next() {
+					out.SetMapIndex(copyIter.Key(), copyIter.Value())
+				}
+			}
+			out.SetMapIndex(iter.Key(), masked)
+		}
+		if !out.IsValid() {
+			return v, false
+		}
+		return out, true
+	}
+
+	return v, false
+}
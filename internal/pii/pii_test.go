@@ -0,0 +1,69 @@
+package pii
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskString(t *testing.T) {
+	for _, tt := range []struct {
+		kind, value, want string
+	}{
+		{Phone, "01711111234", "********234"},
+		{Phone, "12", "**"},
+		{Email, "jane@example.com", "j***@example.com"},
+		{Email, "jane", "j***"},
+		{Name, "Ácio", "Á***"},
+		{Address, "Banani, Dhaka", "B***"},
+		{Secret, "eyJhbGciOi", Redacted},
+		{"unknown", "value", Redacted},
+		{Phone, "", ""},
+	} {
+		assert.Equal(t, tt.want, MaskString(tt.kind, tt.value), tt.kind)
+	}
+}
+
+type recipient struct {
+	Name  string `pii:"name"`
+	Phone string `pii:"phone"`
+	City  int64
+}
+
+type order struct {
+	ID         int64
+	Recipient  recipient
+	Recipients []*recipient
+	Notes      map[string]recipient
+	Token      string `pii:"secret"`
+}
+
+func TestMask(t *testing.T) {
+	original := &order{
+		ID:         1,
+		Recipient:  recipient{Name: "Jane", Phone: "01711111234", City: 1},
+		Recipients: []*recipient{{Name: "John", Phone: "01811111234"}, nil},
+		Notes:      map[string]recipient{"a": {Phone: "01911111234"}},
+		Token:      "secret",
+	}
+
+	masked := Mask(original)
+
+	assert.Equal(t, &order{
+		ID:         1,
+		Recipient:  recipient{Name: "J***", Phone: "********234", City: 1},
+		Recipients: []*recipient{{Name: "J***", Phone: "********234"}, nil},
+		Notes:      map[string]recipient{"a": {Phone: "********234"}},
+		Token:      Redacted,
+	}, masked)
+
+	// the original is left untouched
+	assert.Equal(t, "01711111234", original.Recipient.Phone)
+	assert.Equal(t, "01811111234", original.Recipients[0].Phone)
+	assert.Equal(t, "01911111234", original.Notes["a"].Phone)
+
+	// values without pii fields are returned as is
+	var iface any = recipient{Name: "Jane"}
+	assert.Equal(t, recipient{Name: "J***"}, Mask(iface))
+	assert.Equal(t, 42, Mask(42))
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/kaium123/order/internal/encryption"
 	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/metrics"
 	"github.com/kaium123/order/internal/model"
+	"slices"
 	"time"
 )
 
@@ -18,6 +21,12 @@ type IRedisCache interface {
 	GetToken(ctx context.Context, key string) (string, error)
 	DeleteKey(ctx context.Context, key string) error
 	FindAllOrders(ctx context.Context, req *model.FindAllRequest) ([]model.Order, error)
+	// MarkWrite records a write of given user for given window, see
+	// WroteRecently.
+	MarkWrite(ctx context.Context, userID int64, window time.Duration) error
+	// WroteRecently reports whether given user wrote within the window of
+	// their last MarkWrite.
+	WroteRecently(ctx context.Context, userID int64) (bool, error)
 }
 
 type InitRedisCache struct {
@@ -42,14 +51,24 @@ func NewRedisCache(initRedisCache *InitRedisCache) IRedisCache {
 func (t *redisCache) CacheOrder(ctx context.Context, order model.Order) error {
 	orderKey := fmt.Sprintf("order:%s", order.OrderConsignmentID) // Use order ID as key
 
+	// The recipient details are kept encrypted, as in the database
+	recipientPhone, err := encryption.Default().Encrypt(string(order.RecipientPhone))
+	if err != nil {
+		return err
+	}
+	recipientAddress, err := encryption.Default().Encrypt(string(order.RecipientAddress))
+	if err != nil {
+		return err
+	}
+
 	// Store the order details as a Redis hash
-	result, err := t.client.HSet(ctx, orderKey, map[string]interface{}{
+	_, err = t.client.HSet(ctx, orderKey, map[string]interface{}{
 		"id":                   order.ID,
 		"store_id":             order.StoreID,
 		"merchant_order_id":    order.MerchantOrderID,
 		"recipient_name":       order.RecipientName,
-		"recipient_phone":      order.RecipientPhone,
-		"recipient_address":    order.RecipientAddress,
+		"recipient_phone":      recipientPhone,
+		"recipient_address":    recipientAddress,
 		"recipient_city":       order.RecipientCity,
 		"recipient_zone":       order.RecipientZone,
 		"recipient_area":       order.RecipientArea,
@@ -79,8 +98,6 @@ func (t *redisCache) CacheOrder(ctx context.Context, order model.Order) error {
 		return err
 	}
 
-	fmt.Println("cache store result ", result)
-
 	// Add the order to the sorted set with CreatedAt as the score
 	zAddErr := t.client.ZAdd(ctx, "orders", &redis.Z{
 		Score:  float64(order.CreatedAt.Unix()),
@@ -124,7 +141,7 @@ func (r *redisCache) InvalidateSession(ctx context.Context, userID int64) error
 	err := r.client.Del(ctx, sessionKey).Err()
 	if err != nil {
 		// Log the error if invalidating the session fails
-		r.log.Error(ctx, fmt.Sprintf("Failed to invalidate session for user %s: %v", userID, err))
+		r.log.Error(ctx, fmt.Sprintf("Failed to invalidate session for user %d: %v", userID, err))
 		return fmt.Errorf("failed to invalidate session")
 	}
 
@@ -136,7 +153,7 @@ func (r *redisCache) InvalidateSession(ctx context.Context, userID int64) error
 func (r *redisCache) StoreToken(ctx context.Context, key string, token string, expiry time.Duration) error {
 	err := r.client.Set(ctx, key, token, expiry).Err()
 	if err != nil {
-		r.log.Error(ctx, fmt.Sprintf("Failed to store access token.", err))
+		r.log.Error(ctx, fmt.Sprintf("Failed to store access token: %v", err))
 		return fmt.Errorf("failed to store access token: %w", err)
 	}
 	return nil
@@ -146,12 +163,14 @@ func (r *redisCache) StoreToken(ctx context.Context, key string, token string, e
 func (r *redisCache) GetToken(ctx context.Context, key string) (string, error) {
 	token, err := r.client.Get(ctx, key).Result()
 	if err == redis.Nil {
-		r.log.Error(ctx, fmt.Sprintf("Access token not found"))
+		metrics.CacheLookups.WithLabelValues("token", metrics.CacheMiss).Inc()
+		r.log.Error(ctx, "Access token not found")
 		return "", nil
 	} else if err != nil {
-		r.log.Error(ctx, fmt.Sprintf("Failed to retrieve access token ", err))
+		r.log.Error(ctx, fmt.Sprintf("Failed to retrieve access token: %v", err))
 		return "", fmt.Errorf("failed to retrieve access token: %w", err)
 	}
+	metrics.CacheLookups.WithLabelValues("token", metrics.CacheHit).Inc()
 	return token, nil
 }
 
@@ -169,6 +188,25 @@ func (r *redisCache) DeleteKey(ctx context.Context, key string) error {
 	return nil
 }
 
+// MarkWrite records a write of given user, expiring after given window.
+func (r *redisCache) MarkWrite(ctx context.Context, userID int64, window time.Duration) error {
+	if err := r.client.Set(ctx, fmt.Sprintf("wrote:%d", userID), 1, window).Err(); err != nil {
+		r.log.Error(ctx, fmt.Sprintf("Failed to mark the write of user %d: %v", userID, err))
+		return fmt.Errorf("failed to mark the write: %w", err)
+	}
+	return nil
+}
+
+// WroteRecently reports whether the write mark of given user is still there.
+func (r *redisCache) WroteRecently(ctx context.Context, userID int64) (bool, error) {
+	n, err := r.client.Exists(ctx, fmt.Sprintf("wrote:%d", userID)).Result()
+	if err != nil {
+		r.log.Error(ctx, fmt.Sprintf("Failed to look up the write of user %d: %v", userID, err))
+		return false, fmt.Errorf("failed to look up the write: %w", err)
+	}
+	return n > 0, nil
+}
+
 // FindAllOrders retrieves orders from Redis based on the given filter and paginates them.
 func (t *redisCache) FindAllOrders(ctx context.Context, req *model.FindAllRequest) ([]model.Order, error) {
 	// Fetch order consignment IDs from the sorted set with score-based pagination (limit and offset)
@@ -195,6 +233,11 @@ func (t *redisCache) FindAllOrders(ctx context.Context, req *model.FindAllReques
 			t.log.Error(ctx, fmt.Sprintf("Error fetching order data for consignment ID %s: %v", z.Member, err))
 			continue
 		}
+		if len(orderData) == 0 {
+			metrics.CacheLookups.WithLabelValues("order", metrics.CacheMiss).Inc()
+			continue
+		}
+		metrics.CacheLookups.WithLabelValues("order", metrics.CacheHit).Inc()
 
 		// Convert the hash data to an order object
 		order := model.Order{
@@ -205,8 +248,8 @@ func (t *redisCache) FindAllOrders(ctx context.Context, req *model.FindAllReques
 			UpdatedAt: parseTime(orderData["updated_at"]),
 		}
 
-		// Apply filtering if an order status is specified
-		if req.TransferStatus != "" && order.OrderStatus.String() != req.TransferStatus {
+		// Apply filtering if order statuses are specified
+		if len(req.OrderStatuses) > 0 && !slices.Contains(req.OrderStatuses, order.OrderStatus) {
 			continue // Skip this order if it doesn't match the filter
 		}
 
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/uptrace/bun"
+)
+
+// IDelivery is the repository of the delivery attempts of the orders.
+type IDelivery interface {
+	// RecordAttempt records the delivery attempt of req, along with its
+	// order.delivery_attempted event. A delivered attempt hands the order
+	// over to the recipient. The failed attempt reaching the maximum of
+	// given rules returns the order to the merchant, charged the RTO fee of
+	// given pricing, along with its order.returned event. It returns
+	// model.ErrNotFound for an order out of reach and model.ErrInvalidAttempt
+	// for an order which isn't with a rider.
+	RecordAttempt(ctx context.Context, req *model.AttemptRequest, rules model.DeliveryRules, pricing model.Pricing) (*model.DeliveryAttempt, error)
+	// FindAttempts returns the delivery attempts of an order, the oldest
+	// first.
+	FindAttempts(ctx context.Context, orderID int64) ([]*model.DeliveryAttempt, error)
+}
+
+type InitDeliveryRepository struct {
+	Db  *db.DB
+	Log *log.Logger
+}
+
+type DeliveryReceiver struct {
+	log *log.Logger
+	db  *db.DB
+}
+
+// NewDelivery returns a new instance of the Delivery repository.
+func NewDelivery(initDeliveryRepository *InitDeliveryRepository) IDelivery {
+	return &DeliveryReceiver{
+		log: initDeliveryRepository.Log,
+		db:  initDeliveryRepository.Db,
+	}
+}
+
+func (d *DeliveryReceiver) RecordAttempt(ctx context.Context, req *model.AttemptRequest, rules model.DeliveryRules, pricing model.Pricing) (*model.DeliveryAttempt, error) {
+	var attempt *model.DeliveryAttempt
+	err := d.db.InTx(ctx, func(ctx context.Context, r model.Repository) error {
+		tx := bunTx(r)
+
+		// the order is locked so the attempts are numbered one after another
+		order := new(model.Order)
+		query := tx.NewSelect().Model(order).
+			Where("order_consignment_id = ?", req.ConsignmentID).
+			For("UPDATE")
+		if req.OwnerId != 0 {
+			query.Where("user_id = ?", req.OwnerId)
+		}
+		err := query.Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if order.TransferStatus != model.WithRider || order.OrderStatus == model.ReturnToOrigin {
+			return fmt.Errorf("%w: the %s order is %s, only the orders out for delivery are attempted", model.ErrInvalidAttempt, order.OrderStatus, order.TransferStatus)
+		}
+
+		var attempts, failed int
+		err = tx.NewSelect().
+			Model((*model.DeliveryAttempt)(nil)).
+			ColumnExpr("count(*)").
+			ColumnExpr("count(*) FILTER (WHERE outcome = ?)", model.AttemptFailed).
+			Where("order_id = ?", order.ID).
+			Scan(ctx, &attempts, &failed)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		attempt = &model.DeliveryAttempt{
+			OrderID:       order.ID,
+			Attempt:       attempts + 1,
+			Outcome:       req.Outcome,
+			FailureReason: req.FailureReason,
+			RiderRef:      req.RiderRef,
+			RiderNotes:    req.RiderNotes,
+			RecordedBy:    req.UserId,
+			AttemptedAt:   req.AttemptedAt.UTC(),
+			CreatedAt:     now,
+		}
+		if _, err := tx.NewInsert().Model(attempt).Exec(ctx); err != nil {
+			return err
+		}
+
+		event, err := model.NewOutboxEvent(model.EventDeliveryAttempted, order.OrderConsignmentID, &model.DeliveryAttemptedEvent{
+			ConsignmentID: order.OrderConsignmentID,
+			StoreID:       order.StoreID,
+			UserID:        order.UserID,
+			Attempt:       attempt.Attempt,
+			Outcome:       attempt.Outcome,
+			FailureReason: attempt.FailureReason,
+			AttemptedAt:   attempt.AttemptedAt,
+		})
+		if err != nil {
+			return err
+		}
+		events := []*model.OutboxEvent{event}
+
+		switch {
+		case attempt.Outcome == model.AttemptDelivered:
+			// the rider hands the order over to the recipient
+			handedOver, err := handOver(ctx, tx, order, &model.OrderHandover{
+				OrderID:     order.ID,
+				From:        order.TransferStatus,
+				To:          model.WithRecipient,
+				ReceivedBy:  order.RecipientName,
+				ReceiverRef: req.RiderRef,
+				Note:        "delivered on attempt " + strconv.Itoa(attempt.Attempt),
+				RecordedBy:  req.UserId,
+				CreatedAt:   now,
+			})
+			if err != nil {
+				return err
+			}
+			events = append(events, handedOver...)
+		case failed+1 >= rules.MaxFailedAttempts:
+			returned, err := returnOrder(ctx, tx, order, failed+1, pricing, now)
+			if err != nil {
+				return err
+			}
+			events = append(events, returned...)
+		}
+		return insertEvents(ctx, tx, events...)
+	})
+	if err != nil {
+		if !errors.Is(err, model.ErrNotFound) && !errors.Is(err, model.ErrInvalidAttempt) {
+			d.log.Error(ctx, err.Error())
+		}
+		return nil, err
+	}
+
+	return attempt, nil
+}
+
+// returnOrder moves given order, locked, to the return status and charges
+// the RTO fee. It returns the order.status_changed and order.returned
+// events.
+func returnOrder(ctx context.Context, tx bun.IDB, order *model.Order, failed int, pricing model.Pricing, now time.Time) ([]*model.OutboxEvent, error) {
+	from := order.OrderStatus
+	order.ApplyRTO(pricing)
+	order.OrderStatus = model.ReturnToOrigin
+	// the order status is stored as its number
+	_, err := tx.NewUpdate().Model((*model.Order)(nil)).
+		Set("order_status = ?", strconv.Itoa(int(order.OrderStatus))).
+		Set("rto_fee = ?", order.RTOFee).
+		Set("cod_fee = ?", order.CodFee).
+		Set("total_fee = ?", order.TotalFee).
+		Set("updated_at = ?", now).
+		Where("id = ?", order.ID).
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statusChanged, err := model.NewOutboxEvent(model.EventOrderStatusChanged, order.OrderConsignmentID, &model.OrderStatusChangedEvent{
+		ConsignmentID: order.OrderConsignmentID,
+		StoreID:       order.StoreID,
+		UserID:        order.UserID,
+		From:          from.String(),
+		To:            order.OrderStatus.String(),
+		ChangedAt:     now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	returned, err := model.NewOutboxEvent(model.EventOrderReturned, order.OrderConsignmentID, &model.OrderReturnedEvent{
+		ConsignmentID:  order.OrderConsignmentID,
+		StoreID:        order.StoreID,
+		UserID:         order.UserID,
+		FailedAttempts: failed,
+		RTOFee:         order.RTOFee,
+		TotalFee:       order.TotalFee,
+		ReturnedAt:     now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []*model.OutboxEvent{statusChanged, returned}, nil
+}
+
+// failedSincePickup reports whether the rider holding given order failed to
+// deliver it since they picked it up.
+func failedSincePickup(ctx context.Context, tx bun.IDB, orderID int64) (bool, error) {
+	pickedUp := tx.NewSelect().
+		Model((*model.OrderHandover)(nil)).
+		ColumnExpr("max(created_at)").
+		Where("order_id = ?", orderID).
+		Where("to_status = ?", model.WithRider)
+	return tx.NewSelect().
+		Model((*model.DeliveryAttempt)(nil)).
+		Where("order_id = ?", orderID).
+		Where("outcome = ?", model.AttemptFailed).
+		Where("created_at >= (?)", pickedUp).
+		Exists(ctx)
+}
+
+func (d *DeliveryReceiver) FindAttempts(ctx context.Context, orderID int64) ([]*model.DeliveryAttempt, error) {
+	attempts := []*model.DeliveryAttempt{}
+	err := d.db.NewSelect().
+		Model(&attempts).
+		Where("order_id = ?", orderID).
+		Order("attempt").
+		Scan(ctx)
+	if err != nil {
+		d.log.Error(ctx, err.Error())
+		return nil, err
+	}
+	return attempts, nil
+}
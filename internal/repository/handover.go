@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/uptrace/bun"
+)
+
+// IHandover is the repository of the handovers of the orders.
+type IHandover interface {
+	// RecordHandover hands the order of req over to given transfer status,
+	// along with its order.handed_over event and, once the order status
+	// follows, its order.status_changed event. It returns model.ErrNotFound
+	// for an order out of reach, model.ErrForbiddenHandover for a handover
+	// other than the pickup restricted by req.PickupOnly and
+	// model.ErrInvalidHandover for a handover the order can't take.
+	RecordHandover(ctx context.Context, req *model.HandoverRequest, to model.TransferStatus) (*model.OrderHandover, error)
+	// FindHandovers returns the handovers of an order, the oldest first.
+	FindHandovers(ctx context.Context, req *model.FindHandoversRequest) ([]*model.OrderHandover, error)
+}
+
+type InitHandoverRepository struct {
+	Db  *db.DB
+	Log *log.Logger
+}
+
+type HandoverReceiver struct {
+	log *log.Logger
+	db  *db.DB
+}
+
+// NewHandover returns a new instance of the Handover repository.
+func NewHandover(initHandoverRepository *InitHandoverRepository) IHandover {
+	return &HandoverReceiver{
+		log: initHandoverRepository.Log,
+		db:  initHandoverRepository.Db,
+	}
+}
+
+func (h *HandoverReceiver) RecordHandover(ctx context.Context, req *model.HandoverRequest, to model.TransferStatus) (*model.OrderHandover, error) {
+	var handover *model.OrderHandover
+	err := h.db.InTx(ctx, func(ctx context.Context, r model.Repository) error {
+		tx := bunTx(r)
+
+		// the order is locked so concurrent handovers follow one another
+		order := new(model.Order)
+		query := tx.NewSelect().Model(order).
+			Where("order_consignment_id = ?", req.ConsignmentID).
+			For("UPDATE")
+		if req.OwnerId != 0 {
+			query.Where("user_id = ?", req.OwnerId)
+		}
+		err := query.Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if req.PickupOnly && (order.TransferStatus != model.AtMerchant || to != model.AtHub) {
+			return model.ErrForbiddenHandover
+		}
+		if !order.CanHandOverTo(to) {
+			return fmt.Errorf("%w: the %s order is %s, it can't be handed over to %s", model.ErrInvalidHandover, order.OrderStatus, order.TransferStatus, to)
+		}
+		if order.TransferStatus == model.WithRider {
+			failed, err := failedSincePickup(ctx, tx, order.ID)
+			if err != nil {
+				return err
+			}
+			if !failed {
+				return fmt.Errorf("%w: the rider hands the order back to the hub after a failed delivery attempt only", model.ErrInvalidHandover)
+			}
+		}
+
+		handover = &model.OrderHandover{
+			OrderID:     order.ID,
+			From:        order.TransferStatus,
+			To:          to,
+			ReceivedBy:  req.ReceivedBy,
+			ReceiverRef: req.ReceiverRef,
+			Note:        req.Note,
+			RecordedBy:  req.UserId,
+			CreatedAt:   time.Now().UTC(),
+		}
+		events, err := handOver(ctx, tx, order, handover)
+		if err != nil {
+			return err
+		}
+		return insertEvents(ctx, tx, events...)
+	})
+	if err != nil {
+		if !errors.Is(err, model.ErrNotFound) && !errors.Is(err, model.ErrInvalidHandover) && !errors.Is(err, model.ErrForbiddenHandover) {
+			h.log.Error(ctx, err.Error())
+		}
+		return nil, err
+	}
+
+	return handover, nil
+}
+
+// handOver moves given order, locked, to the transfer status of given
+// handover and records it. It returns the order.handed_over event and, once
+// the order status follows, the order.status_changed event.
+func handOver(ctx context.Context, tx bun.IDB, order *model.Order, handover *model.OrderHandover) ([]*model.OutboxEvent, error) {
+	status := order.HandedOverStatus(handover.To)
+	// the order status is stored as its number
+	_, err := tx.NewUpdate().Model((*model.Order)(nil)).
+		Set("transfer_status = ?", handover.To).
+		Set("order_status = ?", strconv.Itoa(int(status))).
+		Set("updated_at = ?", handover.CreatedAt).
+		Where("id = ?", order.ID).
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.NewInsert().Model(handover).Returning("*").Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	event, err := model.NewOutboxEvent(model.EventOrderHandedOver, order.OrderConsignmentID, &model.OrderHandedOverEvent{
+		ConsignmentID: order.OrderConsignmentID,
+		StoreID:       order.StoreID,
+		UserID:        order.UserID,
+		From:          handover.From.String(),
+		To:            handover.To.String(),
+		ReceiverRef:   handover.ReceiverRef,
+		HandedOverAt:  handover.CreatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	events := []*model.OutboxEvent{event}
+	if status != order.OrderStatus {
+		event, err := model.NewOutboxEvent(model.EventOrderStatusChanged, order.OrderConsignmentID, &model.OrderStatusChangedEvent{
+			ConsignmentID: order.OrderConsignmentID,
+			StoreID:       order.StoreID,
+			UserID:        order.UserID,
+			From:          order.OrderStatus.String(),
+			To:            status.String(),
+			ChangedAt:     handover.CreatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	order.TransferStatus, order.OrderStatus = handover.To, status
+	return events, nil
+}
+
+func (h *HandoverReceiver) FindHandovers(ctx context.Context, req *model.FindHandoversRequest) ([]*model.OrderHandover, error) {
+	order := h.db.NewSelect().
+		Model((*model.Order)(nil)).
+		Column("id").
+		Where("order_consignment_id = ?", req.ConsignmentID)
+	if req.OwnerId != 0 {
+		order.Where("user_id = ?", req.OwnerId)
+	}
+	var orderID int64
+	err := order.Scan(ctx, &orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		h.log.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	handovers := []*model.OrderHandover{}
+	err = h.db.NewSelect().
+		Model(&handovers).
+		Where("order_id = ?", orderID).
+		Order("id").
+		Scan(ctx)
+	if err != nil {
+		h.log.Error(ctx, err.Error())
+		return nil, err
+	}
+	return handovers, nil
+}
@@ -3,9 +3,18 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/internal/encryption"
 	"github.com/kaium123/order/internal/log"
 	"github.com/kaium123/order/internal/model"
+	"github.com/uptrace/bun"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,7 +22,25 @@ import (
 type IOrder interface {
 	CreateOrder(ctx context.Context, order *model.Order) (*model.Order, error)
 	FindAllOrders(ctx context.Context, req *model.FindAllRequest) ([]*model.Order, *model.PaginationResponse, error)
+	SearchOrders(ctx context.Context, req *model.SearchOrdersRequest) ([]*model.OrderSearchRow, error)
+	// FindOrder returns the order of a user, 0 for any user, having given
+	// consignment ID, or model.ErrNotFound. It reads the primary.
+	FindOrder(ctx context.Context, userID int64, consignmentID string) (*model.Order, error)
+	// UpdateOrder writes the edition of an order at given version, along with
+	// its history and its order.updated event, and bumps the version. It
+	// returns model.ErrVersionConflict once the order changed since, and
+	// model.ErrNotEditable once it was picked up.
+	UpdateOrder(ctx context.Context, order *model.Order, version int, changedBy int64, changes []model.OrderChange) error
+	// FindOrderHistory returns the editions of an order, the oldest first.
+	FindOrderHistory(ctx context.Context, req *model.FindHistoryRequest) ([]*model.OrderHistory, error)
+	// CancelOrder cancels an order of a user, it returns model.ErrNotFound,
+	// model.ErrAlreadyCancelled or model.ErrNotCancellable for an order
+	// which can't be.
 	CancelOrder(ctx context.Context, req *model.OrderCancelRequest) error
+	ArchiveOrders(ctx context.Context, req *model.ArchiveRequest) (int, error)
+	ArchiveCompletedOrders(ctx context.Context, before time.Time, limit int) (int, error)
+	MerchantOrderExists(ctx context.Context, userID int64, merchantOrderID string) (bool, error)
+	ReencryptOrders(ctx context.Context, afterID int64, limit int) (lastID int64, updated int, err error)
 }
 
 type InitOrderRepository struct {
@@ -34,11 +61,38 @@ func NewOrder(initOrderRepository *InitOrderRepository) IOrder {
 	}
 }
 
-// CreateOrder creates a new order in the database.
+// CreateOrder creates a new order in the database, along with its
+// order.created event.
 func (o *OrderReceiver) CreateOrder(ctx context.Context, order *model.Order) (*model.Order, error) {
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = time.Now().UTC()
+	}
+
+	err := o.db.InTx(ctx, func(ctx context.Context, r model.Repository) error {
+		tx := bunTx(r)
+
+		// Use the database transaction to insert the order
+		if _, err := tx.NewInsert().Model(order).Exec(ctx); err != nil {
+			return err
+		}
 
-	// Use the database connection to insert the order
-	_, err := o.db.NewInsert().Model(order).Exec(ctx)
+		event, err := model.NewOutboxEvent(model.EventOrderCreated, order.OrderConsignmentID, &model.OrderCreatedEvent{
+			ConsignmentID:   order.OrderConsignmentID,
+			MerchantOrderID: order.MerchantOrderID,
+			StoreID:         order.StoreID,
+			UserID:          order.UserID,
+			RecipientCity:   order.RecipientCity,
+			OrderStatus:     order.OrderStatus.String(),
+			AmountToCollect: order.AmountToCollect,
+			DeliveryFee:     order.DeliveryFee,
+			TotalFee:        order.TotalFee,
+			CreatedAt:       order.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		return insertEvents(ctx, tx, event)
+	})
 	if err != nil {
 		o.log.Error(ctx, err.Error())
 		return nil, err
@@ -47,54 +101,689 @@ func (o *OrderReceiver) CreateOrder(ctx context.Context, order *model.Order) (*m
 	return order, nil
 }
 
+// FindAllOrders lists the orders of a user from a read replica, they may lag
+// behind the writes unless ctx is marked by db.WithPrimary.
 func (o *OrderReceiver) FindAllOrders(ctx context.Context, req *model.FindAllRequest) ([]*model.Order, *model.PaginationResponse, error) {
-	// Step 1: Query for paginated data
-	orders := []*model.Order{}
-	query := o.db.NewSelect().
+	// Step 1: Query for the orders matching the filters, the count reads the
+	// same database
+	reader := o.db.Reader(ctx)
+	query := reader.NewSelect().
 		Model((*model.Order)(nil)).
+		Where("user_id = ? and deleted_at is null ", req.UserId)
+	applyOrderFilters(query, req)
+
+	// Step 2: Count them, unless skipped
+	paginationResponse := &model.PaginationResponse{PerPage: req.Limit}
+	switch req.Count {
+	case model.CountNone:
+	case model.CountEstimate:
+		total, err := estimateCount(ctx, reader, query)
+		if err != nil {
+			o.log.Error(ctx, err.Error())
+			return nil, nil, err
+		}
+		paginationResponse.Total = total
+		paginationResponse.TotalEstimated = true
+	default:
+		total, err := query.Count(ctx)
+		if err != nil {
+			o.log.Error(ctx, err.Error())
+			return nil, nil, err
+		}
+		paginationResponse.Total = total
+	}
+
+	// Step 3: Query for the page
+	var (
+		orders []*model.Order
+		err    error
+	)
+	if req.Keyset {
+		orders, err = o.findOrdersAfter(ctx, query, req, paginationResponse)
+	} else {
+		orders, err = o.findOrdersPage(ctx, query, req, paginationResponse)
+	}
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return nil, nil, err
+	}
+	paginationResponse.TotalInPage = len(orders)
+
+	return orders, paginationResponse, nil
+}
+
+// findOrdersPage returns the page of the orders at req.Offset, sorted by
+// req.Sort.
+func (o *OrderReceiver) findOrdersPage(ctx context.Context, query *bun.SelectQuery, req *model.FindAllRequest, paginationResponse *model.PaginationResponse) ([]*model.Order, error) {
+	// Sort, by ID last so the pages are stable
+	direction := bun.Safe("DESC")
+	if req.Order == "asc" {
+		direction = bun.Safe("ASC")
+	}
+	column, ok := model.OrderSortColumns[req.Sort]
+	if !ok {
+		column = "created_at"
+	}
+
+	orders := []*model.Order{}
+	err := query.OrderExpr("? ?, id ?", bun.Ident(column), direction, direction).
 		Limit(req.Limit).
 		Offset(req.Offset).
-		Where("user_id = ? and deleted_at is null ", req.UserId)
+		Scan(ctx, &orders)
+	if err != nil {
+		return nil, err
+	}
+
+	paginationResponse.CurrentPage = req.Offset/req.Limit + 1
+	paginationResponse.LastPage = (paginationResponse.Total + req.Limit - 1) / req.Limit
+	return orders, nil
+}
 
-	if req.TransferStatus != "" {
-		query.Where("transfer_status = ?", req.TransferStatus)
+// findOrdersAfter returns the orders after, or before, req.Cursor by
+// (created_at, id), and the cursors of the pages around them. The deep pages
+// cost as much as the first ones, unlike with an offset.
+func (o *OrderReceiver) findOrdersAfter(ctx context.Context, query *bun.SelectQuery, req *model.FindAllRequest, paginationResponse *model.PaginationResponse) ([]*model.Order, error) {
+	backward := req.Cursor != nil && req.Cursor.Before
+	// the orders are scanned from the cursor, so backwards in reverse order
+	ascending := (req.Order == "asc") != backward
+	direction, comparison := bun.Safe("DESC"), bun.Safe("<")
+	if ascending {
+		direction, comparison = bun.Safe("ASC"), bun.Safe(">")
 	}
-	if req.Archive != 0 {
-		query.Where("archive = ?", req.Archive)
+	if req.Cursor != nil {
+		query.Where("(created_at, id) ? (?, ?)", comparison, req.Cursor.CreatedAt, req.Cursor.ID)
 	}
 
-	query.Order("created_at DESC")
-	total, err := query.ScanAndCount(ctx, &orders)
+	// one more order tells whether there are more past the page
+	orders := []*model.Order{}
+	err := query.OrderExpr("created_at ?, id ?", direction, direction).
+		Limit(req.Limit+1).
+		Scan(ctx, &orders)
 	if err != nil {
+		return nil, err
+	}
+	more := len(orders) > req.Limit
+	if more {
+		orders = orders[:req.Limit]
+	}
+	if backward {
+		slices.Reverse(orders)
+	}
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	// the page the client came from is always there
+	if (more && !backward) || (backward && req.Cursor != nil) {
+		paginationResponse.NextCursor = model.NewOrderCursor(orders[len(orders)-1], false, req.Order).Encode()
+	}
+	if (more && backward) || (!backward && req.Cursor != nil) {
+		paginationResponse.PrevCursor = model.NewOrderCursor(orders[0], true, req.Order).Encode()
+	}
+	return orders, nil
+}
+
+// estimateCount returns the planner estimate of the number of rows of given
+// query on given database, derived from the table statistics in pg_class and
+// pg_statistic. It doesn't scan the rows, but it's only as fresh as the last
+// ANALYZE.
+func estimateCount(ctx context.Context, reader bun.IDB, query *bun.SelectQuery) (int, error) {
+	var explain string
+	if err := reader.NewRaw("EXPLAIN (FORMAT JSON) ?", query).Scan(ctx, &explain); err != nil {
+		return 0, err
+	}
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(explain), &plans); err != nil {
+		return 0, err
+	}
+	if len(plans) == 0 {
+		return 0, fmt.Errorf("no plan to estimate the count from")
+	}
+	return int(plans[0].Plan.Rows), nil
+}
+
+// SearchOrders finds the orders matching req.Query by the words of the
+// recipient name, the merchant order ID and the description, by substrings
+// and typos of the recipient name and the merchant order ID, and by prefix of
+// the consignment ID, the most relevant first. The recipient addresses are
+// only searched while they aren't encrypted.
+func (o *OrderReceiver) SearchOrders(ctx context.Context, req *model.SearchOrdersRequest) ([]*model.OrderSearchRow, error) {
+	var (
+		pattern       = "%" + escapeLike(req.Query) + "%"
+		searchAddress = !encryption.Default().Enabled()
+		// the best of the full-text rank and the similarities of the fields
+		addressRank = bun.SafeQuery("0")
+	)
+	if searchAddress {
+		addressRank = bun.SafeQuery("word_similarity(?, recipient_address)", req.Query)
+	}
+
+	rows := []*model.OrderSearchRow{}
+	query := o.db.Reader(ctx).NewSelect().
+		Model(&rows).
+		ColumnExpr("?TableColumns").
+		ColumnExpr("greatest(ts_rank(search_vector, websearch_to_tsquery('simple', ?)), word_similarity(?, recipient_name), word_similarity(?, merchant_order_id), ?) AS rank",
+			req.Query, req.Query, req.Query, addressRank).
+		WhereGroup(" AND ", func(query *bun.SelectQuery) *bun.SelectQuery {
+			query.WhereOr("search_vector @@ websearch_to_tsquery('simple', ?)", req.Query).
+				WhereOr("recipient_name ILIKE ?", pattern).
+				WhereOr("? <% recipient_name", req.Query).
+				WhereOr("merchant_order_id ILIKE ?", pattern).
+				WhereOr("order_consignment_id LIKE ?", strings.ToUpper(escapeLike(req.Query))+"%")
+			if searchAddress {
+				query.WhereOr("recipient_address ILIKE ?", pattern).
+					WhereOr("? <% recipient_address", req.Query)
+			}
+			return query
+		}).
+		OrderExpr("rank DESC, id DESC").
+		Limit(req.Limit).
+		Offset(req.Offset)
+	if req.UserId != 0 {
+		query.Where("user_id = ?", req.UserId)
+	}
+	if req.StoreID != 0 {
+		query.Where("store_id = ?", req.StoreID)
+	}
+
+	if err := query.Scan(ctx); err != nil {
 		o.log.Error(ctx, err.Error())
-		return nil, nil, err
+		return nil, err
 	}
+	return rows, nil
+}
 
-	// Step 3: Calculate pagination metadata
-	currentPage := req.Offset/req.Limit + 1
-	lastPage := (total + req.Limit - 1) / req.Limit
-	totalInPage := len(orders)
+// escapeLike escapes the wildcards of given LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
 
-	paginationResponse := &model.PaginationResponse{
-		Total:       total,
-		CurrentPage: currentPage,
-		PerPage:     req.Limit,
-		TotalInPage: totalInPage,
-		LastPage:    lastPage,
+// applyOrderFilters narrows given query of the orders to the filters of req.
+func applyOrderFilters(query *bun.SelectQuery, req *model.FindAllRequest) {
+	if req.TransferStatus != nil {
+		query.Where("transfer_status = ?", *req.TransferStatus)
+	}
+	if req.Archive != nil {
+		query.Where("archive = ?", *req.Archive)
+	}
+	if len(req.OrderStatuses) > 0 {
+		// stored as the number of the status in a VARCHAR column
+		statuses := make([]string, len(req.OrderStatuses))
+		for i, status := range req.OrderStatuses {
+			statuses[i] = strconv.Itoa(int(status))
+		}
+		query.Where("order_status IN (?)", bun.In(statuses))
+	}
+	if req.StoreID != 0 {
+		query.Where("store_id = ?", req.StoreID)
 	}
+	if req.RecipientCity != 0 {
+		query.Where("recipient_city = ?", req.RecipientCity)
+	}
+	if req.RecipientZone != 0 {
+		query.Where("recipient_zone = ?", req.RecipientZone)
+	}
+	if req.RecipientArea != 0 {
+		query.Where("recipient_area = ?", req.RecipientArea)
+	}
+	if !req.CreatedFrom.IsZero() {
+		query.Where("created_at >= ?", req.CreatedFrom)
+	}
+	if !req.CreatedBefore.IsZero() {
+		query.Where("created_at < ?", req.CreatedBefore)
+	}
+	if req.CODMin != nil {
+		query.Where("amount_to_collect >= ?", *req.CODMin)
+	}
+	if req.CODMax != nil {
+		query.Where("amount_to_collect <= ?", *req.CODMax)
+	}
+	if req.Phone != "" {
+		// the encrypted phones are only found whole, by their blind index
+		if keyring := encryption.Default(); keyring.Enabled() {
+			query.Where("recipient_phone_hash = ?", keyring.BlindIndex(req.Phone))
+		} else {
+			query.Where("recipient_phone LIKE ?", req.Phone+"%")
+		}
+	}
+	if req.ConsignmentID != "" {
+		query.Where("order_consignment_id LIKE ?", req.ConsignmentID+"%")
+	}
+}
 
-	return orders, paginationResponse, nil
+// FindOrder returns the order of a user having given consignment ID, read
+// from the primary as it's edited after.
+func (o *OrderReceiver) FindOrder(ctx context.Context, userID int64, consignmentID string) (*model.Order, error) {
+	order := new(model.Order)
+	query := o.db.Primary().NewSelect().
+		Model(order).
+		Where("order_consignment_id = ?", consignmentID)
+	if userID != 0 {
+		query.Where("user_id = ?", userID)
+	}
+	err := query.Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return nil, err
+	}
+	return order, nil
 }
 
-func (o *OrderReceiver) CancelOrder(ctx context.Context, req *model.OrderCancelRequest) error {
-	_, err := o.db.NewUpdate().Model((*model.Order)(nil)).
-		Set("deleted_at = ?", time.Now().UTC()).
-		Where("order_consignment_id = ? and user_id = ?", req.ConsignmentID, req.UserId).
-		Exec(ctx)
+// encryptedChanges are the fields whose changes are sealed in the history,
+// as their order columns.
+var encryptedChanges = map[string]bool{
+	"recipient_phone":   true,
+	"recipient_address": true,
+}
+
+// UpdateOrder writes the editable fields and the fees of given order.
+func (o *OrderReceiver) UpdateOrder(ctx context.Context, order *model.Order, version int, changedBy int64, changes []model.OrderChange) error {
+	err := o.db.InTx(ctx, func(ctx context.Context, r model.Repository) error {
+		tx := bunTx(r)
+
+		// the order is locked so the checks hold until the update
+		current := new(model.Order)
+		err := tx.NewSelect().Model(current).
+			Column("id", "version", "order_status", "transfer_status").
+			Where("id = ?", order.ID).
+			For("UPDATE").
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if current.Version != version {
+			return model.ErrVersionConflict
+		}
+		if !current.Editable() {
+			return model.ErrNotEditable
+		}
+
+		now := time.Now().UTC()
+		order.Version = version + 1
+		order.UpdatedAt = now
+		// the recipient phone and address are encrypted by their Value. The
+		// update has no model value, BeforeAppendModel doesn't run, so the
+		// blind index of the phone is set here
+		_, err = tx.NewUpdate().Model((*model.Order)(nil)).
+			Set("recipient_name = ?", order.RecipientName).
+			Set("recipient_phone = ?", order.RecipientPhone).
+			Set("recipient_phone_hash = ?", encryption.BlindIndex(string(order.RecipientPhone))).
+			Set("recipient_address = ?", order.RecipientAddress).
+			Set("recipient_city = ?", order.RecipientCity).
+			Set("recipient_zone = ?", order.RecipientZone).
+			Set("recipient_area = ?", order.RecipientArea).
+			Set("item_weight = ?", order.ItemWeight).
+			Set("item_quantity = ?", order.ItemQuantity).
+			Set("amount_to_collect = ?", order.AmountToCollect).
+			Set("delivery_fee = ?", order.DeliveryFee).
+			Set("cod_fee = ?", order.CodFee).
+			Set("total_fee = ?", order.TotalFee).
+			Set("version = ?", order.Version).
+			Set("updated_at = ?", now).
+			Where("id = ?", order.ID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		sealed, err := sealChanges(changes)
+		if err != nil {
+			return err
+		}
+		history := &model.OrderHistory{
+			OrderID:   order.ID,
+			Version:   order.Version,
+			ChangedBy: changedBy,
+			Changes:   sealed,
+			CreatedAt: now,
+		}
+		if _, err := tx.NewInsert().Model(history).Exec(ctx); err != nil {
+			return err
+		}
+
+		fields := make([]string, 0, len(changes))
+		for _, change := range changes {
+			fields = append(fields, change.Field)
+		}
+		event, err := model.NewOutboxEvent(model.EventOrderUpdated, order.OrderConsignmentID, &model.OrderUpdatedEvent{
+			ConsignmentID: order.OrderConsignmentID,
+			StoreID:       order.StoreID,
+			UserID:        order.UserID,
+			Version:       order.Version,
+			Fields:        fields,
+			DeliveryFee:   order.DeliveryFee,
+			TotalFee:      order.TotalFee,
+			UpdatedAt:     now,
+		})
+		if err != nil {
+			return err
+		}
+		return insertEvents(ctx, tx, event)
+	})
+	if err != nil {
+		if !errors.Is(err, model.ErrVersionConflict) && !errors.Is(err, model.ErrNotEditable) && !errors.Is(err, model.ErrNotFound) {
+			o.log.Error(ctx, err.Error())
+		}
+		return err
+	}
+	return nil
+}
+
+// sealChanges returns the changes with the values of the encrypted fields
+// encrypted.
+func sealChanges(changes []model.OrderChange) ([]model.OrderChange, error) {
+	keyring := encryption.Default()
+	sealed := make([]model.OrderChange, len(changes))
+	for i, change := range changes {
+		if encryptedChanges[change.Field] {
+			from, err := keyring.Encrypt(fmt.Sprint(change.From))
+			if err != nil {
+				return nil, err
+			}
+			to, err := keyring.Encrypt(fmt.Sprint(change.To))
+			if err != nil {
+				return nil, err
+			}
+			change.From, change.To = from, to
+		}
+		sealed[i] = change
+	}
+	return sealed, nil
+}
+
+// openChanges decrypts in place the values of the encrypted fields.
+func openChanges(changes []model.OrderChange) error {
+	keyring := encryption.Default()
+	for i, change := range changes {
+		if !encryptedChanges[change.Field] {
+			continue
+		}
+		from, err := keyring.Decrypt(fmt.Sprint(change.From))
+		if err != nil {
+			return err
+		}
+		to, err := keyring.Decrypt(fmt.Sprint(change.To))
+		if err != nil {
+			return err
+		}
+		changes[i].From, changes[i].To = from, to
+	}
+	return nil
+}
+
+// FindOrderHistory returns the editions of an order of a user.
+func (o *OrderReceiver) FindOrderHistory(ctx context.Context, req *model.FindHistoryRequest) ([]*model.OrderHistory, error) {
+	var orderID int64
+	err := o.db.NewSelect().
+		Model((*model.Order)(nil)).
+		Column("id").
+		Where("order_consignment_id = ? AND user_id = ?", req.ConsignmentID, req.UserId).
+		Scan(ctx, &orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	history := []*model.OrderHistory{}
+	err = o.db.NewSelect().
+		Model(&history).
+		Where("order_id = ?", orderID).
+		Order("version").
+		Scan(ctx)
 	if err != nil {
 		o.log.Error(ctx, err.Error())
+		return nil, err
+	}
+	for _, edition := range history {
+		if err := openChanges(edition.Changes); err != nil {
+			o.log.Error(ctx, err.Error())
+			return nil, err
+		}
+	}
+	return history, nil
+}
+
+// CancelOrder moves an order of a user to the cancelled status, along with
+// its order.cancelled and order.status_changed events. The order is kept.
+func (o *OrderReceiver) CancelOrder(ctx context.Context, req *model.OrderCancelRequest) error {
+	err := o.db.InTx(ctx, func(ctx context.Context, r model.Repository) error {
+		tx := bunTx(r)
+
+		// the order is locked so a handover can't pick it up meanwhile
+		order := new(model.Order)
+		err := tx.NewSelect().Model(order).
+			Column("id", "store_id", "order_status", "transfer_status").
+			Where("order_consignment_id = ? AND user_id = ?", req.ConsignmentID, req.UserId).
+			For("UPDATE").
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if order.OrderStatus == model.Cancelled {
+			return model.ErrAlreadyCancelled
+		}
+		if !order.Cancellable() {
+			return model.ErrNotCancellable
+		}
+
+		// the order status is stored as its number, the version is bumped so
+		// the editions of the order fail from now on
+		cancelledAt := time.Now().UTC()
+		_, err = tx.NewUpdate().Model((*model.Order)(nil)).
+			Set("order_status = ?", strconv.Itoa(int(model.Cancelled))).
+			Set("cancelled_at = ?", cancelledAt).
+			Set("cancel_reason = ?", req.Reason).
+			Set("cancel_note = NULLIF(?, '')", req.Note).
+			Set("version = version + 1").
+			Set("updated_at = ?", cancelledAt).
+			Where("id = ?", order.ID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		cancelled, err := model.NewOutboxEvent(model.EventOrderCancelled, req.ConsignmentID, &model.OrderCancelledEvent{
+			ConsignmentID: req.ConsignmentID,
+			StoreID:       order.StoreID,
+			UserID:        req.UserId,
+			Reason:        req.Reason,
+			CancelledAt:   cancelledAt,
+		})
+		if err != nil {
+			return err
+		}
+		statusChanged, err := model.NewOutboxEvent(model.EventOrderStatusChanged, req.ConsignmentID, &model.OrderStatusChangedEvent{
+			ConsignmentID: req.ConsignmentID,
+			StoreID:       order.StoreID,
+			UserID:        req.UserId,
+			From:          order.OrderStatus.String(),
+			To:            model.Cancelled.String(),
+			ChangedAt:     cancelledAt,
+		})
+		if err != nil {
+			return err
+		}
+		return insertEvents(ctx, tx, cancelled, statusChanged)
+	})
+	if err != nil {
+		if !errors.Is(err, model.ErrNotFound) && !errors.Is(err, model.ErrAlreadyCancelled) && !errors.Is(err, model.ErrNotCancellable) {
+			o.log.Error(ctx, err.Error())
+		}
 		return err
 	}
 
 	return nil
 }
+
+// ArchiveOrders archives, or unarchives, the orders of req and returns their
+// number, those archived, or unarchived, already included. The orders
+// unarchived are kept out of the automatic archiving.
+func (o *OrderReceiver) ArchiveOrders(ctx context.Context, req *model.ArchiveRequest) (int, error) {
+	now := time.Now().UTC()
+	query := o.db.NewUpdate().Model((*model.Order)(nil)).
+		Where("user_id = ?", req.UserId)
+	if req.Archive {
+		query.Set("archive = ?", model.Archived).
+			Set("archived_at = CASE WHEN archive = ? THEN archived_at ELSE ? END", model.Archived, now)
+	} else {
+		query.Set("archive = ?", model.NotArchived).
+			Set("archived_at = NULL").
+			Set("unarchived_at = CASE WHEN archive = ? THEN ? ELSE unarchived_at END", model.Archived, now)
+	}
+
+	if len(req.ConsignmentIDs) > 0 {
+		query.Where("order_consignment_id IN (?)", bun.In(req.ConsignmentIDs))
+	} else {
+		orders := o.db.NewSelect().
+			Model((*model.Order)(nil)).
+			Column("id").
+			Where("user_id = ?", req.UserId)
+		applyOrderFilters(orders, req.Filter)
+		query.Where("id IN (?)", orders)
+	}
+
+	res, err := query.Exec(ctx)
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return 0, err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(updated), nil
+}
+
+// ArchiveCompletedOrders archives a batch of the orders completed, and left
+// unchanged, since before given time and returns their number. The orders
+// unarchived by their merchant are left as they are. Concurrent archivers
+// skip the orders locked by one another.
+func (o *OrderReceiver) ArchiveCompletedOrders(ctx context.Context, before time.Time, limit int) (int, error) {
+	// the completed orders, stored as the number of the status
+	orders := o.db.NewSelect().
+		Model((*model.Order)(nil)).
+		Column("id").
+		Where("archive = ? AND order_status = ?", model.NotArchived, strconv.Itoa(int(model.Completed))).
+		Where("unarchived_at IS NULL AND updated_at < ?", before).
+		Limit(limit).
+		For("UPDATE SKIP LOCKED")
+
+	res, err := o.db.NewUpdate().Model((*model.Order)(nil)).
+		Set("archive = ?", model.Archived).
+		Set("archived_at = ?", time.Now().UTC()).
+		Where("id IN (?)", orders).
+		Exec(ctx)
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return 0, err
+	}
+	archived, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(archived), nil
+}
+
+// MerchantOrderExists reports whether given user has an order with given
+// merchant order ID. It reads the primary, a retried creation must see the
+// first one.
+func (o *OrderReceiver) MerchantOrderExists(ctx context.Context, userID int64, merchantOrderID string) (bool, error) {
+	exists, err := o.db.NewSelect().
+		Model((*model.Order)(nil)).
+		Where("user_id = ? AND merchant_order_id = ?", userID, merchantOrderID).
+		Exists(ctx)
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return false, err
+	}
+	return exists, nil
+}
+
+// storedRecipient is the recipient details of an order as stored, encrypted
+// or not.
+type storedRecipient struct {
+	ID                 int64  `bun:"id"`
+	RecipientPhone     string `bun:"recipient_phone"`
+	RecipientPhoneHash string `bun:"recipient_phone_hash"`
+	RecipientAddress   string `bun:"recipient_address"`
+}
+
+// ReencryptOrders writes again the recipient details of up to limit orders
+// after given ID which don't follow the encryption configuration, e.g. sealed
+// with a retired key, and refreshes their blind index. It returns the ID of
+// the last order read, 0 once every order was read.
+func (o *OrderReceiver) ReencryptOrders(ctx context.Context, afterID int64, limit int) (lastID int64, updated int, err error) {
+	keyring := encryption.Default()
+	err = o.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// the batch is locked so the orders edited meanwhile aren't written
+		// back with their previous recipient details
+		var rows []storedRecipient
+		err := tx.NewSelect().
+			Table("orders").
+			Column("id", "recipient_phone", "recipient_phone_hash", "recipient_address").
+			Where("id > ?", afterID).
+			Order("id").
+			Limit(limit).
+			For("UPDATE").
+			Scan(ctx, &rows)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		lastID = rows[len(rows)-1].ID
+
+		for _, row := range rows {
+			phone, err := keyring.Decrypt(row.RecipientPhone)
+			if err != nil {
+				return fmt.Errorf("order %d: %w", row.ID, err)
+			}
+			hash := keyring.BlindIndex(phone)
+			if !keyring.NeedsReencrypt(row.RecipientPhone) &&
+				!keyring.NeedsReencrypt(row.RecipientAddress) && hash == row.RecipientPhoneHash {
+				continue
+			}
+
+			address, err := keyring.Decrypt(row.RecipientAddress)
+			if err != nil {
+				return fmt.Errorf("order %d: %w", row.ID, err)
+			}
+
+			_, err = tx.NewUpdate().
+				Table("orders").
+				Set("recipient_phone = ?", encryption.String(phone)).
+				Set("recipient_phone_hash = ?", hash).
+				Set("recipient_address = ?", encryption.String(address)).
+				Where("id = ?", row.ID).
+				Exec(ctx)
+			if err != nil {
+				return fmt.Errorf("order %d: %w", row.ID, err)
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return 0, 0, err
+	}
+
+	return lastID, updated, nil
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// IOutbox is the repository of the outbox events, read by the relay.
+type IOutbox interface {
+	// RelayEvents passes the next events not yet published to given sink, up
+	// to limit, to publish and moves the offset of the sink past them unless
+	// it fails. It returns the number of events published, 0 when there is
+	// none or another relay holds the sink.
+	RelayEvents(ctx context.Context, sink string, limit int,
+		publish func(ctx context.Context, events []*model.OutboxEvent) error) (int, error)
+	// PurgeEvents deletes the events created before given time which were
+	// published to every given sink, none without sinks.
+	PurgeEvents(ctx context.Context, sinks []string, before time.Time) (int64, error)
+	// FindUserEvents returns the last events of given user published after
+	// the event of given transaction and ID, up to limit and oldest first.
+	// truncated reports whether older ones were left out.
+	FindUserEvents(ctx context.Context, userID int64, afterTxID string, afterID int64, limit int) (events []*model.OutboxEvent, truncated bool, err error)
+}
+
+type InitOutboxRepository struct {
+	Db  *db.DB
+	Log *log.Logger
+}
+
+type OutboxReceiver struct {
+	log *log.Logger
+	db  *db.DB
+}
+
+// NewOutbox returns a new instance of the Outbox repository.
+func NewOutbox(initOutboxRepository *InitOutboxRepository) IOutbox {
+	return &OutboxReceiver{
+		log: initOutboxRepository.Log,
+		db:  initOutboxRepository.Db,
+	}
+}
+
+// insertEvents writes given events in the transaction of the changes they
+// describe.
+func insertEvents(ctx context.Context, tx bun.IDB, events ...*model.OutboxEvent) error {
+	_, err := tx.NewInsert().Model(&events).Exec(ctx)
+	return err
+}
+
+// bunTx returns the transaction of given db.InTx repository.
+func bunTx(r model.Repository) bun.IDB {
+	return r.(*db.Tx).Tx
+}
+
+// outboxOffset is the last event published to a sink.
+type outboxOffset struct {
+	TxID    string `bun:"txid"`
+	EventID int64  `bun:"event_id"`
+}
+
+// RelayEvents locks the offset of the sink for the time of the publishing, so
+// concurrent relays never publish the same events. The events are read in
+// the order of their transactions, only once every older transaction ended:
+// a transaction committing late can't slip an event behind the offset.
+func (o *OutboxReceiver) RelayEvents(ctx context.Context, sink string, limit int,
+	publish func(ctx context.Context, events []*model.OutboxEvent) error) (n int, err error) {
+	err = o.db.InTx(ctx, func(ctx context.Context, r model.Repository) error {
+		tx := bunTx(r)
+
+		_, err := tx.NewRaw("INSERT INTO outbox_offsets (sink) VALUES (?) ON CONFLICT DO NOTHING", sink).Exec(ctx)
+		if err != nil {
+			return err
+		}
+		var offset outboxOffset
+		err = tx.NewRaw("SELECT txid::text, event_id FROM outbox_offsets WHERE sink = ? FOR UPDATE SKIP LOCKED", sink).
+			Scan(ctx, &offset.TxID, &offset.EventID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil // held by another relay
+		}
+		if err != nil {
+			return err
+		}
+
+		var events []*model.OutboxEvent
+		err = tx.NewSelect().
+			Model(&events).
+			ColumnExpr("id, txid::text AS txid, event_type, aggregate_id, payload, created_at").
+			Where("(txid, id) > (?::xid8, ?)", offset.TxID, offset.EventID).
+			Where("txid < pg_snapshot_xmin(pg_current_snapshot())").
+			OrderExpr("txid, id").
+			Limit(limit).
+			Scan(ctx)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		if err = publish(ctx, events); err != nil {
+			return err
+		}
+
+		last := events[len(events)-1]
+		_, err = tx.NewRaw("UPDATE outbox_offsets SET txid = ?::xid8, event_id = ?, updated_at = now() WHERE sink = ?",
+			last.TxID, last.ID, sink).Exec(ctx)
+		if err != nil {
+			return err
+		}
+		n = len(events)
+		return nil
+	})
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return 0, err
+	}
+	return n, nil
+}
+
+// PurgeEvents keeps the events of the sinks without offset yet.
+func (o *OutboxReceiver) PurgeEvents(ctx context.Context, sinks []string, before time.Time) (int64, error) {
+	if len(sinks) == 0 {
+		return 0, nil // every event would pass the check of the sinks
+	}
+	res, err := o.db.NewDelete().
+		TableExpr("outbox AS o").
+		Where("o.created_at < ?", before).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM unnest(?::text[]) AS s(sink)
+			LEFT JOIN outbox_offsets AS f ON f.sink = s.sink
+			WHERE f.sink IS NULL OR (o.txid, o.id) > (f.txid, f.event_id))`, pgdialect.Array(sinks)).
+		Exec(ctx)
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// FindUserEvents reads the events not purged yet, by the user ID of their
+// payload. Like the relay, it only reads the events once every older
+// transaction ended, in the order of their transactions, so the events it
+// returns were published after the given one.
+func (o *OutboxReceiver) FindUserEvents(ctx context.Context, userID int64, afterTxID string, afterID int64, limit int) ([]*model.OutboxEvent, bool, error) {
+	var events []*model.OutboxEvent
+	err := o.db.NewSelect().
+		Model(&events).
+		ColumnExpr("id, txid::text AS txid, event_type, aggregate_id, payload, created_at").
+		Where("payload->>'user_id' = ?", strconv.FormatInt(userID, 10)).
+		Where("(txid, id) > (?::xid8, ?)", afterTxID, afterID).
+		Where("txid < pg_snapshot_xmin(pg_current_snapshot())").
+		OrderExpr("txid DESC, id DESC").
+		Limit(limit + 1).
+		Scan(ctx)
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return nil, false, err
+	}
+
+	truncated := len(events) > limit
+	if truncated {
+		events = events[:limit]
+	}
+	slices.Reverse(events)
+	return events, truncated, nil
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeEventsWithoutSinks(t *testing.T) {
+	// no database: nothing may be deleted without a sink to check
+	outbox := NewOutbox(&InitOutboxRepository{Log: log.New()})
+	purged, err := outbox.PurgeEvents(context.Background(), nil, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+)
+
+// IStore is the repository of the merchant stores and the rate cards.
+type IStore interface {
+	CreateStore(ctx context.Context, store *model.Store) (*model.Store, error)
+	FindStoreByName(ctx context.Context, userID int64, name string) (*model.Store, error)
+	FindStore(ctx context.Context, userID, id int64) (*model.Store, error)
+	SaveRateCard(ctx context.Context, rateCard *model.RateCard) error
+	FindRateCardByCity(ctx context.Context, cityID int64) (*model.RateCard, error)
+}
+
+type InitStoreRepository struct {
+	Db  *db.DB
+	Log *log.Logger
+}
+
+type StoreReceiver struct {
+	log *log.Logger
+	db  *db.DB
+}
+
+// NewStore returns a new instance of the Store repository.
+func NewStore(initStoreRepository *InitStoreRepository) IStore {
+	return &StoreReceiver{
+		log: initStoreRepository.Log,
+		db:  initStoreRepository.Db,
+	}
+}
+
+// CreateStore creates a new store in the database.
+func (s *StoreReceiver) CreateStore(ctx context.Context, store *model.Store) (*model.Store, error) {
+	_, err := s.db.NewInsert().Model(store).Exec(ctx)
+	if err != nil {
+		s.log.Error(ctx, err.Error())
+		return nil, err
+	}
+	return store, nil
+}
+
+// FindStoreByName returns the store of given user with given name.
+func (s *StoreReceiver) FindStoreByName(ctx context.Context, userID int64, name string) (*model.Store, error) {
+	store := &model.Store{}
+	err := s.db.NewSelect().
+		Model(store).
+		Where("user_id = ? AND name = ?", userID, name).
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		s.log.Error(ctx, err.Error())
+		return nil, err
+	}
+	return store, nil
+}
+
+// FindStore returns the store of given user with given ID.
+func (s *StoreReceiver) FindStore(ctx context.Context, userID, id int64) (*model.Store, error) {
+	store := &model.Store{}
+	err := s.db.NewSelect().
+		Model(store).
+		Where("user_id = ? AND id = ?", userID, id).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		s.log.Error(ctx, err.Error())
+		return nil, err
+	}
+	return store, nil
+}
+
+// SaveRateCard creates the rate card of a city or replaces its fees.
+func (s *StoreReceiver) SaveRateCard(ctx context.Context, rateCard *model.RateCard) error {
+	_, err := s.db.NewInsert().Model(rateCard).
+		On("CONFLICT (city_id) DO UPDATE").
+		Set("base_delivery_fee = EXCLUDED.base_delivery_fee").
+		Set("cod_percentage = EXCLUDED.cod_percentage").
+		Set("updated_at = current_timestamp").
+		Exec(ctx)
+	if err != nil {
+		s.log.Error(ctx, err.Error())
+		return err
+	}
+	return nil
+}
+
+// FindRateCardByCity returns the rate card of given city.
+func (s *StoreReceiver) FindRateCardByCity(ctx context.Context, cityID int64) (*model.RateCard, error) {
+	rateCard := &model.RateCard{}
+	err := s.db.NewSelect().
+		Model(rateCard).
+		Where("city_id = ?", cityID).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		s.log.Error(ctx, err.Error())
+		return nil, err
+	}
+	return rateCard, nil
+}
@@ -2,12 +2,15 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"github.com/kaium123/order/internal/db"
 	"github.com/kaium123/order/internal/log"
 	"github.com/kaium123/order/internal/model"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 	"time"
 )
@@ -15,6 +18,7 @@ import (
 // IUser defines the repository interface for user-related operations.
 type IUser interface {
 	FindUserByUserNameOrEmail(ctx context.Context, req *model.UserLoginRequest) (*model.User, error)
+	CreateUser(ctx context.Context, user *model.User) (*model.User, error)
 	SaveAccessToken(ctx context.Context, accessToken *model.AccessToken) error
 	SaveRefreshToken(ctx context.Context, refreshToken *model.RefreshToken) error
 	RemoveAccessToken(ctx context.Context, userID int64) ([]*model.AccessToken, error)
@@ -39,7 +43,8 @@ func NewUser(initUserRepository *InitUserRepository) IUser {
 	}
 }
 
-// FindUserByUsername retrieves a user by username or email from the database.
+// FindUserByUsername retrieves a user by username or email from the database,
+// or model.ErrNotFound.
 func (u *UserReceiver) FindUserByUserNameOrEmail(ctx context.Context, req *model.UserLoginRequest) (*model.User, error) {
 	user := &model.User{}
 	err := u.db.NewSelect().
@@ -47,9 +52,22 @@ func (u *UserReceiver) FindUserByUserNameOrEmail(ctx context.Context, req *model
 		Where("email = ? OR user_name = ?", req.Email, req.Username).
 		Limit(1).
 		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		u.log.Error(ctx, "Error finding user by username or email", zap.String("user_name", req.Username),
+			zap.String("email", req.Email), zap.Error(err))
+		return nil, err
+	}
+	return user, nil
+}
 
+// CreateUser creates a new user in the database.
+func (u *UserReceiver) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	_, err := u.db.NewInsert().Model(user).Exec(ctx)
 	if err != nil {
-		u.log.Error(ctx, fmt.Sprintf("Error finding user by username %s or email %s: %v", req.Username, req.Email, err))
+		u.log.Error(ctx, err.Error())
 		return nil, err
 	}
 	return user, nil
@@ -84,7 +102,7 @@ func (u *UserReceiver) RemoveAccessToken(ctx context.Context, userID int64) ([]*
 		Returning("*").
 		Exec(ctx, &accessTokens)
 	if err != nil {
-		u.log.Error(ctx, fmt.Sprintf("Failed to remove access token for user %s: %v", userID, err))
+		u.log.Error(ctx, fmt.Sprintf("Failed to remove access token for user %d: %v", userID, err))
 		return nil, err
 	}
 	return accessTokens, nil
@@ -100,7 +118,7 @@ func (u *UserReceiver) RemoveRefreshToken(ctx context.Context, userID int64) ([]
 		Returning("*").
 		Exec(ctx, &refreshTokens)
 	if err != nil {
-		u.log.Error(ctx, fmt.Sprintf("Failed to remove refresh token for user %s: %v", userID, err))
+		u.log.Error(ctx, fmt.Sprintf("Failed to remove refresh token for user %d: %v", userID, err))
 		return nil, err
 	}
 	return refreshTokens, nil
@@ -0,0 +1,374 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kaium123/order/internal/db"
+	"github.com/kaium123/order/internal/encryption"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/uptrace/bun"
+)
+
+// IWebhook is the repository of the webhook subscriptions and their delivery
+// log.
+type IWebhook interface {
+	CreateSubscription(ctx context.Context, subscription *model.WebhookSubscription) (*model.WebhookSubscription, error)
+	FindSubscriptions(ctx context.Context, storeID int64) ([]*model.WebhookSubscription, error)
+	FindSubscription(ctx context.Context, storeID, id int64) (*model.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, storeID, id int64) error
+	// EnqueueDeliveries creates the pending deliveries of given outbox events
+	// to the subscriptions of their stores. It returns the number of
+	// deliveries created, the ones already enqueued are skipped.
+	EnqueueDeliveries(ctx context.Context, events []*model.OutboxEvent) (int64, error)
+	CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	// ClaimDeliveries returns up to limit pending deliveries due, along with
+	// their subscriptions, and postpones them until given time so concurrent
+	// dispatchers skip them.
+	ClaimDeliveries(ctx context.Context, limit int, until time.Time) ([]*model.WebhookDelivery, map[int64]*model.WebhookSubscription, error)
+	// SaveAttempt writes the outcome of the last attempt of given delivery.
+	SaveAttempt(ctx context.Context, delivery *model.WebhookDelivery) error
+	FindDeliveries(ctx context.Context, req *model.FindDeliveriesRequest) ([]*model.WebhookDelivery, *model.PaginationResponse, error)
+	// ReplayDelivery makes a delivery pending again, due now and with all its
+	// attempts left.
+	ReplayDelivery(ctx context.Context, subscriptionID, id int64) error
+	// ReencryptSecrets writes again the secrets which don't follow the
+	// encryption configuration and returns their number.
+	ReencryptSecrets(ctx context.Context) (int, error)
+}
+
+type InitWebhookRepository struct {
+	Db  *db.DB
+	Log *log.Logger
+}
+
+type WebhookReceiver struct {
+	log *log.Logger
+	db  *db.DB
+}
+
+// NewWebhook returns a new instance of the Webhook repository.
+func NewWebhook(initWebhookRepository *InitWebhookRepository) IWebhook {
+	return &WebhookReceiver{
+		log: initWebhookRepository.Log,
+		db:  initWebhookRepository.Db,
+	}
+}
+
+// CreateSubscription creates a new subscription in the database.
+func (w *WebhookReceiver) CreateSubscription(ctx context.Context, subscription *model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	_, err := w.db.NewInsert().Model(subscription).Exec(ctx)
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// FindSubscriptions returns the subscriptions of given store.
+func (w *WebhookReceiver) FindSubscriptions(ctx context.Context, storeID int64) ([]*model.WebhookSubscription, error) {
+	subscriptions := []*model.WebhookSubscription{}
+	err := w.db.NewSelect().
+		Model(&subscriptions).
+		Where("store_id = ?", storeID).
+		Order("id").
+		Scan(ctx)
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// FindSubscription returns the subscription of given store with given ID.
+func (w *WebhookReceiver) FindSubscription(ctx context.Context, storeID, id int64) (*model.WebhookSubscription, error) {
+	subscription := &model.WebhookSubscription{}
+	err := w.db.NewSelect().
+		Model(subscription).
+		Where("store_id = ? AND id = ?", storeID, id).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription deletes a subscription and fails its pending
+// deliveries.
+func (w *WebhookReceiver) DeleteSubscription(ctx context.Context, storeID, id int64) error {
+	err := w.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewDelete().
+			Model((*model.WebhookSubscription)(nil)).
+			Where("store_id = ? AND id = ?", storeID, id).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if affected, err := res.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return model.ErrNotFound
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*model.WebhookDelivery)(nil)).
+			Set("status = ?", model.DeliveryFailed).
+			Set("last_error = ?", "subscription deleted").
+			Set("updated_at = ?", time.Now().UTC()).
+			Where("subscription_id = ? AND status = ?", id, model.DeliveryPending).
+			Exec(ctx)
+		return err
+	})
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		w.log.Error(ctx, err.Error())
+	}
+	return err
+}
+
+// eventStore is the store an event is about, the order events carry it in
+// their payload.
+type eventStore struct {
+	StoreID int64 `json:"store_id"`
+}
+
+// EnqueueDeliveries relies on the unique event of every subscription, the
+// relay may pass the same events twice.
+func (w *WebhookReceiver) EnqueueDeliveries(ctx context.Context, events []*model.OutboxEvent) (int64, error) {
+	storeEvents := make(map[int64][]*model.OutboxEvent)
+	for _, event := range events {
+		var ref eventStore
+		if err := json.Unmarshal(event.Payload, &ref); err != nil || ref.StoreID == 0 {
+			continue
+		}
+		storeEvents[ref.StoreID] = append(storeEvents[ref.StoreID], event)
+	}
+	if len(storeEvents) == 0 {
+		return 0, nil
+	}
+
+	storeIDs := make([]int64, 0, len(storeEvents))
+	for storeID := range storeEvents {
+		storeIDs = append(storeIDs, storeID)
+	}
+	var subscriptions []*model.WebhookSubscription
+	err := w.db.NewSelect().
+		Model(&subscriptions).
+		Where("store_id IN (?)", bun.In(storeIDs)).
+		Scan(ctx)
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	var deliveries []*model.WebhookDelivery
+	for _, subscription := range subscriptions {
+		for _, event := range storeEvents[subscription.StoreID] {
+			if !subscription.Subscribes(event.Type) {
+				continue
+			}
+			deliveries = append(deliveries, &model.WebhookDelivery{
+				SubscriptionID: subscription.ID,
+				EventID:        event.ID,
+				EventType:      event.Type,
+				Payload:        event.Payload,
+				Status:         model.DeliveryPending,
+				NextAttemptAt:  now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+
+	res, err := w.db.NewInsert().
+		Model(&deliveries).
+		On("CONFLICT (subscription_id, event_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CreateDelivery creates a new delivery in the database.
+func (w *WebhookReceiver) CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	_, err := w.db.NewInsert().Model(delivery).Exec(ctx)
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return err
+	}
+	return nil
+}
+
+// ClaimDeliveries leaves out the subscriptions deleted since, their
+// deliveries are failed already.
+func (w *WebhookReceiver) ClaimDeliveries(ctx context.Context, limit int, until time.Time) ([]*model.WebhookDelivery, map[int64]*model.WebhookSubscription, error) {
+	var deliveries []*model.WebhookDelivery
+	err := w.db.NewRaw(`UPDATE webhook_deliveries SET next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED)
+		RETURNING *`,
+		until, time.Now().UTC(), model.DeliveryPending, time.Now().UTC(), limit).
+		Scan(ctx, &deliveries)
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return nil, nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, nil, nil
+	}
+
+	subscriptionIDs := make([]int64, len(deliveries))
+	for i, delivery := range deliveries {
+		subscriptionIDs[i] = delivery.SubscriptionID
+	}
+	var subscriptions []*model.WebhookSubscription
+	err = w.db.NewSelect().
+		Model(&subscriptions).
+		Where("id IN (?)", bun.In(subscriptionIDs)).
+		Scan(ctx)
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return nil, nil, err
+	}
+
+	byID := make(map[int64]*model.WebhookSubscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		byID[subscription.ID] = subscription
+	}
+	return deliveries, byID, nil
+}
+
+// SaveAttempt updates the status and the attempt columns of the delivery.
+func (w *WebhookReceiver) SaveAttempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now().UTC()
+	_, err := w.db.NewUpdate().
+		Model(delivery).
+		Column("status", "attempts", "next_attempt_at", "last_status_code", "last_error",
+			"last_response", "duration_ms", "updated_at", "delivered_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return err
+	}
+	return nil
+}
+
+// FindDeliveries lists the deliveries of a subscription, latest first.
+func (w *WebhookReceiver) FindDeliveries(ctx context.Context, req *model.FindDeliveriesRequest) ([]*model.WebhookDelivery, *model.PaginationResponse, error) {
+	deliveries := []*model.WebhookDelivery{}
+	query := w.db.NewSelect().
+		Model((*model.WebhookDelivery)(nil)).
+		Where("subscription_id = ?", req.ID).
+		Limit(req.Limit).
+		Offset(req.Offset)
+
+	if req.Status != "" {
+		query.Where("status = ?", req.Status)
+	}
+
+	query.Order("id DESC")
+	total, err := query.ScanAndCount(ctx, &deliveries)
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return nil, nil, err
+	}
+
+	paginationResponse := &model.PaginationResponse{
+		Total:       total,
+		CurrentPage: req.Offset/req.Limit + 1,
+		PerPage:     req.Limit,
+		TotalInPage: len(deliveries),
+		LastPage:    (total + req.Limit - 1) / req.Limit,
+	}
+
+	return deliveries, paginationResponse, nil
+}
+
+// ReplayDelivery keeps the outcome of the last attempt until the next one.
+func (w *WebhookReceiver) ReplayDelivery(ctx context.Context, subscriptionID, id int64) error {
+	now := time.Now().UTC()
+	res, err := w.db.NewUpdate().
+		Model((*model.WebhookDelivery)(nil)).
+		Set("status = ?", model.DeliveryPending).
+		Set("attempts = 0").
+		Set("next_attempt_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("subscription_id = ? AND id = ?", subscriptionID, id).
+		Exec(ctx)
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// storedSecret is the secret of a subscription as stored, encrypted or not.
+type storedSecret struct {
+	ID     int64  `bun:"id"`
+	Secret string `bun:"secret"`
+}
+
+// ReencryptSecrets updates the secrets in a single transaction, there are a
+// few per store.
+func (w *WebhookReceiver) ReencryptSecrets(ctx context.Context) (updated int, err error) {
+	keyring := encryption.Default()
+	err = w.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var rows []storedSecret
+		err := tx.NewSelect().
+			Table("webhook_subscriptions").
+			Column("id", "secret").
+			Order("id").
+			For("UPDATE").
+			Scan(ctx, &rows)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if !keyring.NeedsReencrypt(row.Secret) {
+				continue
+			}
+			secret, err := keyring.Decrypt(row.Secret)
+			if err != nil {
+				return fmt.Errorf("webhook %d: %w", row.ID, err)
+			}
+			_, err = tx.NewUpdate().
+				Table("webhook_subscriptions").
+				Set("secret = ?", encryption.String(secret)).
+				Where("id = ?", row.ID).
+				Exec(ctx)
+			if err != nil {
+				return fmt.Errorf("webhook %d: %w", row.ID, err)
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		w.log.Error(ctx, err.Error())
+		return 0, err
+	}
+	return updated, nil
+}
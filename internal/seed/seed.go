@@ -0,0 +1,260 @@
+// Package seed loads fixtures into the database through the repositories.
+package seed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/repository"
+	"github.com/kaium123/order/internal/service"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures to load. Users are referenced by user name and stores by name.
+type Fixtures struct {
+	Users     []User           `json:"users"`
+	Stores    []Store          `json:"stores"`
+	RateCards []model.RateCard `json:"rate_cards"`
+	Orders    []Order          `json:"orders"`
+}
+
+// User fixture with a plain text password.
+type User struct {
+	UserName string     `json:"user_name"`
+	Email    string     `json:"email"`
+	Password string     `json:"password"`
+	Role     model.Role `json:"role,omitempty"` // merchant by default
+}
+
+// Store fixture owned by the user with given user name.
+type Store struct {
+	User string `json:"user"`
+	model.Store
+}
+
+// Order fixture of the user with given user name, placed at the store with
+// given name.
+type Order struct {
+	User  string `json:"user"`
+	Store string `json:"store"`
+	model.Order
+}
+
+// ReadFile reads the fixtures of a yaml or json file. Unknown fields are
+// rejected.
+func ReadFile(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	// json is yaml, decode both with yaml and re-encode to json to use the
+	// json tags of the models
+	var raw interface{}
+	if err = yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	if data, err = json.Marshal(raw); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+
+	fixtures := new(Fixtures)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(fixtures); err != nil {
+		return nil, fmt.Errorf("failed to decode fixtures %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// Result counts the created, the updated and the skipped, already existing,
+// records.
+type Result struct {
+	Created int
+	Updated int
+	Skipped int
+}
+
+type InitSeeder struct {
+	UserRepository  repository.IUser
+	StoreRepository repository.IStore
+	OrderRepository repository.IOrder
+	OrderService    service.IOrder
+	Log             *log.Logger
+}
+
+// Seeder loads fixtures. Loading the same fixtures again creates nothing.
+type Seeder struct {
+	userRepository  repository.IUser
+	storeRepository repository.IStore
+	orderRepository repository.IOrder
+	orderService    service.IOrder
+	log             *log.Logger
+}
+
+// New returns a new Seeder.
+func New(initSeeder *InitSeeder) *Seeder {
+	return &Seeder{
+		userRepository:  initSeeder.UserRepository,
+		storeRepository: initSeeder.StoreRepository,
+		orderRepository: initSeeder.OrderRepository,
+		orderService:    initSeeder.OrderService,
+		log:             initSeeder.Log,
+	}
+}
+
+// Seed loads given fixtures: users, rate cards, stores and orders in order.
+func (s *Seeder) Seed(ctx context.Context, fixtures *Fixtures) (res Result, err error) {
+	users := make(map[string]int64)
+	for _, fixture := range fixtures.Users {
+		var user *model.User
+		if user, err = s.seedUser(ctx, fixture, &res); err != nil {
+			return res, fmt.Errorf("user %s: %w", fixture.UserName, err)
+		}
+		users[fixture.UserName] = user.ID
+	}
+
+	for _, rateCard := range fixtures.RateCards {
+		if err = s.seedRateCard(ctx, rateCard, &res); err != nil {
+			return res, fmt.Errorf("rate card of city %d: %w", rateCard.CityID, err)
+		}
+	}
+
+	stores := make(map[string]int64)
+	for _, fixture := range fixtures.Stores {
+		var store *model.Store
+		if store, err = s.seedStore(ctx, fixture, users, &res); err != nil {
+			return res, fmt.Errorf("store %s: %w", fixture.Name, err)
+		}
+		stores[fixture.Name] = store.ID
+	}
+
+	for _, fixture := range fixtures.Orders {
+		if err = s.seedOrder(ctx, fixture, users, stores, &res); err != nil {
+			return res, fmt.Errorf("order %s: %w", fixture.MerchantOrderID, err)
+		}
+	}
+
+	s.log.Info(ctx, "fixtures loaded", zap.Int("created", res.Created), zap.Int("updated", res.Updated), zap.Int("skipped", res.Skipped))
+	return
+}
+
+func (s *Seeder) seedUser(ctx context.Context, fixture User, res *Result) (*model.User, error) {
+	user, err := s.userRepository.FindUserByUserNameOrEmail(ctx, &model.UserLoginRequest{
+		Username: fixture.UserName,
+		Email:    fixture.Email,
+	})
+	if err == nil {
+		res.Skipped++
+		return user, nil
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		return nil, err
+	}
+
+	hash, err := service.HashPassword(fixture.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	role := fixture.Role
+	if role == "" {
+		role = model.RoleMerchant
+	}
+
+	user, err = s.userRepository.CreateUser(ctx, &model.User{
+		UserName:     fixture.UserName,
+		Email:        fixture.Email,
+		PasswordHash: hash,
+		Role:         role,
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.Created++
+	return user, nil
+}
+
+// seedRateCard creates the rate card of its city, or updates its fees when
+// they changed.
+func (s *Seeder) seedRateCard(ctx context.Context, rateCard model.RateCard, res *Result) error {
+	existing, err := s.storeRepository.FindRateCardByCity(ctx, rateCard.CityID)
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		res.Created++
+	case err != nil:
+		return err
+	case existing.BaseDeliveryFee == rateCard.BaseDeliveryFee && existing.CODPercentage == rateCard.CODPercentage:
+		res.Skipped++
+		return nil
+	default:
+		res.Updated++
+	}
+	return s.storeRepository.SaveRateCard(ctx, &rateCard)
+}
+
+func (s *Seeder) seedStore(ctx context.Context, fixture Store, users map[string]int64, res *Result) (*model.Store, error) {
+	userID, ok := users[fixture.User]
+	if !ok {
+		return nil, fmt.Errorf("unknown user %q", fixture.User)
+	}
+
+	store, err := s.storeRepository.FindStoreByName(ctx, userID, fixture.Name)
+	if err == nil {
+		res.Skipped++
+		return store, nil
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		return nil, err
+	}
+
+	store = &fixture.Store
+	store.UserID = userID
+	if store, err = s.storeRepository.CreateStore(ctx, store); err != nil {
+		return nil, err
+	}
+	res.Created++
+	return store, nil
+}
+
+func (s *Seeder) seedOrder(ctx context.Context, fixture Order, users, stores map[string]int64, res *Result) error {
+	userID, ok := users[fixture.User]
+	if !ok {
+		return fmt.Errorf("unknown user %q", fixture.User)
+	}
+	storeID, ok := stores[fixture.Store]
+	if !ok {
+		return fmt.Errorf("unknown store %q", fixture.Store)
+	}
+
+	exists, err := s.orderRepository.MerchantOrderExists(ctx, userID, fixture.MerchantOrderID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		res.Skipped++
+		return nil
+	}
+
+	order := fixture.Order
+	order.UserID = userID
+	order.StoreID = storeID
+	if validationErr := order.Validate(); validationErr != nil {
+		return fmt.Errorf("invalid order: %v", validationErr.Errors)
+	}
+
+	if _, err = s.orderService.CreateOrder(ctx, &order); err != nil {
+		return err
+	}
+	res.Created++
+	return nil
+}
@@ -0,0 +1,152 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kaium123/order/internal/encryption"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/repository"
+	"github.com/kaium123/order/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFileDevelopment(t *testing.T) {
+	fixtures, err := ReadFile(filepath.Join("..", "..", "sql", "fixtures", "development.yaml"))
+	require.NoError(t, err)
+
+	require.Len(t, fixtures.Users, 2)
+	assert.Equal(t, model.RoleSupport, fixtures.Users[1].Role)
+	require.Len(t, fixtures.Orders, 2)
+	assert.Equal(t, "Banani Store", fixtures.Orders[0].Store)
+	assert.Equal(t, encryption.String("01875113838"), fixtures.Orders[0].RecipientPhone)
+
+	order := fixtures.Orders[0].Order
+	order.StoreID = 1 // resolved from the store name on seed
+	assert.Nil(t, order.Validate())
+}
+
+func TestReadFileJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rate_cards": [{"city_id": 2, "base_delivery_fee": 90, "cod_percentage": 1.5}]}`), 0o600))
+
+	fixtures, err := ReadFile(path)
+	require.NoError(t, err)
+	require.Len(t, fixtures.RateCards, 1)
+	assert.Equal(t, 90.0, fixtures.RateCards[0].BaseDeliveryFee)
+}
+
+func TestReadFileUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("users:\n  - user_name: a\n    pasword: b\n"), 0o600))
+
+	_, err := ReadFile(path)
+	assert.Error(t, err)
+}
+
+// memory keeps the seeded records in memory.
+type memory struct {
+	repository.IUser
+	repository.IStore
+	repository.IOrder
+
+	users     []*model.User
+	stores    []*model.Store
+	rateCards map[int64]model.RateCard
+	orders    map[string]bool
+	// failLookups fails the lookups of the users.
+	failLookups bool
+}
+
+func (m *memory) FindUserByUserNameOrEmail(_ context.Context, req *model.UserLoginRequest) (*model.User, error) {
+	if m.failLookups {
+		return nil, errors.New("connection refused")
+	}
+	for _, user := range m.users {
+		if user.UserName == req.Username || user.Email == req.Email {
+			return user, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *memory) CreateUser(_ context.Context, user *model.User) (*model.User, error) {
+	user.ID = int64(len(m.users) + 1)
+	m.users = append(m.users, user)
+	return user, nil
+}
+
+func (m *memory) FindStoreByName(_ context.Context, userID int64, name string) (*model.Store, error) {
+	for _, store := range m.stores {
+		if store.UserID == userID && store.Name == name {
+			return store, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *memory) CreateStore(_ context.Context, store *model.Store) (*model.Store, error) {
+	store.ID = int64(len(m.stores) + 1)
+	m.stores = append(m.stores, store)
+	return store, nil
+}
+
+func (m *memory) FindRateCardByCity(_ context.Context, cityID int64) (*model.RateCard, error) {
+	rateCard, ok := m.rateCards[cityID]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &rateCard, nil
+}
+
+func (m *memory) SaveRateCard(_ context.Context, rateCard *model.RateCard) error {
+	m.rateCards[rateCard.CityID] = *rateCard
+	return nil
+}
+
+func (m *memory) MerchantOrderExists(_ context.Context, _ int64, merchantOrderID string) (bool, error) {
+	return m.orders[merchantOrderID], nil
+}
+
+// memoryOrders creates the orders of memory.
+type memoryOrders struct {
+	service.IOrder
+	*memory
+}
+
+func (m memoryOrders) CreateOrder(_ context.Context, order *model.Order) (*model.CreateOrderResponse, error) {
+	m.orders[order.MerchantOrderID] = true
+	return &model.CreateOrderResponse{}, nil
+}
+
+func TestSeedTwice(t *testing.T) {
+	fixtures, err := ReadFile(filepath.Join("..", "..", "sql", "fixtures", "development.yaml"))
+	require.NoError(t, err)
+	m := &memory{rateCards: map[int64]model.RateCard{}, orders: map[string]bool{}}
+	seeder := New(&InitSeeder{UserRepository: m, StoreRepository: m, OrderRepository: m, OrderService: memoryOrders{memory: m}, Log: log.New()})
+
+	res, err := seeder.Seed(context.Background(), fixtures)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Created: 6}, res)
+
+	res, err = seeder.Seed(context.Background(), fixtures)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Skipped: 6}, res, "nothing is created again")
+
+	fixtures.RateCards[0].BaseDeliveryFee++
+	res, err = seeder.Seed(context.Background(), fixtures)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Updated: 1, Skipped: 5}, res)
+
+	// only a missing user is created
+	m.failLookups = true
+	res, err = seeder.Seed(context.Background(), fixtures)
+	assert.Error(t, err)
+	assert.Zero(t, res.Created)
+	assert.Len(t, m.users, 2)
+}
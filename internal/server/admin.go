@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/kaium123/order/internal/common"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/metrics"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// adminServer is the admin server exposing the metrics, it's kept apart from
+// the API so it's not reachable by the clients.
+type adminServer struct {
+	port   int
+	engine *echo.Echo
+	log    *log.Logger
+}
+
+// AdminServerOpts is the options for the adminServer
+type AdminServerOpts struct {
+	ListenPort int
+}
+
+type InitNewAdmin struct {
+	AdminServerOpts AdminServerOpts
+	Log             *log.Logger
+}
+
+// NewAdmin returns a new instance of the admin server
+func NewAdmin(ctx context.Context, init *InitNewAdmin) Server {
+
+	engine := echo.New()
+	engine.HideBanner = true
+	engine.HidePort = true
+
+	engine.GET("/metrics", echo.WrapHandler(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+		Registry: metrics.Registry,
+	})))
+
+	s := &adminServer{
+		port:   init.AdminServerOpts.ListenPort,
+		engine: engine,
+		log:    init.Log,
+	}
+
+	return s
+}
+
+func (s *adminServer) Name() string {
+	return "adminServer"
+}
+
+func (s *adminServer) Run() error {
+	s.log.Info(context.Background(), fmt.Sprintf("%s %s serving on port %d", s.Name(), common.GetVersion(), s.port))
+	return s.engine.Start(fmt.Sprintf(":%d", s.port))
+}
+
+func (s *adminServer) Shutdown(ctx context.Context) error {
+	s.log.Info(context.Background(), fmt.Sprintf("shuting down %s %s serving on port %d", s.Name(), common.GetVersion(), s.port))
+	return s.engine.Shutdown(ctx)
+}
@@ -12,18 +12,26 @@ import (
 	"github.com/kaium123/order/internal/db/bundb"
 	"github.com/kaium123/order/internal/handler"
 	"github.com/kaium123/order/internal/log"
+	ordermiddleware "github.com/kaium123/order/internal/middleware"
+	"github.com/kaium123/order/internal/stream"
+	migrations "github.com/kaium123/order/sql"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"go.uber.org/zap"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // orderAPIServer is the API server for Order
 type orderAPIServer struct {
-	port   int
-	engine *echo.Echo
-	log    *log.Logger
-	db     *db.DB
+	port       int
+	engine     *echo.Echo
+	log        *log.Logger
+	db         *db.DB
+	drainDelay time.Duration
+	draining   atomic.Bool
+	streamHub  *stream.Hub
 }
 
 // OrderAPIServerOpts is the options for the OrderAPIServer
@@ -34,6 +42,7 @@ type OrderAPIServerOpts struct {
 
 type InitNewAPI struct {
 	OrderAPIServerOpts OrderAPIServerOpts
+	Reloader           *config.Reloader
 	Log                *log.Logger
 }
 
@@ -83,39 +92,72 @@ func NewAPI(ctx context.Context, init *InitNewAPI) (Server, error) {
 	// Singleton Redis client instance
 	redisClient := getRedisClientInstance(init.OrderAPIServerOpts.Config.Redis)
 
+	latestMigration, err := db.LatestVersion(migrations.GetMigrations())
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize Echo server
 	engine := echo.New()
 	engine.HideBanner = true
 	engine.HidePort = true
 
+	// Create API server instance
+	s := &orderAPIServer{
+		port:       init.OrderAPIServerOpts.ListenPort,
+		engine:     engine,
+		log:        init.Log,
+		db:         dbInstance,
+		drainDelay: init.OrderAPIServerOpts.Config.APIServer.DrainDelay,
+	}
+
+	// Fan out the order events to the order streams if enabled
+	conf := init.OrderAPIServerOpts.Config
+	if conf.Stream.Enable {
+		s.streamHub = stream.NewHub(&stream.InitHub{
+			Client:  redisClient,
+			Channel: conf.Outbox.Redis.Channel,
+			Config:  &conf.Stream,
+			Log:     init.Log.Named("stream"),
+		})
+		go s.streamHub.Run(context.Background())
+	}
+
 	// Register handlers
 	handler.Register(&handler.ServiceRegistry{
-		EchoEngine:  engine,
-		DBInstance:  dbInstance,
-		RedisClient: redisClient,
-		Log:         init.Log,
+		EchoEngine:      engine,
+		DBInstance:      dbInstance,
+		RedisClient:     redisClient,
+		Settings:        init.Reloader,
+		Log:             init.Log,
+		LatestMigration: latestMigration,
+		Draining:        s.draining.Load,
+		StreamHub:       s.streamHub,
+	})
+
+	// Rate limit follows the reloaded configuration
+	rateLimiter := ordermiddleware.NewRateLimiter(init.Reloader.Current().RateLimit)
+	init.Reloader.OnReload(func(conf *config.Config) {
+		rateLimiter.Update(conf.RateLimit)
 	})
 
 	// Add middleware
+	engine.Use(requestTracing())
+	engine.Use(ordermiddleware.RequestID())
 	engine.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.DELETE},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{echo.GET, echo.POST, echo.PUT, echo.DELETE},
+		AllowHeaders:  []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, echo.HeaderXRequestID},
+		ExposeHeaders: []string{echo.HeaderXRequestID},
 	}))
-	engine.Use(requestLogger())
-
-	// Create API server instance
-	s := &orderAPIServer{
-		port:   init.OrderAPIServerOpts.ListenPort,
-		engine: engine,
-		log:    init.Log,
-		db:     dbInstance,
-	}
+	engine.Use(requestLogger(s.log.Named("http")))
+	engine.Use(requestMetrics())
+	engine.Use(rateLimiter.Middleware())
 
 	// Ensure database connection is closed when the server shuts down
 	go func() {
 		<-ctx.Done()
-		dbInstance.DB.Close()
+		dbInstance.Close() //nolint
 	}()
 
 	return s, nil
@@ -131,8 +173,19 @@ func (s *orderAPIServer) Run() error {
 	return s.engine.Start(fmt.Sprintf(":%d", s.port))
 }
 
-// Shutdown stops the Order API server
+// Shutdown stops the Order API server. The server reports not ready during
+// the drain delay, so load balancers stop routing to it, then ends the order
+// streams, stops accepting connections and waits for the in-flight requests.
 func (s *orderAPIServer) Shutdown(ctx context.Context) error {
 	s.log.Info(context.Background(), fmt.Sprintf("shuting down %s %s serving on port %d", s.Name(), common.GetVersion(), s.port))
+	s.draining.Store(true)
+
+	select {
+	case <-time.After(s.drainDelay):
+	case <-ctx.Done():
+	}
+	if s.streamHub != nil {
+		s.streamHub.Close() //nolint
+	}
 	return s.engine.Shutdown(ctx)
 }
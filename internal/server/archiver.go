@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/kaium123/order/internal/archive"
+	"github.com/kaium123/order/internal/common"
+	"github.com/kaium123/order/internal/config"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/repository"
+)
+
+// archiverServer runs the archiver of the completed orders.
+type archiverServer struct {
+	archiver *archive.Archiver
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	log      *log.Logger
+}
+
+// ArchiverOpts is the options for the archiverServer
+type ArchiverOpts struct {
+	Config config.Config
+}
+
+type InitNewArchiver struct {
+	ArchiverOpts ArchiverOpts
+	Log          *log.Logger
+}
+
+// NewArchiver returns a new instance of the archiver, sharing the database
+// instance of the API server.
+func NewArchiver(ctx context.Context, init *InitNewArchiver) (Server, error) {
+	conf := init.ArchiverOpts.Config
+
+	dbInstance, err := getDatabaseInstance(ctx, conf.DB, init.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := init.Log.Named("archiver")
+	archiverCtx, cancel := context.WithCancel(context.Background())
+	return &archiverServer{
+		archiver: archive.NewArchiver(&archive.InitArchiver{
+			OrderRepository: repository.NewOrder(&repository.InitOrderRepository{Db: dbInstance, Log: logger}),
+			Config:          &conf.Archive,
+			Log:             logger,
+		}),
+		ctx:    archiverCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		log:    init.Log,
+	}, nil
+}
+
+func (s *archiverServer) Name() string {
+	return "orderArchiver"
+}
+
+// Run archives the completed orders until Shutdown.
+func (s *archiverServer) Run() error {
+	defer close(s.done)
+
+	s.log.Info(s.ctx, fmt.Sprintf("%s %s archiving the completed orders", s.Name(), common.GetVersion()))
+	s.archiver.Run(s.ctx)
+	return nil
+}
+
+// Shutdown stops the archiver, a batch interrupted is rolled back and
+// archived on the next run.
+func (s *archiverServer) Shutdown(ctx context.Context) error {
+	s.log.Info(ctx, fmt.Sprintf("shuting down %s %s", s.Name(), common.GetVersion()))
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
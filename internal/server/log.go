@@ -1,30 +1,29 @@
 package server
 
 import (
+	"github.com/kaium123/order/internal/log"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	log "github.com/sirupsen/logrus"
+	"go.uber.org/zap"
 )
 
-func writeRequestLogJSON(_ echo.Context, v middleware.RequestLoggerValues) error {
-	log.WithFields(log.Fields{
-		"method":         v.Method,
-		"host":           v.Host,
-		"path":           v.URIPath,
-		"remote_ip":      v.RemoteIP,
-		"user_agent":     v.UserAgent,
-		"protocol":       v.Protocol,
-		"status":         v.Status,
-		"latency":        v.Latency,
-		"content_length": v.ContentLength,
-		"response_size":  v.ResponseSize,
-	}).Info("finished")
-	return nil
-}
-
-func requestLogger() echo.MiddlewareFunc {
+func requestLogger(logger *log.Logger) echo.MiddlewareFunc {
 	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
-		LogValuesFunc:    writeRequestLogJSON,
+		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			logger.Info(c.Request().Context(), "finished",
+				zap.String("method", v.Method),
+				zap.String("host", v.Host),
+				zap.String("path", v.URIPath),
+				zap.String("remote_ip", v.RemoteIP),
+				zap.String("user_agent", v.UserAgent),
+				zap.String("protocol", v.Protocol),
+				zap.Int("status", v.Status),
+				zap.Duration("latency", v.Latency),
+				zap.String("content_length", v.ContentLength),
+				zap.Int64("response_size", v.ResponseSize),
+			)
+			return nil
+		},
 		LogMethod:        true,
 		LogHost:          true,
 		LogURIPath:       true,
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/kaium123/order/internal/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// requestMetrics observes the count and latency of the requests by method,
+// route and status. Unmatched requests share the "unmatched" route so that
+// arbitrary paths don't create new series.
+func requestMetrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				c.Error(err) // write the response to get the final status
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			labels := []string{c.Request().Method, route, strconv.Itoa(c.Response().Status)}
+			metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/kaium123/order/internal/common"
+	"github.com/kaium123/order/internal/config"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/outbox"
+	"github.com/kaium123/order/internal/repository"
+	"github.com/kaium123/order/internal/webhook"
+)
+
+// relayServer runs the outbox relay, publishing the order events to the
+// sinks.
+type relayServer struct {
+	relay  *outbox.Relay
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	log    *log.Logger
+}
+
+// RelayOpts is the options for the relayServer
+type RelayOpts struct {
+	Config config.Config
+}
+
+type InitNewRelay struct {
+	RelayOpts RelayOpts
+	Log       *log.Logger
+}
+
+// NewRelay returns a new instance of the outbox relay, sharing the database
+// and Redis instances of the API server. The webhook deliveries are enqueued
+// by a sink of the relay.
+func NewRelay(ctx context.Context, init *InitNewRelay) (Server, error) {
+	conf := init.RelayOpts.Config
+
+	dbInstance, err := getDatabaseInstance(ctx, conf.DB, init.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	sinks, err := outbox.NewSinks(&conf.Outbox, getRedisClientInstance(conf.Redis))
+	if err != nil {
+		return nil, err
+	}
+
+	logger := init.Log.Named("outbox")
+	if conf.Webhook.Enable {
+		sinks = append(sinks, webhook.NewSink(repository.NewWebhook(&repository.InitWebhookRepository{Db: dbInstance, Log: logger})))
+	}
+
+	relayCtx, cancel := context.WithCancel(context.Background())
+	return &relayServer{
+		relay: outbox.NewRelay(&outbox.InitRelay{
+			OutboxRepository: repository.NewOutbox(&repository.InitOutboxRepository{Db: dbInstance, Log: logger}),
+			Sinks:            sinks,
+			Config:           &conf.Outbox,
+			Log:              logger,
+		}),
+		ctx:    relayCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		log:    init.Log,
+	}, nil
+}
+
+func (s *relayServer) Name() string {
+	return "outboxRelay"
+}
+
+// Run relays the events until Shutdown.
+func (s *relayServer) Run() error {
+	defer close(s.done)
+
+	s.log.Info(s.ctx, fmt.Sprintf("%s %s relaying the outbox events", s.Name(), common.GetVersion()))
+	s.relay.Run(s.ctx)
+	return s.relay.Close()
+}
+
+// Shutdown stops the relay, a batch interrupted is published again by the
+// next relay.
+func (s *relayServer) Shutdown(ctx context.Context) error {
+	s.log.Info(ctx, fmt.Sprintf("shuting down %s %s", s.Name(), common.GetVersion()))
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -33,7 +33,7 @@ func NewSwagger(ctx context.Context, init *InitNewSwagger) Server {
 	engine.HideBanner = true
 	engine.HidePort = true
 
-	engine.Use(requestLogger())
+	engine.Use(requestLogger(init.Log.Named("http")))
 
 	engine.GET("/swagger/*", echoSwagger.WrapHandler)
 
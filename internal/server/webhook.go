@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/kaium123/order/internal/common"
+	"github.com/kaium123/order/internal/config"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/repository"
+	"github.com/kaium123/order/internal/webhook"
+)
+
+// webhookServer runs the dispatcher attempting the webhook deliveries.
+type webhookServer struct {
+	dispatcher *webhook.Dispatcher
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	log        *log.Logger
+}
+
+// WebhookOpts is the options for the webhookServer
+type WebhookOpts struct {
+	Config config.Config
+}
+
+type InitNewWebhook struct {
+	WebhookOpts WebhookOpts
+	Log         *log.Logger
+}
+
+// NewWebhook returns a new instance of the webhook dispatcher, sharing the
+// database instance of the API server.
+func NewWebhook(ctx context.Context, init *InitNewWebhook) (Server, error) {
+	conf := init.WebhookOpts.Config
+
+	dbInstance, err := getDatabaseInstance(ctx, conf.DB, init.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := init.Log.Named("webhook")
+	dispatcherCtx, cancel := context.WithCancel(context.Background())
+	return &webhookServer{
+		dispatcher: webhook.NewDispatcher(&webhook.InitDispatcher{
+			WebhookRepository: repository.NewWebhook(&repository.InitWebhookRepository{Db: dbInstance, Log: logger}),
+			Client:            webhook.NewClient(&conf.Webhook),
+			Config:            &conf.Webhook,
+			Log:               logger,
+		}),
+		ctx:    dispatcherCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		log:    init.Log,
+	}, nil
+}
+
+func (s *webhookServer) Name() string {
+	return "webhookDispatcher"
+}
+
+// Run attempts the deliveries until Shutdown.
+func (s *webhookServer) Run() error {
+	defer close(s.done)
+
+	s.log.Info(s.ctx, fmt.Sprintf("%s %s dispatching the webhook deliveries", s.Name(), common.GetVersion()))
+	s.dispatcher.Run(s.ctx)
+	return nil
+}
+
+// Shutdown stops the dispatcher, the attempts interrupted are made again
+// once their lease expires.
+func (s *webhookServer) Shutdown(ctx context.Context) error {
+	s.log.Info(ctx, fmt.Sprintf("shuting down %s %s", s.Name(), common.GetVersion()))
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
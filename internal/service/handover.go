@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/metrics"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/repository"
+)
+
+// IHandover is the service recording the handovers of the orders from the
+// merchant to the recipient.
+type IHandover interface {
+	// HandOver records the handover of req, see
+	// repository.IHandover.RecordHandover.
+	HandOver(ctx context.Context, req *model.HandoverRequest, to model.TransferStatus) (*model.HandoverResponse, error)
+	FindHandovers(ctx context.Context, req *model.FindHandoversRequest) ([]*model.HandoverResponse, error)
+}
+
+type HandoverReceiver struct {
+	log                *log.Logger
+	handoverRepository repository.IHandover
+}
+
+type InitHandoverService struct {
+	Log                *log.Logger
+	HandoverRepository repository.IHandover
+}
+
+// NewHandover creates a new Handover service.
+func NewHandover(initHandoverService *InitHandoverService) IHandover {
+	return &HandoverReceiver{
+		log:                initHandoverService.Log,
+		handoverRepository: initHandoverService.HandoverRepository,
+	}
+}
+
+func (h *HandoverReceiver) HandOver(ctx context.Context, req *model.HandoverRequest, to model.TransferStatus) (*model.HandoverResponse, error) {
+	handover, err := h.handoverRepository.RecordHandover(ctx, req, to)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.OrderHandovers.WithLabelValues(to.String()).Inc()
+	return model.NewHandoverResponse(handover), nil
+}
+
+func (h *HandoverReceiver) FindHandovers(ctx context.Context, req *model.FindHandoversRequest) ([]*model.HandoverResponse, error) {
+	handovers, err := h.handoverRepository.FindHandovers(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*model.HandoverResponse, 0, len(handovers))
+	for _, handover := range handovers {
+		responses = append(responses, model.NewHandoverResponse(handover))
+	}
+	return responses, nil
+}
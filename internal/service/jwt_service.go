@@ -2,12 +2,13 @@ package service
 
 import (
 	"github.com/golang-jwt/jwt"
+	"github.com/kaium123/order/internal/model"
 	"time"
 )
 
 // IJWTService defines the methods that our JWT service should implement.
 type IJWTService interface {
-	GenerateAccessToken(userID int64) (string, error)
+	GenerateAccessToken(userID int64, role model.Role) (string, error)
 	GenerateRefreshToken(userID int64) (string, error)
 }
 
@@ -22,9 +23,10 @@ func NewJWTService(secretKey string) IJWTService {
 }
 
 // GenerateAccessToken generates an access token for the user.
-func (s *JWTService) GenerateAccessToken(userID int64) (string, error) {
+func (s *JWTService) GenerateAccessToken(userID int64, role model.Role) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,                               // Subject is the user ID
+		"role":    string(role),                         // Permissions of the user
 		"exp":     time.Now().Add(time.Hour * 1).Unix(), // Expiry time set to 1 hour
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -4,8 +4,11 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"github.com/kaium123/order/internal/encryption"
 	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/metrics"
 	"github.com/kaium123/order/internal/model"
 	"github.com/kaium123/order/internal/repository"
 	"math/big"
@@ -15,49 +18,89 @@ import (
 // Order is the service for the Order endpoint.
 type IOrder interface {
 	CreateOrder(ctx context.Context, reqOrder *model.Order) (*model.CreateOrderResponse, error)
+	// UpdateOrder edits an order pending at the merchant and calculates its
+	// fees again. It returns model.ErrVersionConflict for an edition of a
+	// version changed since, model.ErrNotEditable for an order picked up and
+	// a *model.InvalidOrderError for an edition leaving the order invalid.
+	UpdateOrder(ctx context.Context, reqParams *model.UpdateOrderRequest) (*model.OrderResponse, error)
+	FindOrderHistory(ctx context.Context, reqParams *model.FindHistoryRequest) ([]*model.OrderHistory, error)
+	// FindOrder returns an order with its delivery attempts.
+	FindOrder(ctx context.Context, reqParams *model.FindOrderRequest) (*model.OrderDetailResponse, error)
+	// RecordAttempt records a delivery attempt of an order out for delivery,
+	// see repository.IDelivery.RecordAttempt. The RTO fee follows the
+	// pricing of the city of the order.
+	RecordAttempt(ctx context.Context, reqParams *model.AttemptRequest) (*model.DeliveryAttemptResponse, error)
+	// CancelOrder cancels an order pending at the merchant for a reason of
+	// the cancellation rules. It returns a *model.InvalidOrderError for an
+	// unknown reason, model.ErrAlreadyCancelled or model.ErrNotCancellable
+	// for an order which can't be cancelled.
 	CancelOrder(ctx context.Context, reqParams *model.OrderCancelRequest) error
+	ArchiveOrders(ctx context.Context, reqParams *model.ArchiveRequest) (*model.ArchiveResponse, error)
 	FindAllOrders(ctx context.Context, reqParams *model.FindAllRequest) (*model.FindAllResponse, error)
+	SearchOrders(ctx context.Context, reqParams *model.SearchOrdersRequest) (*model.SearchOrdersResponse, error)
 }
 
 type OrderReceiver struct {
-	log             *log.Logger
-	OrderRepository repository.IOrder
-	redisCache      repository.IRedisCache
+	log                *log.Logger
+	OrderRepository    repository.IOrder
+	storeRepository    repository.IStore
+	deliveryRepository repository.IDelivery
+	redisCache         repository.IRedisCache
+	pricing            func() model.Pricing
+	cancellation       func() model.Cancellation
+	delivery           func() model.DeliveryRules
+	features           func() model.Features
 }
 
 type InitOrderService struct {
-	Log             *log.Logger
-	OrderRepository repository.IOrder
-	RedisCache      repository.IRedisCache
+	Log                *log.Logger
+	OrderRepository    repository.IOrder
+	StoreRepository    repository.IStore
+	DeliveryRepository repository.IDelivery
+	RedisCache         repository.IRedisCache
+	// Pricing returns the current pricing parameters, it's called for every
+	// order so reloaded parameters apply to the next order.
+	Pricing func() model.Pricing
+	// Cancellation returns the current cancellation rules.
+	Cancellation func() model.Cancellation
+	// Delivery returns the current rules of the delivery attempts.
+	Delivery func() model.DeliveryRules
+	// Features returns the current feature flags.
+	Features func() model.Features
 }
 
 // NewOrder creates a new Order service.
 func NewOrder(initOrderService *InitOrderService) IOrder {
 	return &OrderReceiver{
-		log:             initOrderService.Log,
-		OrderRepository: initOrderService.OrderRepository,
-		redisCache:      initOrderService.RedisCache,
+		log:                initOrderService.Log,
+		OrderRepository:    initOrderService.OrderRepository,
+		storeRepository:    initOrderService.StoreRepository,
+		deliveryRepository: initOrderService.DeliveryRepository,
+		redisCache:         initOrderService.RedisCache,
+		pricing:            initOrderService.Pricing,
+		cancellation:       initOrderService.Cancellation,
+		delivery:           initOrderService.Delivery,
+		features:           initOrderService.Features,
 	}
 }
 func (o *OrderReceiver) CreateOrder(ctx context.Context, reqOrder *model.Order) (*model.CreateOrderResponse, error) {
 	// Generate consignment ID
 	consignmentID := GenerateConsignmentID("DA", 6)
 	reqOrder.OrderConsignmentID = consignmentID
-	if reqOrder.RecipientCity == 1 {
-		reqOrder.CalculateDeliveryFee(60)
-	} else {
-		reqOrder.CalculateDeliveryFee(100)
-	}
 
-	reqOrder.CalculateCodFee()
-	reqOrder.CalculateTotalFee()
+	pricing, err := o.cityPricing(ctx, reqOrder.RecipientCity)
+	if err != nil {
+		return nil, err
+	}
+	reqOrder.ApplyPricing(pricing)
 	reqOrder.OrderStatus = model.Pending
 	reqOrder.DeliveryType = model.Delivery
 	reqOrder.ItemType = model.Parcel
 	reqOrder.OrderTypeID = 1
 	reqOrder.OrderType = model.Delivery
-	reqOrder.TransferStatus = 1
-	reqOrder.Archive = 0
+	reqOrder.TransferStatus = model.AtMerchant
+	reqOrder.Archive = model.NotArchived
+	reqOrder.Version = 1
 
 	// Create the order in the repository (DB)
 	order, err := o.OrderRepository.CreateOrder(ctx, reqOrder)
@@ -66,6 +109,9 @@ func (o *OrderReceiver) CreateOrder(ctx context.Context, reqOrder *model.Order)
 		return nil, err
 	}
 
+	metrics.OrdersCreated.Inc()
+	metrics.CODAmountBooked.Add(order.AmountToCollect)
+
 	// Cache the order in Redis
 	err = o.redisCache.CacheOrder(ctx, *order)
 	if err != nil {
@@ -81,13 +127,122 @@ func (o *OrderReceiver) CreateOrder(ctx context.Context, reqOrder *model.Order)
 	}, nil
 }
 
+// cityPricing returns the configured pricing, overridden by the rate card of
+// given city if any.
+func (o *OrderReceiver) cityPricing(ctx context.Context, cityID int64) (model.Pricing, error) {
+	pricing := o.pricing()
+
+	rateCard, err := o.storeRepository.FindRateCardByCity(ctx, cityID)
+	if errors.Is(err, model.ErrNotFound) {
+		return pricing, nil
+	}
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return pricing, err
+	}
+
+	return rateCard.Pricing(pricing), nil
+}
+
+func (o *OrderReceiver) UpdateOrder(ctx context.Context, reqParams *model.UpdateOrderRequest) (*model.OrderResponse, error) {
+	order, err := o.OrderRepository.FindOrder(ctx, reqParams.UserId, reqParams.ConsignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Version != reqParams.Version {
+		return nil, model.ErrVersionConflict
+	}
+	if !order.Editable() {
+		return nil, model.ErrNotEditable
+	}
+
+	before := *order
+	reqParams.Apply(order)
+	if validationErr := order.Validate(); validationErr != nil {
+		return nil, &model.InvalidOrderError{Response: validationErr}
+	}
+	pricing, err := o.cityPricing(ctx, order.RecipientCity)
+	if err != nil {
+		return nil, err
+	}
+	order.ApplyPricing(pricing)
+
+	changes := model.DiffOrders(&before, order)
+	if len(changes) == 0 {
+		return newOrderResponse(order), nil
+	}
+	err = o.OrderRepository.UpdateOrder(ctx, order, reqParams.Version, reqParams.UserId, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.redisCache.CacheOrder(ctx, *order)
+	if err != nil {
+		o.log.Error(ctx, fmt.Sprintf("Failed to cache order with ID %s: %v", order.OrderConsignmentID, err))
+	}
+	return newOrderResponse(order), nil
+}
+
+func (o *OrderReceiver) FindOrderHistory(ctx context.Context, reqParams *model.FindHistoryRequest) ([]*model.OrderHistory, error) {
+	return o.OrderRepository.FindOrderHistory(ctx, reqParams)
+}
+
+func (o *OrderReceiver) FindOrder(ctx context.Context, reqParams *model.FindOrderRequest) (*model.OrderDetailResponse, error) {
+	order, err := o.OrderRepository.FindOrder(ctx, reqParams.OwnerId, reqParams.ConsignmentID)
+	if err != nil {
+		return nil, err
+	}
+	attempts, err := o.deliveryRepository.FindAttempts(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.OrderDetailResponse{
+		OrderResponse: newOrderResponse(order),
+		UserID:        order.UserID,
+		RTOFee:        order.RTOFee,
+		Attempts:      make([]*model.DeliveryAttemptResponse, 0, len(attempts)),
+	}
+	for _, attempt := range attempts {
+		if attempt.Outcome == model.AttemptFailed {
+			response.FailedAttempts++
+		}
+		response.Attempts = append(response.Attempts, model.NewDeliveryAttemptResponse(attempt))
+	}
+	return response, nil
+}
+
+func (o *OrderReceiver) RecordAttempt(ctx context.Context, reqParams *model.AttemptRequest) (*model.DeliveryAttemptResponse, error) {
+	order, err := o.OrderRepository.FindOrder(ctx, reqParams.OwnerId, reqParams.ConsignmentID)
+	if err != nil {
+		return nil, err
+	}
+	pricing, err := o.cityPricing(ctx, order.RecipientCity)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt, err := o.deliveryRepository.RecordAttempt(ctx, reqParams, o.delivery(), pricing)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.DeliveryAttempts.WithLabelValues(attempt.Outcome).Inc()
+	return model.NewDeliveryAttemptResponse(attempt), nil
+}
+
 func (o *OrderReceiver) CancelOrder(ctx context.Context, reqParams *model.OrderCancelRequest) error {
+	if validationErr := reqParams.Validate(o.cancellation()); validationErr != nil {
+		return &model.InvalidOrderError{Response: validationErr}
+	}
+
 	err := o.OrderRepository.CancelOrder(ctx, reqParams)
 	if err != nil {
-		o.log.Error(ctx, err.Error())
 		return err
 	}
 
+	metrics.OrdersCancelled.WithLabelValues(reqParams.Reason).Inc()
+
 	err = o.redisCache.CancelOrder(ctx, reqParams)
 	if err != nil {
 		o.log.Error(ctx, err.Error())
@@ -95,6 +250,19 @@ func (o *OrderReceiver) CancelOrder(ctx context.Context, reqParams *model.OrderC
 	return nil
 }
 
+func (o *OrderReceiver) ArchiveOrders(ctx context.Context, reqParams *model.ArchiveRequest) (*model.ArchiveResponse, error) {
+	updated, err := o.OrderRepository.ArchiveOrders(ctx, reqParams)
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	if reqParams.Archive {
+		metrics.OrdersArchived.WithLabelValues(metrics.ArchivedByMerchant).Add(float64(updated))
+	}
+	return &model.ArchiveResponse{Updated: updated}, nil
+}
+
 func (o *OrderReceiver) FindAllOrders(ctx context.Context, reqParams *model.FindAllRequest) (*model.FindAllResponse, error) {
 	//allOrders, err := o.redisCache.FindAllOrders(ctx, reqParams)
 	//if err != nil {
@@ -111,36 +279,24 @@ func (o *OrderReceiver) FindAllOrders(ctx context.Context, reqParams *model.Find
 	}
 
 	response := &model.FindAllResponse{
-		Total:       paginationResponse.Total,
-		CurrentPage: paginationResponse.CurrentPage,
-		PerPage:     paginationResponse.PerPage,
-		TotalInPage: paginationResponse.TotalInPage,
-		LastPage:    paginationResponse.LastPage,
+		TotalEstimated: paginationResponse.TotalEstimated,
+		PerPage:        paginationResponse.PerPage,
+		TotalInPage:    paginationResponse.TotalInPage,
+		NextCursor:     paginationResponse.NextCursor,
+		PrevCursor:     paginationResponse.PrevCursor,
 	}
-
-	for _, order := range orders {
-		orderResponse := &model.OrderResponse{
-			OrderConsignmentID: order.OrderConsignmentID,
-			OrderCreatedAt:     order.CreatedAt,
-			OrderDescription:   order.ItemDescription,
-			MerchantOrderID:    order.MerchantOrderID,
-			RecipientName:      order.RecipientName,
-			RecipientAddress:   order.RecipientAddress,
-			RecipientPhone:     order.RecipientPhone,
-			OrderAmount:        order.AmountToCollect,
-			TotalFee:           order.TotalFee,
-			Instruction:        order.SpecialInstruction,
-			OrderTypeID:        order.OrderTypeID,
-			CODFee:             order.CodFee,
-			PromoDiscount:      order.PromoDiscount,
-			Discount:           order.Discount,
-			DeliveryFee:        order.DeliveryFee,
-			OrderStatus:        order.OrderStatus.String(),
-			OrderType:          order.DeliveryType.String(),
-			ItemType:           order.ItemType.String(),
+	if reqParams.Count != model.CountNone {
+		response.Total = &paginationResponse.Total
+	}
+	if !reqParams.Keyset {
+		response.CurrentPage = &paginationResponse.CurrentPage
+		if reqParams.Count != model.CountNone {
+			response.LastPage = &paginationResponse.LastPage
 		}
+	}
 
-		response.Orders = append(response.Orders, orderResponse)
+	for _, order := range orders {
+		response.Orders = append(response.Orders, newOrderResponse(order))
 	}
 
 	return response, nil
@@ -172,3 +328,69 @@ func generateRandomAlphanumeric(length int) string {
 	}
 	return string(result)
 }
+
+func (o *OrderReceiver) SearchOrders(ctx context.Context, reqParams *model.SearchOrdersRequest) (*model.SearchOrdersResponse, error) {
+	rows, err := o.OrderRepository.SearchOrders(ctx, reqParams)
+	if err != nil {
+		o.log.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	response := &model.SearchOrdersResponse{
+		Orders:      []*model.OrderSearchResult{},
+		CurrentPage: reqParams.Offset/reqParams.Limit + 1,
+		PerPage:     reqParams.Limit,
+		TotalInPage: len(rows),
+	}
+	highlight := o.features().Enabled(model.FeatureSearchHighlights)
+	// the encrypted addresses aren't searched, they aren't highlighted either
+	highlightAddress := !encryption.Default().Enabled()
+	for _, row := range rows {
+		var highlights *model.OrderSearchHighlights
+		if highlight {
+			highlights = &model.OrderSearchHighlights{
+				RecipientName:      model.HighlightMatches(row.RecipientName, reqParams.Query),
+				MerchantOrderID:    model.HighlightMatches(row.MerchantOrderID, reqParams.Query),
+				ItemDescription:    model.HighlightMatches(row.ItemDescription, reqParams.Query),
+				OrderConsignmentID: model.HighlightMatches(row.OrderConsignmentID, reqParams.Query),
+			}
+			if highlightAddress {
+				highlights.RecipientAddress = model.HighlightMatches(string(row.RecipientAddress), reqParams.Query)
+			}
+		}
+		response.Orders = append(response.Orders, &model.OrderSearchResult{
+			OrderResponse: newOrderResponse(&row.Order),
+			UserID:        row.UserID,
+			Rank:          row.Rank,
+			Highlights:    highlights,
+		})
+	}
+
+	return response, nil
+}
+
+// newOrderResponse returns the listing entry of given order.
+func newOrderResponse(order *model.Order) *model.OrderResponse {
+	return &model.OrderResponse{
+		OrderConsignmentID: order.OrderConsignmentID,
+		OrderCreatedAt:     order.CreatedAt,
+		OrderDescription:   order.ItemDescription,
+		MerchantOrderID:    order.MerchantOrderID,
+		RecipientName:      order.RecipientName,
+		RecipientAddress:   string(order.RecipientAddress),
+		RecipientPhone:     string(order.RecipientPhone),
+		OrderAmount:        order.AmountToCollect,
+		TotalFee:           order.TotalFee,
+		Instruction:        order.SpecialInstruction,
+		OrderTypeID:        order.OrderTypeID,
+		CODFee:             order.CodFee,
+		PromoDiscount:      order.PromoDiscount,
+		Discount:           order.Discount,
+		DeliveryFee:        order.DeliveryFee,
+		OrderStatus:        order.OrderStatus.String(),
+		TransferStatus:     order.TransferStatus.String(),
+		Version:            order.Version,
+		OrderType:          order.DeliveryType.String(),
+		ItemType:           order.ItemType.String(),
+	}
+}
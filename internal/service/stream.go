@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/repository"
+	"github.com/kaium123/order/internal/stream"
+)
+
+// IOrderStream is the service of the order streams of the users.
+type IOrderStream interface {
+	// Subscribe returns the subscription to the events of given user as they
+	// are relayed.
+	Subscribe(userID int64) *stream.Subscription
+	// Replay returns the events of given user after the event of given
+	// transaction and ID, see repository.IOutbox.FindUserEvents.
+	Replay(ctx context.Context, userID int64, afterTxID string, afterID int64) (events []*model.OutboxEvent, truncated bool, err error)
+}
+
+type OrderStreamReceiver struct {
+	log              *log.Logger
+	hub              *stream.Hub
+	outboxRepository repository.IOutbox
+	conf             *stream.Config
+}
+
+type InitOrderStreamService struct {
+	Log              *log.Logger
+	Hub              *stream.Hub
+	OutboxRepository repository.IOutbox
+	Config           *stream.Config
+}
+
+// NewOrderStream creates a new OrderStream service.
+func NewOrderStream(initOrderStreamService *InitOrderStreamService) IOrderStream {
+	return &OrderStreamReceiver{
+		log:              initOrderStreamService.Log,
+		hub:              initOrderStreamService.Hub,
+		outboxRepository: initOrderStreamService.OutboxRepository,
+		conf:             initOrderStreamService.Config,
+	}
+}
+
+func (o *OrderStreamReceiver) Subscribe(userID int64) *stream.Subscription {
+	return o.hub.Subscribe(userID)
+}
+
+func (o *OrderStreamReceiver) Replay(ctx context.Context, userID int64, afterTxID string, afterID int64) ([]*model.OutboxEvent, bool, error) {
+	if o.conf.ReplayLimit <= 0 {
+		return nil, true, nil
+	}
+	return o.outboxRepository.FindUserEvents(ctx, userID, afterTxID, afterID, o.conf.ReplayLimit)
+}
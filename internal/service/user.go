@@ -65,7 +65,7 @@ func (u *UserReceiver) Login(ctx context.Context, reqLogin *model.UserLoginReque
 	}
 
 	// Generate JWT tokens (access and refresh tokens)
-	accessToken, err := u.jwtService.GenerateAccessToken(user.ID)
+	accessToken, err := u.jwtService.GenerateAccessToken(user.ID, user.Role)
 	if err != nil {
 		u.log.Error(ctx, err.Error())
 		return nil, err
@@ -149,20 +149,29 @@ func (u *UserReceiver) Logout(ctx context.Context, userID int64) error {
 		key := fmt.Sprintf("access_token:%s", accessToken.Token)
 		err := u.redisCache.DeleteKey(ctx, key)
 		if err != nil {
-			u.log.Error(ctx, fmt.Sprintf("Failed to invalidate session for user %s: %v", userID, err))
+			u.log.Error(ctx, fmt.Sprintf("Failed to invalidate session for user %d: %v", userID, err))
 		}
 	}
 	for _, refreshToken := range refreshTokens {
 		key := fmt.Sprintf("refresh_token:%s", refreshToken.Token)
 		err := u.redisCache.DeleteKey(ctx, key)
 		if err != nil {
-			u.log.Error(ctx, fmt.Sprintf("Failed to invalidate session for user %s: %v", userID, err))
+			u.log.Error(ctx, fmt.Sprintf("Failed to invalidate session for user %d: %v", userID, err))
 		}
 	}
 
 	return nil
 }
 
+// HashPassword returns the bcrypt hash of given password.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
 // CheckPasswordHash compares the provided password with the stored hash.
 func CheckPasswordHash(providedPassword, storedHash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(providedPassword))
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/kaium123/order/internal/encryption"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/repository"
+	"github.com/kaium123/order/internal/webhook"
+)
+
+// IWebhook is the service managing the webhooks of the stores of a user.
+// Stores of other users are not found.
+type IWebhook interface {
+	CreateWebhook(ctx context.Context, req *model.CreateWebhookRequest) (*model.CreateWebhookResponse, error)
+	FindWebhooks(ctx context.Context, req *model.WebhookRequest) ([]*model.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, req *model.WebhookRequest) error
+	FindDeliveries(ctx context.Context, req *model.FindDeliveriesRequest) (*model.FindDeliveriesResponse, error)
+	ReplayDelivery(ctx context.Context, req *model.WebhookDeliveryRequest) error
+	// SendTestEvent delivers a webhook.test event right away and returns the
+	// delivery, retried like the others if it failed.
+	SendTestEvent(ctx context.Context, req *model.WebhookRequest) (*model.WebhookDelivery, error)
+}
+
+type WebhookReceiver struct {
+	log               *log.Logger
+	webhookRepository repository.IWebhook
+	storeRepository   repository.IStore
+	client            *webhook.Client
+	conf              *webhook.Config
+}
+
+type InitWebhookService struct {
+	Log               *log.Logger
+	WebhookRepository repository.IWebhook
+	StoreRepository   repository.IStore
+	Client            *webhook.Client
+	Config            *webhook.Config
+}
+
+// NewWebhook creates a new Webhook service.
+func NewWebhook(initWebhookService *InitWebhookService) IWebhook {
+	return &WebhookReceiver{
+		log:               initWebhookService.Log,
+		webhookRepository: initWebhookService.WebhookRepository,
+		storeRepository:   initWebhookService.StoreRepository,
+		client:            initWebhookService.Client,
+		conf:              initWebhookService.Config,
+	}
+}
+
+func (w *WebhookReceiver) CreateWebhook(ctx context.Context, req *model.CreateWebhookRequest) (*model.CreateWebhookResponse, error) {
+	if _, err := w.storeRepository.FindStore(ctx, req.UserId, req.StoreID); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		secret = generateWebhookSecret()
+	}
+	subscription, err := w.webhookRepository.CreateSubscription(ctx, &model.WebhookSubscription{
+		StoreID:    req.StoreID,
+		UserID:     req.UserId,
+		URL:        req.URL,
+		Secret:     encryption.String(secret),
+		EventTypes: req.EventTypes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CreateWebhookResponse{WebhookSubscription: subscription, Secret: secret}, nil
+}
+
+func (w *WebhookReceiver) FindWebhooks(ctx context.Context, req *model.WebhookRequest) ([]*model.WebhookSubscription, error) {
+	if _, err := w.storeRepository.FindStore(ctx, req.UserId, req.StoreID); err != nil {
+		return nil, err
+	}
+	return w.webhookRepository.FindSubscriptions(ctx, req.StoreID)
+}
+
+func (w *WebhookReceiver) DeleteWebhook(ctx context.Context, req *model.WebhookRequest) error {
+	if _, err := w.storeRepository.FindStore(ctx, req.UserId, req.StoreID); err != nil {
+		return err
+	}
+	return w.webhookRepository.DeleteSubscription(ctx, req.StoreID, req.ID)
+}
+
+func (w *WebhookReceiver) FindDeliveries(ctx context.Context, req *model.FindDeliveriesRequest) (*model.FindDeliveriesResponse, error) {
+	if _, err := w.subscription(ctx, &req.WebhookRequest); err != nil {
+		return nil, err
+	}
+
+	deliveries, paginationResponse, err := w.webhookRepository.FindDeliveries(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &model.FindDeliveriesResponse{Deliveries: deliveries, PaginationResponse: *paginationResponse}, nil
+}
+
+func (w *WebhookReceiver) ReplayDelivery(ctx context.Context, req *model.WebhookDeliveryRequest) error {
+	if _, err := w.subscription(ctx, &req.WebhookRequest); err != nil {
+		return err
+	}
+	return w.webhookRepository.ReplayDelivery(ctx, req.ID, req.DeliveryID)
+}
+
+func (w *WebhookReceiver) SendTestEvent(ctx context.Context, req *model.WebhookRequest) (*model.WebhookDelivery, error) {
+	subscription, err := w.subscription(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(&model.WebhookTestEvent{
+		StoreID: req.StoreID,
+		Message: "This is a test event.",
+		SentAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// leased like a dispatched delivery, so the dispatcher doesn't attempt it
+	// at the same time
+	delivery := &model.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventType:      model.EventWebhookTest,
+		Payload:        payload,
+		Status:         model.DeliveryPending,
+		NextAttemptAt:  time.Now().UTC().Add(2 * w.conf.Timeout),
+		CreatedAt:      time.Now().UTC(),
+	}
+	if err := w.webhookRepository.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	w.client.Deliver(ctx, subscription, delivery)
+	if err := w.webhookRepository.SaveAttempt(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// subscription returns the subscription of the request, checking the store
+// belongs to the user.
+func (w *WebhookReceiver) subscription(ctx context.Context, req *model.WebhookRequest) (*model.WebhookSubscription, error) {
+	if _, err := w.storeRepository.FindStore(ctx, req.UserId, req.StoreID); err != nil {
+		return nil, err
+	}
+	return w.webhookRepository.FindSubscription(ctx, req.StoreID, req.ID)
+}
+
+// generateWebhookSecret returns a random secret of 32 bytes, hex encoded.
+func generateWebhookSecret() string {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic("failed to generate a webhook secret")
+	}
+	return "whsec_" + hex.EncodeToString(secret)
+}
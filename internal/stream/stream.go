@@ -0,0 +1,184 @@
+// Package stream fans the order events out to the streams of the connected
+// users. Every API instance subscribes once to the Redis channel the outbox
+// relay publishes the events to, whichever instance relays them.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/metrics"
+	"github.com/kaium123/order/internal/model"
+	"go.uber.org/zap"
+)
+
+// Config of the order streams.
+type Config struct {
+	// Enable serves GET /api/v1/orders/stream, it requires the outbox relay
+	// to publish to outbox.redis.channel.
+	Enable bool `json:"enable" yaml:"enable" toml:"enable" mapstructure:"enable"`
+	// Heartbeat is the interval of the comments keeping idle streams open
+	// through the proxies.
+	Heartbeat time.Duration `json:"heartbeat" yaml:"heartbeat" toml:"heartbeat" mapstructure:"heartbeat"`
+	// ReplayLimit is the maximum number of events replayed on resume, the
+	// client is told to refetch the orders when it missed more.
+	ReplayLimit int `json:"replay_limit" yaml:"replay_limit" toml:"replay_limit" mapstructure:"replay_limit"`
+	// BufferSize is the number of events buffered per stream. A stream
+	// falling that far behind is closed, the client resumes it.
+	BufferSize int `json:"buffer_size" yaml:"buffer_size" toml:"buffer_size" mapstructure:"buffer_size"`
+}
+
+type InitHub struct {
+	Client  *redis.Client
+	Channel string
+	Config  *Config
+	Log     *log.Logger
+}
+
+// Hub dispatches the events received on the Redis channel to the
+// subscriptions of their users.
+type Hub struct {
+	client  *redis.Client
+	channel string
+	conf    *Config
+	log     *log.Logger
+
+	mu            sync.Mutex
+	pubsub        *redis.PubSub
+	subscriptions map[int64]map[*Subscription]struct{}
+	closed        bool
+}
+
+// NewHub returns a new Hub, not receiving until Run.
+func NewHub(initHub *InitHub) *Hub {
+	return &Hub{
+		client:        initHub.Client,
+		channel:       initHub.Channel,
+		conf:          initHub.Config,
+		log:           initHub.Log,
+		subscriptions: make(map[int64]map[*Subscription]struct{}),
+	}
+}
+
+// Run receives the events until Close. The Redis client reconnects on its
+// own, the events published meanwhile are only sent to the streams resumed.
+func (h *Hub) Run(ctx context.Context) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.pubsub = h.client.Subscribe(ctx, h.channel)
+	h.mu.Unlock()
+
+	for msg := range h.pubsub.Channel() {
+		h.dispatch(ctx, msg.Payload)
+	}
+}
+
+// eventUser is the user an order event is about.
+type eventUser struct {
+	UserID int64 `json:"user_id"`
+}
+
+func (h *Hub) dispatch(ctx context.Context, message string) {
+	var event model.OutboxEvent
+	if err := json.Unmarshal([]byte(message), &event); err != nil {
+		h.log.Error(ctx, "invalid order event received", zap.Error(err))
+		return
+	}
+	var user eventUser
+	if err := json.Unmarshal(event.Payload, &user); err != nil || user.UserID == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subscriptions[user.UserID] {
+		select {
+		case s.events <- &event:
+		default:
+			h.log.Warn(ctx, "order stream too slow, closing it", zap.Int64("user_id", user.UserID))
+			h.remove(s)
+		}
+	}
+}
+
+// Subscribe returns a new subscription to the events of given user. It's
+// closed already if the Hub is.
+func (h *Hub) Subscribe(userID int64) *Subscription {
+	s := &Subscription{
+		userID: userID,
+		events: make(chan *model.OutboxEvent, h.conf.BufferSize),
+		hub:    h,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(s.events)
+		return s
+	}
+	if h.subscriptions[userID] == nil {
+		h.subscriptions[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscriptions[userID][s] = struct{}{}
+	metrics.StreamConnections.Inc()
+	return s
+}
+
+// remove closes given subscription, h.mu must be held.
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subscriptions[s.userID][s]; !ok {
+		return
+	}
+	delete(h.subscriptions[s.userID], s)
+	if len(h.subscriptions[s.userID]) == 0 {
+		delete(h.subscriptions, s.userID)
+	}
+	close(s.events)
+	metrics.StreamConnections.Dec()
+}
+
+// Close stops receiving and closes every subscription, so the streams end
+// before the server shuts down.
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	for _, subscriptions := range h.subscriptions {
+		for s := range subscriptions {
+			h.remove(s)
+		}
+	}
+	if h.pubsub != nil {
+		return h.pubsub.Close()
+	}
+	return nil
+}
+
+// Subscription receives the events of a user.
+type Subscription struct {
+	userID int64
+	events chan *model.OutboxEvent
+	hub    *Hub
+}
+
+// Events returns the channel of the events, closed once the subscription is.
+func (s *Subscription) Events() <-chan *model.OutboxEvent {
+	return s.events
+}
+
+// Close unsubscribes.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
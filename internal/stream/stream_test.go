@@ -0,0 +1,73 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func message(t *testing.T, id, userID int64) string {
+	event, err := model.NewOutboxEvent(model.EventOrderCancelled, "DA1", &model.OrderCancelledEvent{ConsignmentID: "DA1", UserID: userID})
+	require.NoError(t, err)
+	event.ID = id
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func received(s *Subscription) (ids []int64) {
+	for {
+		select {
+		case event, ok := <-s.Events():
+			if !ok {
+				return ids
+			}
+			ids = append(ids, event.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestHubDispatch(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		hub    = NewHub(&InitHub{Config: &Config{BufferSize: 2}, Log: log.New()})
+		first  = hub.Subscribe(1)
+		second = hub.Subscribe(1)
+		other  = hub.Subscribe(2)
+	)
+
+	hub.dispatch(ctx, message(t, 10, 1))
+	hub.dispatch(ctx, message(t, 11, 2))
+	hub.dispatch(ctx, "not json")
+	assert.Equal(t, []int64{10}, received(first))
+	assert.Equal(t, []int64{10}, received(second))
+	assert.Equal(t, []int64{11}, received(other))
+
+	// a subscription falling behind the buffer is closed, the others go on
+	for id := int64(12); id <= 14; id++ {
+		hub.dispatch(ctx, message(t, id, 1))
+		received(first)
+	}
+	assert.Equal(t, []int64{12, 13}, received(second))
+	_, open := <-second.Events()
+	assert.False(t, open)
+	second.Close() // closing again is a no-op
+
+	first.Close()
+	_, open = <-first.Events()
+	assert.False(t, open)
+	hub.dispatch(ctx, message(t, 15, 1))
+
+	require.NoError(t, hub.Close())
+	_, open = <-other.Events()
+	assert.False(t, open)
+	_, open = <-hub.Subscribe(2).Events()
+	assert.False(t, open, "subscriptions of a closed hub are closed")
+}
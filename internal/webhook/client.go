@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kaium123/order/internal/common"
+	"github.com/kaium123/order/internal/metrics"
+	"github.com/kaium123/order/internal/model"
+)
+
+// maxResponseSize is the size of the beginning of the response bodies kept in
+// the delivery log.
+const maxResponseSize = 1024
+
+// Client posts the deliveries to the webhooks.
+type Client struct {
+	http *http.Client
+	conf *Config
+}
+
+// NewClient returns a Client refusing to connect to private addresses unless
+// allowed by given configuration. The check is made on the resolved
+// addresses, a public host name can't point to the internal network.
+func NewClient(conf *Config) *Client {
+	dialer := &net.Dialer{Timeout: conf.Timeout}
+	if !conf.AllowPrivateNetworks {
+		dialer.Control = denyPrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Client{
+		http: &http.Client{
+			Transport: transport,
+			Timeout:   conf.Timeout,
+			// redirects are failed attempts, the webhook URL must be updated
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		conf: conf,
+	}
+}
+
+// denyPrivate fails the connections to non public addresses.
+func denyPrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%s is not a public address", host)
+	}
+	return nil
+}
+
+// Deliver attempts given delivery to given subscription and records the
+// outcome in the delivery: succeeded on a 2xx response, otherwise pending
+// until the next attempt or failed once out of attempts.
+func (c *Client) Deliver(ctx context.Context, subscription *model.WebhookSubscription, delivery *model.WebhookDelivery) {
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastStatusCode = 0
+	delivery.LastError = ""
+	delivery.LastResponse = ""
+
+	err := c.post(ctx, subscription, delivery, now)
+	delivery.DurationMs = time.Since(now).Milliseconds()
+
+	switch {
+	case err == nil:
+		delivery.Status = model.DeliverySucceeded
+		delivery.DeliveredAt = time.Now().UTC()
+	case delivery.Attempts >= c.conf.MaxAttempts:
+		delivery.Status = model.DeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.Status = model.DeliveryPending
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(c.conf.Backoff(delivery.Attempts))
+	}
+	metrics.WebhookDeliveries.WithLabelValues(delivery.Status).Inc()
+}
+
+// post sends the signed payload of the delivery and fails unless the
+// response is 2xx.
+func (c *Client) post(ctx context.Context, subscription *model.WebhookSubscription, delivery *model.WebhookDelivery, now time.Time) error {
+	body, err := json.Marshal(&model.WebhookPayload{
+		DeliveryID: delivery.ID,
+		EventID:    delivery.EventID,
+		Type:       delivery.EventType,
+		CreatedAt:  delivery.CreatedAt,
+		Data:       delivery.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "order-webhooks/"+common.GetVersion().String())
+	req.Header.Set(HeaderID, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(string(subscription.Secret), now, body))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	delivery.LastStatusCode = resp.StatusCode
+	delivery.LastResponse = strings.ToValidUTF8(string(response), "")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kaium123/order/internal/log"
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/repository"
+	"go.uber.org/zap"
+)
+
+type InitDispatcher struct {
+	WebhookRepository repository.IWebhook
+	Client            *Client
+	Config            *Config
+	Log               *log.Logger
+}
+
+// Dispatcher attempts the deliveries due.
+type Dispatcher struct {
+	webhookRepository repository.IWebhook
+	client            *Client
+	conf              *Config
+	log               *log.Logger
+}
+
+// NewDispatcher returns a new Dispatcher.
+func NewDispatcher(initDispatcher *InitDispatcher) *Dispatcher {
+	return &Dispatcher{
+		webhookRepository: initDispatcher.WebhookRepository,
+		client:            initDispatcher.Client,
+		conf:              initDispatcher.Config,
+		log:               initDispatcher.Log,
+	}
+}
+
+// Run attempts the deliveries until given context is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		n, err := d.DispatchOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.log.Error(ctx, "failed to dispatch the webhook deliveries", zap.Error(err))
+		}
+		if n == d.conf.BatchSize {
+			continue // more are due
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.conf.PollInterval):
+		}
+	}
+}
+
+// DispatchOnce attempts a batch of deliveries due concurrently and returns
+// their number. The deliveries are leased for twice the timeout: an instance
+// stopping mid-attempt leaves them to the others once it expires.
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	lease := time.Now().UTC().Add(2 * d.conf.Timeout)
+	deliveries, subscriptions, err := d.webhookRepository.ClaimDeliveries(ctx, d.conf.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *model.WebhookDelivery) {
+			defer wg.Done()
+			d.dispatch(ctx, subscriptions[delivery.SubscriptionID], delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, subscription *model.WebhookSubscription, delivery *model.WebhookDelivery) {
+	if subscription == nil {
+		delivery.Status = model.DeliveryFailed
+		delivery.LastError = "subscription deleted"
+	} else {
+		d.client.Deliver(ctx, subscription, delivery)
+	}
+	if ctx.Err() != nil {
+		return // interrupted, attempted again once the lease expires
+	}
+
+	if err := d.webhookRepository.SaveAttempt(ctx, delivery); err != nil {
+		d.log.Error(ctx, "failed to save the webhook delivery attempt", zap.Int64("delivery_id", delivery.ID), zap.Error(err))
+		return
+	}
+	d.log.Debug(ctx, "webhook delivery attempted", zap.Int64("delivery_id", delivery.ID),
+		zap.String("status", delivery.Status), zap.Int("attempts", delivery.Attempts), zap.String("error", delivery.LastError))
+}
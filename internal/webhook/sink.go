@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/kaium123/order/internal/model"
+	"github.com/kaium123/order/internal/repository"
+)
+
+// Sink enqueues the deliveries of the outbox events to the webhooks, it's
+// added to the sinks of the outbox relay.
+type Sink struct {
+	webhookRepository repository.IWebhook
+}
+
+// NewSink returns a Sink enqueuing the deliveries with given repository.
+func NewSink(webhookRepository repository.IWebhook) *Sink {
+	return &Sink{webhookRepository: webhookRepository}
+}
+
+func (s *Sink) Name() string {
+	return "webhooks"
+}
+
+// Publish creates the deliveries of the events to the subscriptions of their
+// stores. The events published twice are enqueued once.
+func (s *Sink) Publish(ctx context.Context, events []*model.OutboxEvent) error {
+	_, err := s.webhookRepository.EnqueueDeliveries(ctx, events)
+	return err
+}
+
+// Close does nothing, the repository is shared.
+func (s *Sink) Close() error {
+	return nil
+}
@@ -0,0 +1,97 @@
+// Package webhook delivers the order events to the webhooks of the stores,
+// signed and retried with exponential backoff.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of the webhook requests.
+const (
+	// HeaderID is the ID of the delivery, it's kept across the attempts.
+	HeaderID = "X-Webhook-Id"
+	// HeaderEvent is the type of the event.
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderTimestamp is the Unix time of the attempt, it's signed along with
+	// the body so a captured request can't be replayed later.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is "sha256=" followed by the hex encoded HMAC-SHA256 of
+	// "<timestamp>.<body>" keyed by the secret of the subscription.
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Errors of Verify.
+var (
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	ErrExpiredTimestamp = errors.New("webhook: timestamp out of tolerance")
+)
+
+// Config of the webhook deliveries.
+type Config struct {
+	// Enable enqueues the deliveries of the events relayed by the outbox and
+	// runs the dispatcher in this instance.
+	Enable bool `json:"enable" yaml:"enable" toml:"enable" mapstructure:"enable"`
+	// PollInterval between the reads of the deliveries due once all were
+	// attempted.
+	PollInterval time.Duration `json:"poll_interval" yaml:"poll_interval" toml:"poll_interval" mapstructure:"poll_interval"`
+	// BatchSize is the maximum number of deliveries attempted at once.
+	BatchSize int `json:"batch_size" yaml:"batch_size" toml:"batch_size" mapstructure:"batch_size"`
+	// Timeout of an attempt, including the connection.
+	Timeout time.Duration `json:"timeout" yaml:"timeout" toml:"timeout" mapstructure:"timeout"`
+	// MaxAttempts of a delivery before it fails.
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts" toml:"max_attempts" mapstructure:"max_attempts"`
+	// MinBackoff is the wait after the first failed attempt, doubled after
+	// every other one up to MaxBackoff.
+	MinBackoff time.Duration `json:"min_backoff" yaml:"min_backoff" toml:"min_backoff" mapstructure:"min_backoff"`
+	MaxBackoff time.Duration `json:"max_backoff" yaml:"max_backoff" toml:"max_backoff" mapstructure:"max_backoff"`
+	// AllowPrivateNetworks allows the webhooks on loopback, private and link
+	// local addresses, for development only.
+	AllowPrivateNetworks bool `json:"allow_private_networks" yaml:"allow_private_networks" toml:"allow_private_networks" mapstructure:"allow_private_networks"`
+}
+
+// Sign returns the value of HeaderSignature for given body sent at given
+// time.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a webhook request received with given
+// headers and body, and that it was sent within tolerance of now. It's what
+// the receivers are expected to do.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	timestamp := time.Unix(unix, 0)
+	if !hmac.Equal([]byte(header.Get(HeaderSignature)), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	if age := time.Since(timestamp); age > tolerance || age < -tolerance {
+		return ErrExpiredTimestamp
+	}
+	return nil
+}
+
+// Backoff returns the wait before the next attempt of a delivery after given
+// number of failed attempts.
+func (c *Config) Backoff(attempts int) time.Duration {
+	wait := c.MinBackoff
+	for i := 1; i < attempts && wait < c.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if c.MaxBackoff > 0 && wait > c.MaxBackoff {
+		wait = c.MaxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kaium123/order/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "whsec_0123456789abcdef"
+
+func testConfig() *Config {
+	return &Config{
+		Timeout:              time.Second,
+		MaxAttempts:          3,
+		MinBackoff:           time.Minute,
+		MaxBackoff:           time.Hour,
+		AllowPrivateNetworks: true,
+	}
+}
+
+func testDelivery() *model.WebhookDelivery {
+	return &model.WebhookDelivery{
+		ID:             7,
+		SubscriptionID: 1,
+		EventID:        42,
+		EventType:      model.EventOrderCancelled,
+		Payload:        json.RawMessage(`{"consignment_id":"DA1","store_id":3}`),
+		Status:         model.DeliveryPending,
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(secret, r.Header, body, time.Minute); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "7", r.Header.Get(HeaderID))
+		assert.Equal(t, model.EventOrderCancelled, r.Header.Get(HeaderEvent))
+
+		var payload model.WebhookPayload
+		assert.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, int64(42), payload.EventID)
+		assert.JSONEq(t, `{"consignment_id":"DA1","store_id":3}`, string(payload.Data))
+
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("try later"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var (
+		ctx          = context.Background()
+		client       = NewClient(testConfig())
+		subscription = &model.WebhookSubscription{ID: 1, URL: server.URL, Secret: secret}
+		delivery     = testDelivery()
+	)
+
+	client.Deliver(ctx, subscription, delivery)
+	assert.Equal(t, model.DeliveryPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.LastStatusCode)
+	assert.Equal(t, "try later", delivery.LastResponse)
+	assert.Equal(t, "unexpected status 503", delivery.LastError)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), delivery.NextAttemptAt, 5*time.Second)
+
+	client.Deliver(ctx, subscription, delivery)
+	assert.Equal(t, model.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Equal(t, http.StatusNoContent, delivery.LastStatusCode)
+	assert.Empty(t, delivery.LastError)
+	assert.False(t, delivery.DeliveredAt.IsZero())
+
+	// a wrong secret is refused by the receiver until out of attempts
+	delivery = testDelivery()
+	subscription.Secret = "whsec_wrong"
+	for i := 0; i < 3; i++ {
+		client.Deliver(ctx, subscription, delivery)
+	}
+	assert.Equal(t, model.DeliveryFailed, delivery.Status)
+	assert.Equal(t, http.StatusUnauthorized, delivery.LastStatusCode)
+}
+
+func TestDeliverPrivateNetwork(t *testing.T) {
+	var called atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called.Store(true)
+	}))
+	defer server.Close()
+
+	conf := testConfig()
+	conf.AllowPrivateNetworks = false
+	delivery := testDelivery()
+	NewClient(conf).Deliver(context.Background(), &model.WebhookSubscription{URL: server.URL, Secret: secret}, delivery)
+
+	assert.False(t, called.Load())
+	assert.Equal(t, model.DeliveryPending, delivery.Status)
+	assert.Contains(t, delivery.LastError, "127.0.0.1 is not a public address")
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"webhook.test"}`)
+	header := http.Header{}
+	now := time.Now()
+	header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	header.Set(HeaderSignature, Sign(secret, now, body))
+
+	require.NoError(t, Verify(secret, header, body, time.Minute))
+	assert.ErrorIs(t, Verify("whsec_other", header, body, time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(secret, header, []byte(`{}`), time.Minute), ErrInvalidSignature)
+
+	old := now.Add(-time.Hour)
+	header.Set(HeaderTimestamp, strconv.FormatInt(old.Unix(), 10))
+	header.Set(HeaderSignature, Sign(secret, old, body))
+	assert.ErrorIs(t, Verify(secret, header, body, time.Minute), ErrExpiredTimestamp)
+}
+
+func TestBackoff(t *testing.T) {
+	conf := &Config{MinBackoff: 30 * time.Second, MaxBackoff: 5 * time.Minute}
+	for attempts, want := range map[int]time.Duration{
+		1: 30 * time.Second,
+		2: time.Minute,
+		3: 2 * time.Minute,
+		4: 4 * time.Minute,
+		5: 5 * time.Minute,
+		9: 5 * time.Minute,
+	} {
+		assert.Equal(t, want, conf.Backoff(attempts), "after %d attempts", attempts)
+	}
+}
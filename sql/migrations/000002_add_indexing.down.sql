@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_orders_merchant_order_id;
+DROP INDEX IF EXISTS idx_orders_store_id;
@@ -0,0 +1,11 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS total_fee,
+    DROP COLUMN IF EXISTS order_amount,
+    DROP COLUMN IF EXISTS order_type,
+    DROP COLUMN IF EXISTS order_status,
+    DROP COLUMN IF EXISTS delivery_fee,
+    DROP COLUMN IF EXISTS discount,
+    DROP COLUMN IF EXISTS promo_discount,
+    DROP COLUMN IF EXISTS cod_fee,
+    DROP COLUMN IF EXISTS order_type_id,
+    DROP COLUMN IF EXISTS order_consignment_id;
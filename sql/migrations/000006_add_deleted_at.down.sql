@@ -0,0 +1,8 @@
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE access_tokens
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deleted_at;
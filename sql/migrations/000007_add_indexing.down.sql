@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_orders_archive;
+DROP INDEX IF EXISTS idx_orders_transfer_status;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS archive,
+    DROP COLUMN IF EXISTS transfer_status;
+
+DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
+DROP INDEX IF EXISTS idx_access_tokens_user_id;
+DROP INDEX IF EXISTS idx_users_user_name;
+DROP INDEX IF EXISTS idx_users_email;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rate_cards;
+DROP TABLE IF EXISTS stores;
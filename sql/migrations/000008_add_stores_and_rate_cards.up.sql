@@ -0,0 +1,23 @@
+CREATE TABLE stores (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    contact_phone VARCHAR(50),
+    address TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT NULL,
+    deleted_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, name)
+);
+
+CREATE INDEX idx_stores_user_id ON stores(user_id);
+
+CREATE TABLE rate_cards (
+    id SERIAL PRIMARY KEY,
+    city_id INT NOT NULL UNIQUE,
+    base_delivery_fee DOUBLE PRECISION NOT NULL,
+    cod_percentage DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT NULL
+);
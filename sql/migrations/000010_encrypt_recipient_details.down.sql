@@ -0,0 +1,7 @@
+-- run `reencrypt` with the encryption disabled first, encrypted phone numbers
+-- don't fit VARCHAR(50)
+DROP INDEX IF EXISTS idx_orders_recipient_phone_hash;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS recipient_phone_hash,
+    ALTER COLUMN recipient_phone TYPE VARCHAR(50);
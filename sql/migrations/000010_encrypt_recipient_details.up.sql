@@ -0,0 +1,6 @@
+-- encrypted values don't fit the plaintext sizes
+ALTER TABLE orders
+    ALTER COLUMN recipient_phone TYPE TEXT,
+    ADD COLUMN recipient_phone_hash VARCHAR(64);
+
+CREATE INDEX idx_orders_recipient_phone_hash ON orders(recipient_phone_hash);
@@ -0,0 +1,20 @@
+-- events written in the transaction of the order changes, published by the
+-- relay in (txid, id) order
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    txid XID8 NOT NULL DEFAULT pg_current_xact_id(),
+    event_type VARCHAR(64) NOT NULL,
+    aggregate_id VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_txid_id ON outbox(txid, id);
+
+-- last event published to every sink
+CREATE TABLE IF NOT EXISTS outbox_offsets (
+    sink VARCHAR(64) PRIMARY KEY,
+    txid XID8 NOT NULL DEFAULT '0',
+    event_id BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
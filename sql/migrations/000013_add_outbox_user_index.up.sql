@@ -0,0 +1,2 @@
+-- events of a user, replayed when an order stream resumes
+CREATE INDEX IF NOT EXISTS idx_outbox_user_id ON outbox ((payload->>'user_id'), id);
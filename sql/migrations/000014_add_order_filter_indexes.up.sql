@@ -0,0 +1,3 @@
+-- prefix search on the consignment IDs and the date ranges of the order listing
+CREATE INDEX IF NOT EXISTS idx_orders_consignment_id_pattern ON orders (order_consignment_id text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_orders_user_id_created_at ON orders (user_id, created_at);
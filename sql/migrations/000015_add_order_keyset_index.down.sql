@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_orders_user_id_created_at ON orders (user_id, created_at);
+DROP INDEX IF EXISTS idx_orders_user_id_created_at_id;
@@ -0,0 +1,3 @@
+-- keyset pagination of the order listing by (created_at, id)
+CREATE INDEX IF NOT EXISTS idx_orders_user_id_created_at_id ON orders (user_id, created_at, id);
+DROP INDEX IF EXISTS idx_orders_user_id_created_at;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_orders_recipient_address_trgm;
+DROP TRIGGER IF EXISTS orders_search_vector ON orders;
+DROP FUNCTION IF EXISTS orders_set_search_vector();
+DROP FUNCTION IF EXISTS orders_search_vector(TEXT, TEXT, TEXT);
+ALTER TABLE orders DROP COLUMN IF EXISTS search_vector;
+-- pg_trgm is kept, dropping an extension needs more privileges than the migrations may have
@@ -0,0 +1,24 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- full-text search on the words of the plain text fields. The column is nullable so adding it doesn't
+-- rewrite the table: the trigger fills it on write, migration 000023 backfills the existing orders and
+-- the indexes are built concurrently by the migrations 000024 to 000026.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION orders_search_vector(recipient_name TEXT, merchant_order_id TEXT, item_description TEXT)
+    RETURNS tsvector LANGUAGE sql IMMUTABLE AS $$
+    SELECT to_tsvector('simple',
+        coalesce(recipient_name, '') || ' ' || coalesce(merchant_order_id, '') || ' ' || coalesce(item_description, ''))
+$$;
+
+CREATE OR REPLACE FUNCTION orders_set_search_vector() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.search_vector := orders_search_vector(NEW.recipient_name, NEW.merchant_order_id, NEW.item_description);
+    RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS orders_search_vector ON orders;
+CREATE TRIGGER orders_search_vector
+    BEFORE INSERT OR UPDATE OF recipient_name, merchant_order_id, item_description ON orders
+    FOR EACH ROW EXECUTE FUNCTION orders_set_search_vector();
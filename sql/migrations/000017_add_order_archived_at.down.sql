@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_orders_archivable;
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS unarchived_at,
+    DROP COLUMN IF EXISTS archived_at;
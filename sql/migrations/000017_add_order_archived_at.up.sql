@@ -0,0 +1,7 @@
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS unarchived_at TIMESTAMP; -- unarchived orders aren't archived automatically again
+
+-- completed orders the archiver is left to archive
+CREATE INDEX IF NOT EXISTS idx_orders_archivable ON orders (updated_at)
+    WHERE archive = 0 AND order_status = '2' AND unarchived_at IS NULL AND deleted_at IS NULL;
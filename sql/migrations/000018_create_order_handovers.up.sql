@@ -0,0 +1,15 @@
+-- handovers of the orders from the merchant to the hub, the rider and the
+-- recipient, orders.transfer_status is the party holding the order
+CREATE TABLE IF NOT EXISTS order_handovers (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status INT NOT NULL,
+    to_status INT NOT NULL,
+    received_by VARCHAR(100) NOT NULL,
+    receiver_ref VARCHAR(50),
+    note TEXT,
+    recorded_by INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_handovers_order_id ON order_handovers(order_id, id);
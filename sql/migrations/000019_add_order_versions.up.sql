@@ -0,0 +1,14 @@
+-- optimistic locking of the editions of the orders, and their history
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+-- the changes of the encrypted recipient details are encrypted as well
+CREATE TABLE IF NOT EXISTS order_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    changed_by INT NOT NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_history_order_id ON order_history(order_id, version);
@@ -0,0 +1,8 @@
+UPDATE orders
+SET deleted_at = cancelled_at, order_status = '0'
+WHERE order_status = '3';
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS cancel_note,
+    DROP COLUMN IF EXISTS cancel_reason,
+    DROP COLUMN IF EXISTS cancelled_at;
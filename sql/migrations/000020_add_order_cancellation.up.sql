@@ -0,0 +1,10 @@
+-- the cancelled orders are kept with the cancelled status, 3, instead of
+-- being deleted
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS cancel_reason VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS cancel_note TEXT;
+
+UPDATE orders
+SET order_status = '3', cancelled_at = deleted_at, deleted_at = NULL
+WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS rto_fee;
+DROP TABLE IF EXISTS delivery_attempts;
@@ -0,0 +1,17 @@
+-- delivery attempts of the orders with a rider, the orders failing too many
+-- attempts are returned to the merchant, status 4, charged the RTO fee
+CREATE TABLE IF NOT EXISTS delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    outcome VARCHAR(20) NOT NULL,
+    failure_reason VARCHAR(50),
+    rider_ref VARCHAR(50),
+    rider_notes TEXT,
+    recorded_by INT NOT NULL,
+    attempted_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (order_id, attempt)
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS rto_fee DOUBLE PRECISION NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+-- The test user isn't brought back, load the development fixtures with `order seed` instead.
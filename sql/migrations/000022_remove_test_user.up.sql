@@ -0,0 +1,13 @@
+-- The test user of 000004 shipped with a well-known password to every environment, the development
+-- fixtures load it through `order seed` instead. It's removed unless it owns orders or stores, which
+-- would be left without their user, its password is disabled then.
+DELETE FROM users u
+WHERE u.user_name = '01901901901@mailinator.com'
+  AND u.password_hash = '$2a$10$QLWmBwCTIa4HEOEONaIK7uubsyk2vwZIqqvZizKjhiJvQGSPm8qcu'
+  AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id)
+  AND NOT EXISTS (SELECT 1 FROM stores s WHERE s.user_id = u.id);
+
+UPDATE users
+SET password_hash = '', updated_at = CURRENT_TIMESTAMP
+WHERE user_name = '01901901901@mailinator.com'
+  AND password_hash = '$2a$10$QLWmBwCTIa4HEOEONaIK7uubsyk2vwZIqqvZizKjhiJvQGSPm8qcu';
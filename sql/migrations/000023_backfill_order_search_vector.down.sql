@@ -0,0 +1 @@
+-- The search vectors are dropped with their column by migration 000016.
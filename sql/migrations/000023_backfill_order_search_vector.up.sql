@@ -0,0 +1,24 @@
+-- fills the search vector of the orders written before migration 000016 by batches, each committed on
+-- its own so the rows aren't all locked at once. The DO block is the only statement of the migration,
+-- it can't commit inside a transaction.
+DO $$
+DECLARE
+    last_id BIGINT := 0;
+    batch_last_id BIGINT;
+BEGIN
+    LOOP
+        WITH batch AS (
+            SELECT id FROM orders WHERE id > last_id ORDER BY id LIMIT 5000
+        ), updated AS (
+            UPDATE orders AS o
+            SET search_vector = orders_search_vector(o.recipient_name, o.merchant_order_id, o.item_description)
+            FROM batch
+            WHERE o.id = batch.id AND o.search_vector IS NULL
+        )
+        SELECT max(id) INTO batch_last_id FROM batch;
+        EXIT WHEN batch_last_id IS NULL;
+        last_id := batch_last_id;
+        COMMIT;
+    END LOOP;
+END
+$$;
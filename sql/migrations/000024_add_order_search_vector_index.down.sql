@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS idx_orders_search_vector;
@@ -0,0 +1,3 @@
+-- CONCURRENTLY can't run in a transaction, so the index is the only statement of the migration. A build
+-- which failed leaves an invalid index behind, drop it before running the migration again.
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_orders_search_vector ON orders USING GIN (search_vector);
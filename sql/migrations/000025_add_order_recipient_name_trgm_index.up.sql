@@ -0,0 +1,4 @@
+-- substring and fuzzy search of the names, built concurrently as the only statement of the migration.
+-- The addresses are only searched while they aren't encrypted, which the migrations can't tell, so
+-- their index is left to the deployments keeping them in plain text.
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_orders_recipient_name_trgm ON orders USING GIN (recipient_name gin_trgm_ops);
@@ -0,0 +1,3 @@
+-- substring and fuzzy search of the merchant order IDs, built concurrently as the only statement of the
+-- migration
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_orders_merchant_order_id_trgm ON orders USING GIN (merchant_order_id gin_trgm_ops);